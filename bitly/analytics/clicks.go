@@ -0,0 +1,50 @@
+package analytics
+
+import (
+	"context"
+	"github.com/lcd1232/go-bitly/bitly"
+	"github.com/pkg/errors"
+	"net/url"
+	"strconv"
+)
+
+// Clicks is the response of the clicks endpoint of a Bitlink
+type Clicks struct {
+	LinkClicks    []LinkClicks   `json:"link_clicks"`
+	Units         int            `json:"units"`
+	Unit          string         `json:"unit"`
+	UnitReference bitly.JSONDate `json:"unit_reference"`
+}
+
+// LinkClicks is the number of clicks in the unit starting at Date
+type LinkClicks struct {
+	Date   bitly.JSONDate `json:"date"`
+	Clicks int            `json:"clicks"`
+}
+
+// Series returns clicks as a Series sorted by time, Bitly lists the newest unit first
+func (c *Clicks) Series() (Series, error) {
+	points := make([]Point, len(c.LinkClicks))
+	for i, clicks := range c.LinkClicks {
+		points[i] = Point{Time: clicks.Date.Time(), Clicks: clicks.Clicks}
+	}
+	return NewSeries(Unit(c.Unit), points)
+}
+
+// GetClicks returns clicks of a Bitlink for the last units of unit,
+// -1 units returns every unit with clicks
+func GetClicks(ctx context.Context, client *bitly.Client, bitlink string, unit Unit, units int) (Series, error) {
+	if !unit.Valid() {
+		return Series{}, errors.Errorf("analytics: unknown unit %q", unit)
+	}
+	id, err := bitly.ParseBitlink(bitlink)
+	if err != nil {
+		return Series{}, err
+	}
+	params := url.Values{"unit": {string(unit)}, "units": {strconv.Itoa(units)}}
+	clicks := &Clicks{}
+	if _, err := client.Call(ctx, "GET", "/v4/bitlinks/"+id.Path()+"/clicks", params, nil, clicks); err != nil {
+		return Series{}, err
+	}
+	return clicks.Series()
+}
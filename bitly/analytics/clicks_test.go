@@ -0,0 +1,78 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/lcd1232/go-bitly/bitly/bitlytest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClicks_Series(t *testing.T) {
+	fixture := `{
+		"link_clicks": [
+			{"date": "2018-07-19T00:00:00+0000", "clicks": 3},
+			{"date": "2018-07-18T00:00:00+0000", "clicks": 0},
+			{"date": "2018-07-17T00:00:00+0000", "clicks": 2}
+		],
+		"units": 3,
+		"unit": "day",
+		"unit_reference": "2018-07-19T12:00:00+0000"
+	}`
+	clicks := &Clicks{}
+	if err := json.Unmarshal([]byte(fixture), clicks); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s, err := clicks.Series()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"2018-07-17T00:00:00Z 2", "2018-07-18T00:00:00Z 0", "2018-07-19T00:00:00Z 3"}
+	if got := summary(s); !reflect.DeepEqual(want, got) || s.Unit != Day {
+		t.Fatalf("want series %v got %v", want, got)
+	}
+
+	clicks.Unit = "year"
+	if _, err := clicks.Series(); err == nil {
+		t.Fatalf("want error for unknown unit")
+	}
+}
+
+func TestGetClicks(t *testing.T) {
+	s := bitlytest.NewServer()
+	defer s.Close()
+	reference := time.Date(2018, 7, 19, 12, 0, 0, 0, time.UTC)
+	s.Now = func() time.Time { return reference }
+	a := s.AddBitlink(bitlytest.Bitlink{LongURL: "https://example.com/a"})
+	b := s.AddBitlink(bitlytest.Bitlink{LongURL: "https://example.com/b"})
+	s.AddClicks(a, reference.Add(-time.Hour), 3)
+	s.AddClicks(a, reference.AddDate(0, 0, -2), 2)
+	s.AddClicks(b, reference.Add(-2*time.Hour), 1)
+	c := s.Client()
+
+	var series []Series
+	for _, id := range []string{a, b} {
+		hourly, err := GetClicks(context.Background(), c, id, Hour, 72)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		series = append(series, hourly)
+	}
+	total, err := Merge(series...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	daily, err := Resample(total, Day, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"2018-07-16T00:00:00Z 0", "2018-07-17T00:00:00Z 2", "2018-07-18T00:00:00Z 0", "2018-07-19T00:00:00Z 4"}
+	if got := summary(daily); !reflect.DeepEqual(want, got) {
+		t.Fatalf("want group total %v got %v", want, got)
+	}
+
+	if _, err := GetClicks(context.Background(), c, a, Unit("year"), 1); err == nil {
+		t.Fatalf("want error for unknown unit")
+	}
+}
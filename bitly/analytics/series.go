@@ -0,0 +1,280 @@
+// Package analytics works with click series of Bitlinks: resampling to
+// coarser units in a time zone, cumulative sums, moving averages,
+// period-over-period comparisons and totals of many Bitlinks.
+//
+//	s, err := analytics.GetClicks(ctx, client, "bit.ly/2Ld3Bx9", analytics.Hour, 24*28)
+//	weekly, err := analytics.Resample(s, analytics.Week, berlin)
+//	avg := analytics.MovingAverage(weekly, 4)
+//
+// Functions do not modify their arguments and return new series.
+package analytics
+
+import (
+	"github.com/pkg/errors"
+	"sort"
+	"time"
+)
+
+// Unit is a unit of time of a Series, the values are the ones of the Bitly API
+type Unit string
+
+const (
+	Minute Unit = "minute"
+	Hour   Unit = "hour"
+	Day    Unit = "day"
+	Week   Unit = "week"
+	Month  Unit = "month"
+)
+
+// units are ordered from the finest
+var units = []Unit{Minute, Hour, Day, Week, Month}
+
+func (u Unit) rank() int {
+	for i, unit := range units {
+		if unit == u {
+			return i
+		}
+	}
+	return -1
+}
+
+// Valid reports whether u is a unit of the Bitly API
+func (u Unit) Valid() bool {
+	return u.rank() >= 0
+}
+
+// Start returns the start of the unit containing t in loc. Days start at
+// midnight and weeks on Monday, so daylight saving time changes give 23 and
+// 25 hour days.
+func (u Unit) Start(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch u {
+	case Minute:
+		return t.Add(-time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	case Hour:
+		// Subtracting minutes keeps zones with half hour offsets and the
+		// repeated hour of a daylight saving time change right
+		return t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	case Day:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	case Week:
+		return time.Date(t.Year(), t.Month(), t.Day()-(int(t.Weekday())+6)%7, 0, 0, 0, 0, loc)
+	case Month:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	}
+	return t
+}
+
+// Next returns the start of the unit following the one starting at start
+func (u Unit) Next(start time.Time) time.Time {
+	switch u {
+	case Minute:
+		return start.Add(time.Minute)
+	case Hour:
+		return start.Add(time.Hour)
+	case Day:
+		return time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, start.Location())
+	case Week:
+		return time.Date(start.Year(), start.Month(), start.Day()+7, 0, 0, 0, 0, start.Location())
+	case Month:
+		return time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0, start.Location())
+	}
+	return start
+}
+
+// nests reports whether every unit u lies within a single unit of coarser,
+// weeks cross month boundaries, so they cannot be summed into months
+func (u Unit) nests(coarser Unit) bool {
+	if coarser.rank() < u.rank() {
+		return false
+	}
+	return !(u == Week && coarser == Month)
+}
+
+// Point is the number of clicks in the unit starting at Time
+type Point struct {
+	Time   time.Time
+	Clicks int
+}
+
+// Series is a click series, Points are sorted by time
+type Series struct {
+	Unit   Unit
+	Points []Point
+}
+
+// NewSeries returns a series of points sorted by time, points of the same
+// time are summed
+func NewSeries(unit Unit, points []Point) (Series, error) {
+	if !unit.Valid() {
+		return Series{}, errors.Errorf("analytics: unknown unit %q", unit)
+	}
+	sorted := append([]Point(nil), points...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+	s := Series{Unit: unit}
+	for _, p := range sorted {
+		if n := len(s.Points); n > 0 && s.Points[n-1].Time.Equal(p.Time) {
+			s.Points[n-1].Clicks += p.Clicks
+			continue
+		}
+		s.Points = append(s.Points, p)
+	}
+	return s, nil
+}
+
+// Total returns the sum of clicks of s
+func (s Series) Total() int {
+	total := 0
+	for _, p := range s.Points {
+		total += p.Clicks
+	}
+	return total
+}
+
+// Resample sums clicks of s into units of unit starting in loc, like days of
+// a time zone. Units without clicks between the first and the last point are
+// included with zero clicks, so the result is contiguous. Units of s must
+// nest in units of unit in loc: clicks of an hour cannot be split into
+// minutes, clicks of a week cannot be split between two months and clicks
+// of a UTC day cannot be split between two days of Berlin. Get clicks in
+// hours or minutes to resample them to days of another time zone.
+func Resample(s Series, unit Unit, loc *time.Location) (Series, error) {
+	if !unit.Valid() {
+		return Series{}, errors.Errorf("analytics: unknown unit %q", unit)
+	}
+	if !s.Unit.nests(unit) {
+		return Series{}, errors.Errorf("analytics: cannot resample %s series to %s", s.Unit, unit)
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	out := Series{Unit: unit}
+	for _, p := range s.Points {
+		start := unit.Start(p.Time, loc)
+		if end := s.Unit.Next(p.Time).Add(-time.Nanosecond); !unit.Start(end, loc).Equal(start) {
+			return Series{}, errors.Errorf("analytics: %s of %s spans two %ss in %s, get clicks in a finer unit",
+				s.Unit, p.Time.Format(time.RFC3339), unit, loc)
+		}
+		if n := len(out.Points); n > 0 {
+			last := out.Points[n-1].Time
+			if start.Equal(last) {
+				out.Points[n-1].Clicks += p.Clicks
+				continue
+			}
+			for next := unit.Next(last); next.Before(start); next = unit.Next(next) {
+				out.Points = append(out.Points, Point{Time: next})
+			}
+		}
+		out.Points = append(out.Points, Point{Time: start, Clicks: p.Clicks})
+	}
+	return out, nil
+}
+
+// Merge sums series of the same unit point by point, like clicks of every
+// Bitlink of a group into the group total
+func Merge(series ...Series) (Series, error) {
+	if len(series) == 0 {
+		return Series{}, errors.New("analytics: nothing to merge")
+	}
+	unit := series[0].Unit
+	var points []Point
+	for _, s := range series {
+		if s.Unit != unit {
+			return Series{}, errors.Errorf("analytics: cannot merge %s and %s series, resample them first", unit, s.Unit)
+		}
+		points = append(points, s.Points...)
+	}
+	return NewSeries(unit, points)
+}
+
+// Cumulative returns the running total of clicks of s
+func Cumulative(s Series) Series {
+	out := Series{Unit: s.Unit, Points: make([]Point, len(s.Points))}
+	total := 0
+	for i, p := range s.Points {
+		total += p.Clicks
+		out.Points[i] = Point{Time: p.Time, Clicks: total}
+	}
+	return out
+}
+
+// Average is a moving average of clicks of the window ending at Time
+type Average struct {
+	Time  time.Time
+	Value float64
+}
+
+// MovingAverage returns averages of clicks over window consecutive points,
+// the first one ends at the window-th point. Series with gaps should be
+// resampled first, so every point is a unit.
+func MovingAverage(s Series, window int) []Average {
+	if window < 1 || len(s.Points) < window {
+		return nil
+	}
+	averages := make([]Average, 0, len(s.Points)-window+1)
+	sum := 0
+	for i, p := range s.Points {
+		sum += p.Clicks
+		if i >= window {
+			sum -= s.Points[i-window].Clicks
+		}
+		if i >= window-1 {
+			averages = append(averages, Average{Time: p.Time, Value: float64(sum) / float64(window)})
+		}
+	}
+	return averages
+}
+
+// Comparison compares clicks of a period with the previous one
+type Comparison struct {
+	Current  int
+	Previous int
+}
+
+// Delta returns the change of clicks
+func (c Comparison) Delta() int {
+	return c.Current - c.Previous
+}
+
+// Percent returns the change relative to the previous period, ok is false
+// when the previous period has no clicks
+func (c Comparison) Percent() (percent float64, ok bool) {
+	if c.Previous == 0 {
+		return 0, false
+	}
+	return float64(c.Delta()) * 100 / float64(c.Previous), true
+}
+
+// Compare returns clicks of the last periods points of s compared with the
+// periods points before them, like the last 7 days and the 7 days before
+func Compare(s Series, periods int) (Comparison, error) {
+	if periods < 1 || len(s.Points) < 2*periods {
+		return Comparison{}, errors.Errorf("analytics: want %d points to compare periods of %d got %d", 2*periods, periods, len(s.Points))
+	}
+	n := len(s.Points)
+	current := Series{Points: s.Points[n-periods:]}
+	previous := Series{Points: s.Points[n-2*periods : n-periods]}
+	return Comparison{Current: current.Total(), Previous: previous.Total()}, nil
+}
+
+// PeriodChange is a point compared with the point lag units before it
+type PeriodChange struct {
+	Time time.Time
+	Comparison
+}
+
+// PeriodOverPeriod compares every point with the point lag points before
+// it, like days with the same days of the previous week for lag 7
+func PeriodOverPeriod(s Series, lag int) []PeriodChange {
+	if lag < 1 {
+		return nil
+	}
+	var changes []PeriodChange
+	for i := lag; i < len(s.Points); i++ {
+		changes = append(changes, PeriodChange{
+			Time:       s.Points[i].Time,
+			Comparison: Comparison{Current: s.Points[i].Clicks, Previous: s.Points[i-lag].Clicks},
+		})
+	}
+	return changes
+}
@@ -0,0 +1,250 @@
+package analytics
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return loc
+}
+
+func utc(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// summary formats points as RFC 3339 times in UTC and clicks
+func summary(s Series) []string {
+	var got []string
+	for _, p := range s.Points {
+		got = append(got, p.Time.UTC().Format(time.RFC3339)+" "+strconv.Itoa(p.Clicks))
+	}
+	return got
+}
+
+func TestResample_TimeZone(t *testing.T) {
+	// Clocks in Berlin moved from 02:00 CET to 03:00 CEST on 2020-03-29
+	hourly, err := NewSeries(Hour, []Point{
+		{Time: utc("2020-03-29T22:00:00Z"), Clicks: 1},
+		{Time: utc("2020-03-28T22:00:00Z"), Clicks: 2},
+		{Time: utc("2020-03-28T23:00:00Z"), Clicks: 3},
+		{Time: utc("2020-03-29T21:00:00Z"), Clicks: 4},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		desc string
+		loc  *time.Location
+		want []string
+	}{
+		{
+			desc: "Berlin",
+			loc:  mustLoadLocation(t, "Europe/Berlin"),
+			want: []string{"2020-03-27T23:00:00Z 2", "2020-03-28T23:00:00Z 7", "2020-03-29T22:00:00Z 1"},
+		},
+		{
+			desc: "UTC",
+			loc:  time.UTC,
+			want: []string{"2020-03-28T00:00:00Z 5", "2020-03-29T00:00:00Z 5"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			daily, err := Resample(hourly, Day, tc.loc)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := summary(daily); !reflect.DeepEqual(tc.want, got) || daily.Unit != Day {
+				t.Fatalf("want daily series %v got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestResample_SplitUnits(t *testing.T) {
+	daily, err := NewSeries(Day, []Point{
+		{Time: utc("2020-03-28T00:00:00Z"), Clicks: 5},
+		{Time: utc("2020-03-29T00:00:00Z"), Clicks: 5},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	// A UTC day starts at 1:00 in Berlin, its clicks belong to two Berlin
+	// days, and the last one of a week to two Berlin weeks
+	for unit, day := range map[Unit]string{Day: "2020-03-28", Week: "2020-03-29"} {
+		_, err := Resample(daily, unit, berlin)
+		want := "analytics: day of " + day + "T00:00:00Z spans two " + string(unit) + "s in Europe/Berlin, get clicks in a finer unit"
+		if err == nil || err.Error() != want {
+			t.Fatalf("want error %v got %v", want, err)
+		}
+	}
+	hourly, err := NewSeries(Hour, []Point{{Time: utc("2020-03-28T18:00:00Z"), Clicks: 1}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Resample(hourly, Day, mustLoadLocation(t, "Asia/Kolkata")); err == nil {
+		t.Fatalf("want error for hours split by a half hour offset")
+	}
+	if weekly, err := Resample(daily, Week, time.UTC); err != nil || weekly.Total() != 10 {
+		t.Fatalf("want UTC days resampled to UTC weeks got %v, %v", weekly, err)
+	}
+}
+
+func TestResample_FillsGaps(t *testing.T) {
+	daily, err := NewSeries(Day, []Point{
+		{Time: utc("2020-01-06T00:00:00Z"), Clicks: 1},
+		{Time: utc("2020-01-08T00:00:00Z"), Clicks: 3},
+		{Time: utc("2020-01-21T00:00:00Z"), Clicks: 2},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	weekly, err := Resample(daily, Week, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"2020-01-06T00:00:00Z 4", "2020-01-13T00:00:00Z 0", "2020-01-20T00:00:00Z 2"}
+	if got := summary(weekly); !reflect.DeepEqual(want, got) {
+		t.Fatalf("want weekly series %v got %v", want, got)
+	}
+
+	if _, err := Resample(weekly, Day, nil); err == nil || err.Error() != "analytics: cannot resample week series to day" {
+		t.Fatalf("want error resampling to a finer unit got %v", err)
+	}
+	if _, err := Resample(weekly, Month, nil); err == nil || err.Error() != "analytics: cannot resample week series to month" {
+		t.Fatalf("want error resampling weeks to months got %v", err)
+	}
+	if monthly, err := Resample(daily, Month, nil); err != nil || monthly.Total() != daily.Total() {
+		t.Fatalf("want days resampled to months got %v, %v", monthly, err)
+	}
+	if _, err := Resample(daily, Unit("year"), nil); err == nil {
+		t.Fatalf("want error for unknown unit")
+	}
+}
+
+func TestUnit_Start(t *testing.T) {
+	kolkata := mustLoadLocation(t, "Asia/Kolkata")
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	testCases := []struct {
+		unit Unit
+		t    time.Time
+		loc  *time.Location
+		want string
+	}{
+		{Minute, utc("2020-01-01T10:20:30Z"), time.UTC, "2020-01-01T10:20:00Z"},
+		// India is 5:30 ahead of UTC, its hours start at half past UTC hours
+		{Hour, utc("2020-01-01T00:10:00Z"), kolkata, "2019-12-31T23:30:00Z"},
+		// The second 02:30 of the last Sunday of October 2020 in Berlin
+		{Hour, utc("2020-10-25T01:30:00Z"), berlin, "2020-10-25T01:00:00Z"},
+		{Day, utc("2020-10-25T12:00:00Z"), berlin, "2020-10-24T22:00:00Z"},
+		{Week, utc("2020-01-05T12:00:00Z"), time.UTC, "2019-12-30T00:00:00Z"},
+		{Month, utc("2020-02-29T23:30:00Z"), berlin, "2020-02-29T23:00:00Z"},
+	}
+	for _, tc := range testCases {
+		if got := tc.unit.Start(tc.t, tc.loc).UTC().Format(time.RFC3339); got != tc.want {
+			t.Errorf("%s start of %s in %s: want %s got %s", tc.unit, tc.t, tc.loc, tc.want, got)
+		}
+	}
+	if next := Day.Next(Day.Start(utc("2020-10-25T12:00:00Z"), berlin)); next.Sub(Day.Start(utc("2020-10-25T12:00:00Z"), berlin)) != 25*time.Hour {
+		t.Errorf("want 25 hour day when clocks move back got %v", next)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a, _ := NewSeries(Day, []Point{{Time: utc("2020-01-01T00:00:00Z"), Clicks: 1}, {Time: utc("2020-01-02T00:00:00Z"), Clicks: 2}})
+	b, _ := NewSeries(Day, []Point{{Time: utc("2020-01-02T00:00:00Z"), Clicks: 3}, {Time: utc("2020-01-03T00:00:00Z"), Clicks: 4}})
+	total, err := Merge(a, b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"2020-01-01T00:00:00Z 1", "2020-01-02T00:00:00Z 5", "2020-01-03T00:00:00Z 4"}
+	if got := summary(total); !reflect.DeepEqual(want, got) || total.Total() != 10 {
+		t.Fatalf("want merged series %v got %v", want, got)
+	}
+	if len(a.Points) != 2 || a.Points[1].Clicks != 2 {
+		t.Fatalf("want merged series unchanged got %v", a.Points)
+	}
+
+	hourly, _ := NewSeries(Hour, nil)
+	if _, err := Merge(a, hourly); err == nil {
+		t.Fatalf("want error merging series of different units")
+	}
+	if _, err := Merge(); err == nil {
+		t.Fatalf("want error merging nothing")
+	}
+}
+
+func TestCumulativeAndMovingAverage(t *testing.T) {
+	var points []Point
+	for i, clicks := range []int{1, 2, 3, 4, 5} {
+		points = append(points, Point{Time: utc("2020-01-01T00:00:00Z").AddDate(0, 0, i), Clicks: clicks})
+	}
+	s, _ := NewSeries(Day, points)
+
+	cumulative := Cumulative(s)
+	var got []int
+	for _, p := range cumulative.Points {
+		got = append(got, p.Clicks)
+	}
+	if !reflect.DeepEqual(got, []int{1, 3, 6, 10, 15}) {
+		t.Fatalf("unexpected cumulative sums %v", got)
+	}
+
+	averages := MovingAverage(s, 3)
+	want := []Average{
+		{Time: points[2].Time, Value: 2},
+		{Time: points[3].Time, Value: 3},
+		{Time: points[4].Time, Value: 4},
+	}
+	if !reflect.DeepEqual(want, averages) {
+		t.Fatalf("want moving averages %v got %v", want, averages)
+	}
+	if averages := MovingAverage(s, 6); averages != nil {
+		t.Fatalf("want no averages for a window longer than the series got %v", averages)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	s, _ := NewSeries(Day, []Point{
+		{Time: utc("2020-01-01T00:00:00Z"), Clicks: 0},
+		{Time: utc("2020-01-02T00:00:00Z"), Clicks: 2},
+		{Time: utc("2020-01-03T00:00:00Z"), Clicks: 3},
+		{Time: utc("2020-01-04T00:00:00Z"), Clicks: 4},
+		{Time: utc("2020-01-05T00:00:00Z"), Clicks: 5},
+	})
+	c, err := Compare(s, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if percent, ok := c.Percent(); c.Current != 9 || c.Previous != 5 || c.Delta() != 4 || !ok || percent != 80 {
+		t.Fatalf("unexpected comparison %+v %v", c, percent)
+	}
+	if _, err := Compare(s, 3); err == nil {
+		t.Fatalf("want error for too short series")
+	}
+
+	changes := PeriodOverPeriod(s, 1)
+	if len(changes) != 4 || changes[0].Current != 2 || changes[0].Previous != 0 || !changes[0].Time.Equal(utc("2020-01-02T00:00:00Z")) {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+	if _, ok := changes[0].Percent(); ok {
+		t.Fatalf("want no percent change from zero clicks")
+	}
+	if percent, _ := changes[3].Percent(); percent != 25 {
+		t.Fatalf("want 25%% change got %v", percent)
+	}
+}
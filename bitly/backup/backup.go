@@ -0,0 +1,137 @@
+// Package backup saves a group with its Bitlinks to a versioned Bundle and
+// restores the Bitlinks of a Bundle into a group.
+//
+//	b, err := backup.Backup(ctx, client, "Ba1b2c3d4e5", backup.Options{Clicks: true})
+//	err = b.WriteTar(f)
+//
+//	b, err := backup.ReadBundle(f)
+//	report, err := backup.Restore(ctx, client, b, backup.RestoreOptions{GroupGUID: "Bf6g7h8i9j0"})
+//
+// Bitly has no API to create deep links or clicks, so they are kept in the
+// bundle for reference and are not restored.
+package backup
+
+import (
+	"context"
+	"github.com/lcd1232/go-bitly/bitly"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// FormatVersion is the version of bundles written by this package,
+// ReadBundle refuses bundles of later versions
+const FormatVersion = 1
+
+// ErrUnsupportedVersion is returned by ReadBundle for bundles written by a
+// later version of this package
+var ErrUnsupportedVersion = errors.New("backup: unsupported bundle version")
+
+// Bundle is a snapshot of a group
+type Bundle struct {
+	Version     int                    `json:"version"`
+	CreatedAt   time.Time              `json:"created_at"`
+	Group       bitly.Group            `json:"group"`
+	Preferences bitly.GroupPreferences `json:"preferences"`
+	Links       []Link                 `json:"links"`
+}
+
+// Link is a Bitlink of the group, with tags, deep links and custom
+// back-halves, and its click summary when Options.Clicks is set
+type Link struct {
+	Bitlink bitly.Bitlink `json:"bitlink"`
+	Clicks  *ClickSummary `json:"clicks,omitempty"`
+}
+
+// ClickSummary is the total of clicks of a Bitlink over Units units
+// up to UnitReference
+type ClickSummary struct {
+	TotalClicks   int    `json:"total_clicks"`
+	Unit          string `json:"unit"`
+	Units         int    `json:"units"`
+	UnitReference string `json:"unit_reference"`
+}
+
+// Options configures Backup
+type Options struct {
+	// Clicks adds click summaries of Bitlinks, it costs a request per Bitlink
+	Clicks bool
+	// ClickUnit and ClickUnits are the period of click summaries,
+	// every day Bitly has clicks of by default
+	ClickUnit  string
+	ClickUnits int
+	// Now returns the creation time of the bundle, time.Now by default
+	Now func() time.Time
+}
+
+// Backup fetches the group, its preferences and its Bitlinks, archived ones
+// included, and returns them as a Bundle
+func Backup(ctx context.Context, client *bitly.Client, groupGUID string, options Options) (*Bundle, error) {
+	now := time.Now
+	if options.Now != nil {
+		now = options.Now
+	}
+	group, _, err := client.Groups.GetGroup(ctx, groupGUID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot get group %s", groupGUID)
+	}
+	prefs, _, err := client.Groups.GetGroupPreferences(ctx, groupGUID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot get preferences of group %s", groupGUID)
+	}
+	b := &Bundle{
+		Version:     FormatVersion,
+		CreatedAt:   now().UTC(),
+		Group:       *group,
+		Preferences: *prefs,
+		Links:       []Link{},
+	}
+
+	params := &bitly.GetBitlinksByGroupQueryParams{Size: 100, Archived: bitly.BothOption}
+	paginator, err := client.Groups.GetBitlinksByGroupPaginator(ctx, groupGUID, params)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if err := paginator.Get(); err != nil {
+			return nil, errors.Wrapf(err, "cannot list Bitlinks of group %s", groupGUID)
+		}
+		for _, bitlink := range paginator.Resp.Links {
+			b.Links = append(b.Links, Link{Bitlink: bitlink})
+		}
+		if !paginator.Next() {
+			break
+		}
+	}
+
+	if options.Clicks {
+		for i := range b.Links {
+			summary, err := clickSummary(ctx, client, b.Links[i].Bitlink.ID, options)
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot get clicks of %s", b.Links[i].Bitlink.ID)
+			}
+			b.Links[i].Clicks = summary
+		}
+	}
+	return b, nil
+}
+
+type clickSummaryParams struct {
+	Unit  string `url:"unit,omitempty"`
+	Units int    `url:"units,omitempty"`
+}
+
+func clickSummary(ctx context.Context, client *bitly.Client, bitlink string, options Options) (*ClickSummary, error) {
+	id, err := bitly.ParseBitlink(bitlink)
+	if err != nil {
+		return nil, err
+	}
+	params := &clickSummaryParams{Unit: options.ClickUnit, Units: options.ClickUnits}
+	if params.Units == 0 {
+		params.Units = -1
+	}
+	summary := &ClickSummary{}
+	if _, err := client.Call(ctx, "GET", "/v4/bitlinks/"+id.Path()+"/clicks/summary", params, nil, summary); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
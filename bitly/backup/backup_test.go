@@ -0,0 +1,188 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"github.com/lcd1232/go-bitly/bitly/bitlytest"
+	"github.com/pkg/errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newServer(t *testing.T) *bitlytest.Server {
+	s := bitlytest.NewServer()
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/a", LongURL: "https://example.com/a", Title: "a", Tags: []string{"x"}, CustomBitlinks: []string{"bit.ly/spring"}})
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/b", LongURL: "https://example.com/b", Archived: true})
+	if err := s.AddClicks("bit.ly/a", time.Now(), 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return s
+}
+
+func TestBackup(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	b, err := Backup(context.Background(), s.Client(), bitlytest.DefaultGroupGUID, Options{Clicks: true, Now: func() time.Time { return created }})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.Version != FormatVersion || !b.CreatedAt.Equal(created) {
+		t.Fatalf("unexpected bundle header %d %v", b.Version, b.CreatedAt)
+	}
+	if b.Group.GUID != bitlytest.DefaultGroupGUID || b.Preferences.DomainPreference != bitlytest.DefaultDomain {
+		t.Fatalf("want group and preferences got %#v %#v", b.Group, b.Preferences)
+	}
+	links := map[string]Link{}
+	for _, link := range b.Links {
+		links[link.Bitlink.ID] = link
+	}
+	a, ok := links["bit.ly/a"]
+	if len(links) != 2 || !ok || !links["bit.ly/b"].Bitlink.Archived {
+		t.Fatalf("want every Bitlink with archived ones got %#v", b.Links)
+	}
+	if !reflect.DeepEqual(a.Bitlink.Tags, []string{"x"}) || len(a.Bitlink.CustomBitlinks) != 1 {
+		t.Fatalf("want tags and custom bitlinks got %#v", a.Bitlink)
+	}
+	if a.Clicks == nil || a.Clicks.TotalClicks != 3 {
+		t.Fatalf("want click summary got %#v", a.Clicks)
+	}
+
+	for name, write := range map[string]func(*bytes.Buffer) error{
+		"json": func(buf *bytes.Buffer) error { return b.WriteJSON(buf) },
+		"tar":  func(buf *bytes.Buffer) error { return b.WriteTar(buf) },
+	} {
+		buf := &bytes.Buffer{}
+		if err := write(buf); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		got, err := ReadBundle(buf)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if !reflect.DeepEqual(b, got) {
+			t.Fatalf("%s: want bundle\n%#v\ngot\n%#v", name, b, got)
+		}
+	}
+}
+
+func TestReadBundle_Errors(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		data    string
+		wantErr string
+	}{
+		{desc: "empty", data: "", wantErr: "cannot read bundle: EOF"},
+		{desc: "later version", data: `{"version": 2}`, wantErr: "version 2: backup: unsupported bundle version"},
+		{desc: "no version", data: ` {"links": []}`, wantErr: "invalid bundle: version is missing"},
+		{desc: "not a bundle", data: "links", wantErr: "invalid bundle"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := ReadBundle(strings.NewReader(tc.data))
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("want error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+	if _, err := ReadBundle(strings.NewReader(`{"version": 2}`)); errors.Cause(err) != ErrUnsupportedVersion {
+		t.Fatalf("want ErrUnsupportedVersion got %v", err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+	s.AddGroup(bitlytest.Group{GUID: "Btarget"})
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/t", GroupGUID: "Btarget", LongURL: "https://example.com/b", Title: "renamed"})
+	client := s.Client()
+	ctx := context.Background()
+
+	b, err := Backup(ctx, client, bitlytest.DefaultGroupGUID, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	report, err := Restore(ctx, client, b, RestoreOptions{GroupGUID: "Btarget"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	results := map[string]RestoreResult{}
+	for _, result := range report.Results {
+		results[result.Source] = result
+	}
+	a := results["bit.ly/a"]
+	if a.Status != StatusCreated || a.Err != nil || !reflect.DeepEqual(a.Conflicts, []string{"custom bitlink bit.ly/spring is used by another Bitlink"}) {
+		t.Fatalf("want created Bitlink with custom bitlink conflict got %#v", a)
+	}
+	restored, ok := s.Bitlink(a.Bitlink)
+	if !ok || restored.GroupGUID != "Btarget" || restored.Title != "a" || !reflect.DeepEqual(restored.Tags, []string{"x"}) {
+		t.Fatalf("want Bitlink restored in the target group got %#v", restored)
+	}
+	wantConflicts := []string{`title is "renamed" in the group and "" in the bundle`, "archived is false in the group and true in the bundle"}
+	if r := results["bit.ly/b"]; r.Status != StatusExisting || r.Bitlink != "bit.ly/t" || !reflect.DeepEqual(r.Conflicts, wantConflicts) {
+		t.Fatalf("want existing Bitlink with conflicts got %#v", r)
+	}
+	if report.Count(StatusCreated) != 1 || len(report.Conflicts()) != 2 {
+		t.Fatalf("unexpected report %#v", report)
+	}
+
+	report, err = Restore(ctx, client, b, RestoreOptions{GroupGUID: "Btarget", Overwrite: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Count(StatusExisting) != 1 || report.Count(StatusUpdated) != 1 || report.Count(StatusCreated) != 0 {
+		t.Fatalf("want repeated restore to reuse Bitlinks got %#v", report.Results)
+	}
+	if link, _ := s.Bitlink("bit.ly/t"); link.Title != "" || !link.Archived {
+		t.Fatalf("want existing Bitlink overwritten got %#v", link)
+	}
+
+	if _, err := Restore(ctx, client, b, RestoreOptions{GroupGUID: "Bmissing"}); err == nil {
+		t.Fatalf("want error for missing target group")
+	}
+}
+
+func TestRestore_Domain(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+	client := s.Client()
+	ctx := context.Background()
+
+	b, err := Backup(ctx, client, bitlytest.DefaultGroupGUID, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.AddGroup(bitlytest.Group{GUID: "Btarget"})
+	report, err := Restore(ctx, client, b, RestoreOptions{GroupGUID: "Btarget", Domain: "j.mp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a := restoreResult(report, "bit.ly/a")
+	if a.Status != StatusCreated || a.Err != nil || len(a.Conflicts) != 0 {
+		t.Fatalf("want Bitlink created without conflicts got %#v", a)
+	}
+	restored, _ := s.Bitlink(a.Bitlink)
+	if !strings.HasPrefix(restored.ID, "j.mp/") || !reflect.DeepEqual(restored.CustomBitlinks, []string{"j.mp/spring"}) {
+		t.Fatalf("want Bitlink and back-half on the target domain got %#v", restored)
+	}
+
+	report, err = Restore(ctx, client, b, RestoreOptions{GroupGUID: "Btarget", Domain: "j.mp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a := restoreResult(report, "bit.ly/a"); a.Status != StatusExisting || len(a.Conflicts) != 0 {
+		t.Fatalf("want repeated restore to keep the back-half got %#v", a)
+	}
+}
+
+func restoreResult(report *RestoreReport, source string) RestoreResult {
+	for _, result := range report.Results {
+		if result.Source == source {
+			return result
+		}
+	}
+	return RestoreResult{}
+}
@@ -0,0 +1,150 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+)
+
+// Names of tar bundle entries, bundle.json is the bundle without links
+// and links.jsonl has a Link per line
+const (
+	tarBundleName = "bundle.json"
+	tarLinksName  = "links.jsonl"
+)
+
+// WriteJSON writes the bundle as a single JSON document
+func (b *Bundle) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// WriteTar writes the bundle as a tar archive with bundle.json and
+// links.jsonl, a JSON document of a link per line
+func (b *Bundle) WriteTar(w io.Writer) error {
+	meta := *b
+	meta.Links = nil
+	metaData, err := json.MarshalIndent(&meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	links := &bytes.Buffer{}
+	enc := json.NewEncoder(links)
+	for _, link := range b.Links {
+		if err := enc.Encode(link); err != nil {
+			return err
+		}
+	}
+
+	tw := tar.NewWriter(w)
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{tarBundleName, append(metaData, '\n')},
+		{tarLinksName, links.Bytes()},
+	} {
+		header := &tar.Header{
+			Name:    entry.name,
+			Mode:    0644,
+			Size:    int64(len(entry.data)),
+			ModTime: b.CreatedAt,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(entry.data); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// ReadBundle reads a bundle written by WriteJSON or WriteTar
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	br := bufio.NewReader(r)
+	for {
+		c, err := br.Peek(1)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read bundle")
+		}
+		switch c[0] {
+		case ' ', '\t', '\r', '\n':
+			br.Discard(1)
+			continue
+		case '{':
+			b := &Bundle{}
+			if err := json.NewDecoder(br).Decode(b); err != nil {
+				return nil, errors.Wrap(err, "invalid bundle")
+			}
+			return b, checkVersion(b)
+		}
+		return readTar(br)
+	}
+}
+
+func readTar(r io.Reader) (*Bundle, error) {
+	var b *Bundle
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid bundle")
+		}
+		switch header.Name {
+		case tarBundleName:
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			links := []Link{}
+			if b != nil {
+				links = b.Links
+			}
+			b = &Bundle{}
+			if err := json.Unmarshal(data, b); err != nil {
+				return nil, errors.Wrapf(err, "invalid %s", tarBundleName)
+			}
+			if err := checkVersion(b); err != nil {
+				return nil, err
+			}
+			b.Links = links
+		case tarLinksName:
+			if b == nil {
+				// links.jsonl is read before bundle.json only in archives
+				// repacked by other tools
+				b = &Bundle{Links: []Link{}}
+			}
+			dec := json.NewDecoder(tr)
+			for dec.More() {
+				var link Link
+				if err := dec.Decode(&link); err != nil {
+					return nil, errors.Wrapf(err, "invalid %s", tarLinksName)
+				}
+				b.Links = append(b.Links, link)
+			}
+		}
+	}
+	if b == nil || b.Version == 0 {
+		return nil, errors.Errorf("invalid bundle: %s is missing", tarBundleName)
+	}
+	return b, nil
+}
+
+func checkVersion(b *Bundle) error {
+	if b.Version < 1 {
+		return errors.New("invalid bundle: version is missing")
+	}
+	if b.Version > FormatVersion {
+		return errors.Wrapf(ErrUnsupportedVersion, "version %d", b.Version)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"github.com/lcd1232/go-bitly/bitly"
+	"github.com/pkg/errors"
+	"net/http"
+	"strings"
+)
+
+// Status is the outcome of restoring a Link
+type Status string
+
+const (
+	// StatusCreated means the Bitlink was created in the target group
+	StatusCreated Status = "created"
+	// StatusExisting means the target group already had a Bitlink of the
+	// long URL, differences are reported as conflicts
+	StatusExisting Status = "existing"
+	// StatusUpdated means an existing Bitlink was updated, see RestoreOptions.Overwrite
+	StatusUpdated Status = "updated"
+	// StatusFailed means the Bitlink could not be restored, see RestoreResult.Err
+	StatusFailed Status = "failed"
+)
+
+// RestoreOptions configures Restore
+type RestoreOptions struct {
+	// GroupGUID is the target group, it is required
+	GroupGUID string
+	// Domain is the short domain of created Bitlinks, the domain of the
+	// backed up Bitlink by default. Custom back-halves are added on Domain
+	// too, bit.ly/spring is restored as Domain/spring.
+	Domain string
+	// Overwrite updates title, tags and archived of Bitlinks the target group
+	// already has instead of reporting differences as conflicts
+	Overwrite bool
+	// SkipArchived does not restore archived Bitlinks
+	SkipArchived bool
+}
+
+// RestoreResult is the outcome of restoring a Link
+type RestoreResult struct {
+	// Source is the ID of the Bitlink in the bundle
+	Source string
+	// Bitlink is the ID of the Bitlink in the target group
+	Bitlink string
+	Status  Status
+	// Conflicts describe what could not be restored as it was backed up,
+	// like a custom back-half used by another Bitlink
+	Conflicts []string
+	Err       error
+}
+
+// RestoreReport lists results of restored links in the order of the bundle
+type RestoreReport struct {
+	Results []RestoreResult
+}
+
+// Count returns the number of results with status
+func (r *RestoreReport) Count(status Status) int {
+	n := 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Conflicts returns results with conflicts
+func (r *RestoreReport) Conflicts() []RestoreResult {
+	var conflicts []RestoreResult
+	for _, result := range r.Results {
+		if len(result.Conflicts) > 0 {
+			conflicts = append(conflicts, result)
+		}
+	}
+	return conflicts
+}
+
+// Restore recreates Bitlinks of the bundle in the target group. Bitlinks are
+// matched by long URL and domain, so a restore can be repeated. Failures
+// of single links are reported in their results, the returned error means
+// the target group could not be read or ctx is done.
+func Restore(ctx context.Context, client *bitly.Client, b *Bundle, options RestoreOptions) (*RestoreReport, error) {
+	if options.GroupGUID == "" {
+		return nil, errors.New("backup: RestoreOptions.GroupGUID is required")
+	}
+	existing, err := targetBitlinks(ctx, client, options.GroupGUID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot list Bitlinks of group %s", options.GroupGUID)
+	}
+
+	report := &RestoreReport{}
+	for _, link := range b.Links {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		source := link.Bitlink
+		if options.SkipArchived && source.Archived {
+			continue
+		}
+		result := RestoreResult{Source: source.ID}
+		if err := restoreLink(ctx, client, source, existing, options, &result); err != nil {
+			result.Status, result.Err = StatusFailed, err
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// targetBitlinks returns Bitlinks of the group by domain and long URL
+func targetBitlinks(ctx context.Context, client *bitly.Client, group string) (map[string]*bitly.Bitlink, error) {
+	all, err := bitly.AllBitlinksByGroup(ctx, client.Groups, group)
+	if err != nil {
+		return nil, err
+	}
+	links := map[string]*bitly.Bitlink{}
+	for i := range all {
+		link := &all[i]
+		if id, err := bitly.ParseBitlink(link.ID); err == nil {
+			links[linkKey(id.Domain, link.LongURL)] = link
+		}
+	}
+	return links, nil
+}
+
+func linkKey(domain, longURL string) string {
+	return strings.ToLower(domain) + " " + longURL
+}
+
+func restoreLink(ctx context.Context, client *bitly.Client, source bitly.Bitlink, existing map[string]*bitly.Bitlink, options RestoreOptions, result *RestoreResult) error {
+	domain := options.Domain
+	if domain == "" {
+		id, err := bitly.ParseBitlink(source.ID)
+		if err != nil {
+			return err
+		}
+		domain = id.Domain
+	}
+
+	target := existing[linkKey(domain, source.LongURL)]
+	if target == nil {
+		created, _, err := client.Bitlinks.Shorten(ctx, &bitly.ShortenOptions{
+			LongURL:   source.LongURL,
+			Domain:    domain,
+			GroupGUID: options.GroupGUID,
+		})
+		if err != nil {
+			return err
+		}
+		result.Status, result.Bitlink = StatusCreated, created.ID
+		existing[linkKey(domain, source.LongURL)] = created
+
+		update := &bitly.BitlinkUpdateOptions{}
+		if source.Title != "" {
+			update.Title = bitly.String(source.Title)
+		}
+		if len(source.Tags) > 0 {
+			update.Tags = bitly.Strings(bitly.NormalizeTags(source.Tags))
+		}
+		if source.Archived {
+			update.Archived = bitly.Bool(true)
+		}
+		if update.Title != nil || update.Tags != nil || update.Archived != nil {
+			if _, _, err := client.Bitlinks.Update(ctx, created.ID, update); err != nil {
+				return err
+			}
+		}
+	} else {
+		result.Status, result.Bitlink = StatusExisting, target.ID
+		changes := differences(target, source)
+		if len(changes) > 0 && options.Overwrite {
+			update := &bitly.BitlinkUpdateOptions{
+				Title:    bitly.String(source.Title),
+				Tags:     bitly.Strings(bitly.NormalizeTags(source.Tags)),
+				Archived: bitly.Bool(source.Archived),
+			}
+			if _, _, err := client.Bitlinks.Update(ctx, target.ID, update); err != nil {
+				return err
+			}
+			result.Status = StatusUpdated
+		} else {
+			result.Conflicts = append(result.Conflicts, changes...)
+		}
+	}
+
+	for _, custom := range source.CustomBitlinks {
+		if id, err := bitly.ParseBitlink(custom); err == nil {
+			id.Domain = domain
+			custom = id.String()
+		}
+		if target != nil && hasCustomBitlink(target, custom) {
+			continue
+		}
+		body := map[string]string{"custom_bitlink": custom, "bitlink_id": result.Bitlink}
+		_, err := client.Call(ctx, "POST", "/custom_bitlinks", nil, body, nil)
+		if e, ok := errors.Cause(err).(*bitly.ErrorResponse); ok && e.StatusCode() == http.StatusConflict {
+			result.Conflicts = append(result.Conflicts, fmt.Sprintf("custom bitlink %s is used by another Bitlink", custom))
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "cannot add custom bitlink %s", custom)
+		}
+	}
+	return nil
+}
+
+// differences describes fields of the target Bitlink which differ from the backed up one
+func differences(target *bitly.Bitlink, source bitly.Bitlink) []string {
+	var changes []string
+	if target.Title != source.Title {
+		changes = append(changes, fmt.Sprintf("title is %q in the group and %q in the bundle", target.Title, source.Title))
+	}
+	if from, to := bitly.NormalizeTags(target.Tags), bitly.NormalizeTags(source.Tags); strings.Join(from, "\x00") != strings.Join(to, "\x00") {
+		changes = append(changes, fmt.Sprintf("tags are [%s] in the group and [%s] in the bundle", strings.Join(from, ", "), strings.Join(to, ", ")))
+	}
+	if target.Archived != source.Archived {
+		changes = append(changes, fmt.Sprintf("archived is %t in the group and %t in the bundle", target.Archived, source.Archived))
+	}
+	return changes
+}
+
+func hasCustomBitlink(link *bitly.Bitlink, custom string) bool {
+	want, err := bitly.ParseBitlink(custom)
+	if err != nil {
+		return false
+	}
+	for _, c := range link.CustomBitlinks {
+		if id, err := bitly.ParseBitlink(c); err == nil && id == want {
+			return true
+		}
+	}
+	return false
+}
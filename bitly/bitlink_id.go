@@ -0,0 +1,120 @@
+package bitly
+
+import (
+	"github.com/pkg/errors"
+	"net/url"
+	"strings"
+)
+
+// bitlyDomains are short domains of Bitly available to every group
+var bitlyDomains = []string{"bit.ly", "j.mp"}
+
+// BitlinkID identifies a Bitlink by its short domain and hash, like bit.ly/2Ld3Bx9
+type BitlinkID struct {
+	// Domain is the lower cased short domain, like bit.ly or a branded domain
+	Domain string
+	// Hash is the back-half of the Bitlink, it is case sensitive
+	Hash string
+}
+
+// ParseBitlink parses a Bitlink like bit.ly/2Ld3Bx9 or https://bit.ly/2Ld3Bx9.
+// The domain is lower cased, URL escaping and a trailing slash are removed.
+// When domains are given, like Group.BSDS, the domain must be one of them or
+// a Bitly domain, otherwise any valid host name is accepted as a branded domain.
+func ParseBitlink(s string, domains ...string) (BitlinkID, error) {
+	raw := strings.TrimSpace(s)
+	if i := strings.Index(raw, "://"); i >= 0 {
+		scheme := strings.ToLower(raw[:i])
+		if scheme != "http" && scheme != "https" {
+			return BitlinkID{}, errors.Errorf("invalid bitlink %q: unsupported scheme %s", s, scheme)
+		}
+		raw = raw[i+3:]
+	}
+	// Bitlinks copied from API paths are escaped, like bit.ly%2F2Ld3Bx9
+	if unescaped, err := url.PathUnescape(raw); err == nil {
+		raw = unescaped
+	}
+	if strings.ContainsAny(raw, "?#") {
+		return BitlinkID{}, errors.Errorf("invalid bitlink %q: query and fragment are not allowed", s)
+	}
+	raw = strings.TrimSuffix(raw, "/")
+	i := strings.Index(raw, "/")
+	if i < 0 {
+		return BitlinkID{}, errors.Errorf("invalid bitlink %q: hash is missing", s)
+	}
+	domain, hash := strings.ToLower(raw[:i]), raw[i+1:]
+
+	if err := validateDomain(domain); err != nil {
+		return BitlinkID{}, errors.Errorf("invalid bitlink %q: %v", s, err)
+	}
+	if len(domains) > 0 && !containsDomain(domain, bitlyDomains) && !containsDomain(domain, domains) {
+		return BitlinkID{}, errors.Errorf("invalid bitlink %q: domain %s is not available", s, domain)
+	}
+	if err := validateHash(hash); err != nil {
+		return BitlinkID{}, errors.Errorf("invalid bitlink %q: %v", s, err)
+	}
+	return BitlinkID{Domain: domain, Hash: hash}, nil
+}
+
+// String returns the form used by the API, like bit.ly/2Ld3Bx9
+func (b BitlinkID) String() string {
+	return b.Domain + "/" + b.Hash
+}
+
+// Path returns URL escaped form used in paths of Bitlink endpoints, like bit.ly%2F2Ld3Bx9
+func (b BitlinkID) Path() string {
+	return url.PathEscape(b.String())
+}
+
+// IsZero reports whether b is the zero BitlinkID
+func (b BitlinkID) IsZero() bool {
+	return b.Domain == "" && b.Hash == ""
+}
+
+func validateDomain(domain string) error {
+	if domain == "" {
+		return errors.New("domain is missing")
+	}
+	if len(domain) > 253 {
+		return errors.New("domain is too long")
+	}
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return errors.Errorf("domain %s has no top level domain", domain)
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 {
+			return errors.Errorf("domain %s has invalid label %q", domain, label)
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return errors.Errorf("domain %s has invalid label %q", domain, label)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+				return errors.Errorf("domain %s has invalid character %q", domain, r)
+			}
+		}
+	}
+	return nil
+}
+
+func validateHash(hash string) error {
+	if hash == "" {
+		return errors.New("hash is missing")
+	}
+	for _, r := range hash {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return errors.Errorf("hash %s has invalid character %q", hash, r)
+		}
+	}
+	return nil
+}
+
+func containsDomain(domain string, domains []string) bool {
+	for _, d := range domains {
+		if strings.EqualFold(domain, strings.TrimSpace(d)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package bitly
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseBitlink(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		domains  []string
+		want     BitlinkID
+		wantPath string
+		wantErr  string
+	}{
+		{
+			desc:     "api form",
+			input:    "bit.ly/2Ld3Bx9",
+			want:     BitlinkID{Domain: "bit.ly", Hash: "2Ld3Bx9"},
+			wantPath: "bit.ly%2F2Ld3Bx9",
+		},
+		{
+			desc:     "https link",
+			input:    "https://bit.ly/2Ld3Bx9",
+			want:     BitlinkID{Domain: "bit.ly", Hash: "2Ld3Bx9"},
+			wantPath: "bit.ly%2F2Ld3Bx9",
+		},
+		{
+			desc:     "http link with upper case scheme and domain",
+			input:    "HTTP://BIT.LY/2Ld3Bx9",
+			want:     BitlinkID{Domain: "bit.ly", Hash: "2Ld3Bx9"},
+			wantPath: "bit.ly%2F2Ld3Bx9",
+		},
+		{
+			desc:     "trailing slash and spaces",
+			input:    "  bit.ly/2Ld3Bx9/ \n",
+			want:     BitlinkID{Domain: "bit.ly", Hash: "2Ld3Bx9"},
+			wantPath: "bit.ly%2F2Ld3Bx9",
+		},
+		{
+			desc:     "escaped path form",
+			input:    "bit.ly%2F2Ld3Bx9",
+			want:     BitlinkID{Domain: "bit.ly", Hash: "2Ld3Bx9"},
+			wantPath: "bit.ly%2F2Ld3Bx9",
+		},
+		{
+			desc:     "custom back-half",
+			input:    "j.mp/spring_sale-2019",
+			want:     BitlinkID{Domain: "j.mp", Hash: "spring_sale-2019"},
+			wantPath: "j.mp%2Fspring_sale-2019",
+		},
+		{
+			desc:     "branded domain without restriction",
+			input:    "https://on.natgeo.com/WmsHnP",
+			want:     BitlinkID{Domain: "on.natgeo.com", Hash: "WmsHnP"},
+			wantPath: "on.natgeo.com%2FWmsHnP",
+		},
+		{
+			desc:     "branded domain of group",
+			input:    "Es.Pn/xyz",
+			domains:  []string{"other.co", "es.pn"},
+			want:     BitlinkID{Domain: "es.pn", Hash: "xyz"},
+			wantPath: "es.pn%2Fxyz",
+		},
+		{
+			desc:     "bitly domain with group domains",
+			input:    "bit.ly/abc",
+			domains:  []string{"es.pn"},
+			want:     BitlinkID{Domain: "bit.ly", Hash: "abc"},
+			wantPath: "bit.ly%2Fabc",
+		},
+		{
+			desc:     "domain with digits and hyphen",
+			input:    "go-1.co/A1",
+			want:     BitlinkID{Domain: "go-1.co", Hash: "A1"},
+			wantPath: "go-1.co%2FA1",
+		},
+		{
+			desc:    "domain not in group domains",
+			input:   "es.pn/xyz",
+			domains: []string{"other.co"},
+			wantErr: "domain es.pn is not available",
+		},
+		{
+			desc:    "empty",
+			input:   "",
+			wantErr: "hash is missing",
+		},
+		{
+			desc:    "domain only",
+			input:   "bit.ly",
+			wantErr: "hash is missing",
+		},
+		{
+			desc:    "domain with slash",
+			input:   "https://bit.ly/",
+			wantErr: "hash is missing",
+		},
+		{
+			desc:    "hash only",
+			input:   "/2Ld3Bx9",
+			wantErr: "domain is missing",
+		},
+		{
+			desc:    "unsupported scheme",
+			input:   "ftp://bit.ly/abc",
+			wantErr: "unsupported scheme ftp",
+		},
+		{
+			desc:    "query",
+			input:   "bit.ly/abc?utm_source=x",
+			wantErr: "query and fragment are not allowed",
+		},
+		{
+			desc:    "fragment",
+			input:   "bit.ly/abc#top",
+			wantErr: "query and fragment are not allowed",
+		},
+		{
+			desc:    "nested path",
+			input:   "bit.ly/abc/def",
+			wantErr: `has invalid character '/'`,
+		},
+		{
+			desc:    "hash with space",
+			input:   "bit.ly/ab c",
+			wantErr: `has invalid character ' '`,
+		},
+		{
+			desc:    "non ascii hash",
+			input:   "bit.ly/café",
+			wantErr: `has invalid character 'é'`,
+		},
+		{
+			desc:    "port",
+			input:   "bit.ly:443/abc",
+			wantErr: `has invalid character ':'`,
+		},
+		{
+			desc:    "user info",
+			input:   "user@bit.ly/abc",
+			wantErr: `has invalid character '@'`,
+		},
+		{
+			desc:    "no top level domain",
+			input:   "localhost/abc",
+			wantErr: "domain localhost has no top level domain",
+		},
+		{
+			desc:    "empty label",
+			input:   "bit..ly/abc",
+			wantErr: `has invalid label ""`,
+		},
+		{
+			desc:    "label starting with hyphen",
+			input:   "-bit.ly/abc",
+			wantErr: `has invalid label "-bit"`,
+		},
+		{
+			desc:    "label ending with hyphen",
+			input:   "bit-.ly/abc",
+			wantErr: `has invalid label "bit-"`,
+		},
+		{
+			desc:    "too long label",
+			input:   strings.Repeat("a", 64) + ".ly/abc",
+			wantErr: "has invalid label",
+		},
+		{
+			desc:    "too long domain",
+			input:   strings.Repeat("a.", 127) + "ly/abc",
+			wantErr: "domain is too long",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := ParseBitlink(tc.input, tc.domains...)
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("want error %v got %v", tc.wantErr, err)
+				}
+				if !got.IsZero() {
+					t.Fatalf("want zero bitlink got %v", got)
+				}
+				return
+			}
+			if got != tc.want {
+				t.Fatalf("want %#v got %#v", tc.want, got)
+			}
+			if got.String() != tc.want.Domain+"/"+tc.want.Hash {
+				t.Fatalf("unexpected string %v", got.String())
+			}
+			if got.Path() != tc.wantPath {
+				t.Fatalf("want path %v got %v", tc.wantPath, got.Path())
+			}
+		})
+	}
+}
+
+func TestBitlinksClient_UpdateEscapedPath(t *testing.T) {
+	var gotPath string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		w.Write([]byte(`{"id":"bit.ly/abc"}`))
+	}))
+	defer s.Close()
+
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL
+	if _, _, err := c.Bitlinks.Update(context.Background(), "https://bit.ly/abc/", &BitlinkUpdateOptions{Title: String("new")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotPath != "/v4/bitlinks/bit.ly%2Fabc" {
+		t.Fatalf("want escaped path got %v", gotPath)
+	}
+	if _, _, err := c.Bitlinks.Update(context.Background(), "bit.ly/a b", &BitlinkUpdateOptions{Title: String("new")}); err == nil {
+		t.Fatalf("want invalid bitlink error")
+	}
+}
@@ -0,0 +1,112 @@
+package bitly
+
+import (
+	"context"
+	"sort"
+)
+
+type BitlinksService interface {
+	Shorten(ctx context.Context, options *ShortenOptions) (*Bitlink, *Response, error)
+	Update(ctx context.Context, bitlink string, options *BitlinkUpdateOptions) (*Bitlink, *Response, error)
+}
+
+type BitlinksClient struct {
+	client *Client
+}
+
+// ShortenOptions used by sending body to Shorten
+type ShortenOptions struct {
+	LongURL   string `json:"long_url"`
+	Domain    string `json:"domain,omitempty"`
+	GroupGUID string `json:"group_guid,omitempty"`
+}
+
+// BitlinkUpdateOptions used by sending body to Update.
+// Only non-nil fields are sent, use String, Strings and Bool to set them.
+// An empty Title or Tags clears the title or tags of the Bitlink.
+type BitlinkUpdateOptions struct {
+	Title    *string   `json:"title,omitempty"`
+	Archived *bool     `json:"archived,omitempty"`
+	Tags     *[]string `json:"tags,omitempty"`
+	LongURL  string    `json:"long_url,omitempty"`
+}
+
+// Bool returns a pointer to v, useful for optional fields like BitlinkUpdateOptions.Archived
+func Bool(v bool) *bool {
+	return &v
+}
+
+// String returns a pointer to v, useful for optional fields like BitlinkUpdateOptions.Title
+func String(v string) *string {
+	return &v
+}
+
+// Strings returns a pointer to v, useful for optional fields like BitlinkUpdateOptions.Tags.
+// A nil v is sent as an empty list.
+func Strings(v []string) *[]string {
+	if v == nil {
+		v = []string{}
+	}
+	return &v
+}
+
+// NormalizeTags returns the unique tags sorted, Bitly does not keep the order
+// of tags so normalized tags can be compared. The result is never nil.
+func NormalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, tag := range tags {
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// Shorten converts a long url to a Bitlink
+//
+// see - http://dev.bitly.com/v4/#operation/createBitlink
+func (bc *BitlinksClient) Shorten(ctx context.Context, options *ShortenOptions) (*Bitlink, *Response, error) {
+	if options == nil {
+		return nil, nil, errOptionsRequired
+	}
+	if options.LongURL == "" {
+		return nil, nil, &errorParameter{paramName: "long_url"}
+	}
+	ctx = withOperation(ctx, "Bitlinks.Shorten", "/v4/shorten")
+	path := versioned("shorten")
+	linkResp := &Bitlink{}
+
+	resp, err := bc.client.post(ctx, path, options, linkResp)
+	if !decoded(err) {
+		return nil, resp, err
+	}
+	return linkResp, resp, err
+}
+
+// Update updates fields of a Bitlink
+//
+// see - http://dev.bitly.com/v4/#operation/updateBitlink
+func (bc *BitlinksClient) Update(ctx context.Context, bitlink string, options *BitlinkUpdateOptions) (*Bitlink, *Response, error) {
+	if bitlink == "" {
+		return nil, nil, &errorParameter{paramName: "bitlink"}
+	}
+	if options == nil {
+		return nil, nil, errOptionsRequired
+	}
+	ctx = withOperation(ctx, "Bitlinks.Update", "/v4/bitlinks/{bitlink}")
+	id, err := ParseBitlink(bitlink)
+	if err != nil {
+		return nil, nil, err
+	}
+	path := versioned("/bitlinks/" + id.Path())
+	linkResp := &Bitlink{}
+
+	resp, err := bc.client.patch(ctx, path, options, linkResp)
+	if !decoded(err) {
+		return nil, resp, err
+	}
+	return linkResp, resp, err
+}
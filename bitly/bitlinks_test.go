@@ -0,0 +1,195 @@
+package bitly
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBitlinksClient_Shorten(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		responseCode int
+		responseBody string
+		options      *ShortenOptions
+		wantErr      string
+		wantResult   *Bitlink
+	}{
+		{
+			desc:         "ok response",
+			responseCode: http.StatusCreated,
+			responseBody: `{"created_at":"2018-07-19T11:15:31+0000","id":"bit.ly/2Ld3Bx9","link":"http://bit.ly/2Ld3Bx9","custom_bitlinks":[],"long_url":"http://example.com/","archived":false,"tags":[],"deeplinks":[],"references":{"group":"https://api-ssl.bitly.com/v4/groups/BcciiJcGgDF"}}`,
+			options:      &ShortenOptions{LongURL: "http://example.com/"},
+			wantResult: &Bitlink{
+				References:     map[string]string{"group": "https://api-ssl.bitly.com/v4/groups/BcciiJcGgDF"},
+				ID:             "bit.ly/2Ld3Bx9",
+				Link:           "http://bit.ly/2Ld3Bx9",
+				CustomBitlinks: []string{},
+				Tags:           []string{},
+				CreatedAt:      JSONDate(time.Date(2018, 7, 19, 11, 15, 31, 0, time.UTC)),
+				DeepLinks:      []DeepLink{},
+				LongURL:        "http://example.com/",
+			},
+		},
+		{
+			desc:         "invalid long url",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"message":"INVALID_ARG_LONG_URL"}`,
+			options:      &ShortenOptions{LongURL: "example"},
+			wantErr:      "400 INVALID_ARG_LONG_URL",
+		},
+		{
+			desc:         "non json error",
+			responseCode: http.StatusBadGateway,
+			responseBody: `<html>Bad Gateway</html>`,
+			options:      &ShortenOptions{LongURL: "http://example.com/"},
+			wantErr:      "502 Bad Gateway",
+		},
+		{
+			desc:    "empty options",
+			options: nil,
+			wantErr: "options cannot be empty",
+		},
+		{
+			desc:    "empty long url",
+			options: &ShortenOptions{},
+			wantErr: "long_url paramater is required",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != "POST" {
+					t.Fatalf("invalid request method: %q", r.Method)
+				}
+				if r.URL.Path != "/v4/shorten" {
+					t.Fatalf("invalid request path: %q", r.URL.Path)
+				}
+				w.WriteHeader(tc.responseCode)
+				w.Write([]byte(tc.responseBody))
+			}))
+			defer s.Close()
+
+			c := NewClient(http.DefaultClient)
+			c.BaseURL = s.URL
+
+			got, _, err := c.Bitlinks.Shorten(context.Background(), tc.options)
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("want error %v got %v", tc.wantErr, err)
+			}
+			if !reflect.DeepEqual(tc.wantResult, got) {
+				t.Fatalf("want link %#v got %#v", tc.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestBitlinksClient_Update(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		responseCode int
+		responseBody string
+		bitlink      string
+		options      *BitlinkUpdateOptions
+		wantBody     string
+		wantErr      string
+		wantResult   *Bitlink
+	}{
+		{
+			desc:         "archive",
+			responseCode: http.StatusOK,
+			responseBody: `{"id":"bit.ly/2Ld3Bx9","archived":true,"title":"Example"}`,
+			bitlink:      "bit.ly/2Ld3Bx9",
+			options:      &BitlinkUpdateOptions{Title: String("Example"), Archived: Bool(true)},
+			wantBody:     `{"title":"Example","archived":true}`,
+			wantResult: &Bitlink{
+				ID:       "bit.ly/2Ld3Bx9",
+				Archived: true,
+				Title:    "Example",
+			},
+		},
+		{
+			desc:         "clear title and tags",
+			responseCode: http.StatusOK,
+			responseBody: `{"id":"bit.ly/2Ld3Bx9","title":"","tags":[]}`,
+			bitlink:      "bit.ly/2Ld3Bx9",
+			options:      &BitlinkUpdateOptions{Title: String(""), Tags: Strings(nil)},
+			wantBody:     `{"title":"","tags":[]}`,
+			wantResult: &Bitlink{
+				ID:   "bit.ly/2Ld3Bx9",
+				Tags: []string{},
+			},
+		},
+		{
+			desc:         "not found",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"message":"NOT_FOUND"}`,
+			bitlink:      "bit.ly/2Ld3Bx9",
+			options:      &BitlinkUpdateOptions{Archived: Bool(false)},
+			wantBody:     `{"archived":false}`,
+			wantErr:      "404 NOT_FOUND",
+		},
+		{
+			desc:    "empty bitlink",
+			options: &BitlinkUpdateOptions{},
+			wantErr: "bitlink paramater is required",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != "PATCH" {
+					t.Fatalf("invalid request method: %q", r.Method)
+				}
+				if r.URL.Path != "/v4/bitlinks/"+tc.bitlink {
+					t.Fatalf("invalid request path: %q", r.URL.Path)
+				}
+				body, _ := ioutil.ReadAll(r.Body)
+				if got := strings.TrimSpace(string(body)); got != tc.wantBody {
+					t.Fatalf("want body %v got %v", tc.wantBody, got)
+				}
+				w.WriteHeader(tc.responseCode)
+				w.Write([]byte(tc.responseBody))
+			}))
+			defer s.Close()
+
+			c := NewClient(http.DefaultClient)
+			c.BaseURL = s.URL
+
+			got, _, err := c.Bitlinks.Update(context.Background(), tc.bitlink, tc.options)
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("want error %v got %v", tc.wantErr, err)
+			}
+			if !reflect.DeepEqual(tc.wantResult, got) {
+				t.Fatalf("want link %#v got %#v", tc.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	testCases := []struct {
+		tags []string
+		want []string
+	}{
+		{tags: nil, want: []string{}},
+		{tags: []string{"b", "a"}, want: []string{"a", "b"}},
+		{tags: []string{"b", "a", "b"}, want: []string{"a", "b"}},
+	}
+	for _, tc := range testCases {
+		if got := NormalizeTags(tc.tags); !reflect.DeepEqual(tc.want, got) {
+			t.Errorf("NormalizeTags(%q): want %q got %q", tc.tags, tc.want, got)
+		}
+	}
+}
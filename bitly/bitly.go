@@ -2,19 +2,26 @@ package bitly
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/pkg/errors"
 	"io"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"reflect"
 	"strings"
+	"time"
 )
 
 const (
 	Version = "0.1.0"
 
-	defaultBaseURL   = "https://api-ssl.bitly.com"
+	defaultBaseHost  = "api-ssl.bitly.com"
+	defaultBaseURL   = "https://" + defaultBaseHost
 	defaultUserAgent = "go-bitly/" + Version
 
 	apiVersion = "v4"
@@ -22,15 +29,22 @@ const (
 
 var (
 	errOptionsRequired = errors.New("options cannot be empty")
+
+	errPaginatorNotInitialized = errors.New("paginator is not initialized, use NewBitlinksByGroupPaginator")
 )
 
 type Client struct {
-	httpClient *http.Client
-	BaseURL    string
-	UserAgent  string
-	Debug      bool
-	Groups     GroupsService
-	User       UserService
+	httpClient  *http.Client
+	middlewares []Middleware
+	BaseURL     string
+	UserAgent   string
+	Debug       bool
+	// StrictDecoding makes Do return *UnknownFieldsError when a response has
+	// fields the model does not know, it is meant for debugging
+	StrictDecoding bool
+	Groups         GroupsService
+	User           UserService
+	Bitlinks       BitlinksService
 }
 
 func NewClient(httpClient *http.Client) *Client {
@@ -38,11 +52,18 @@ func NewClient(httpClient *http.Client) *Client {
 	c.UserAgent = defaultUserAgent
 	c.Groups = &GroupsClient{client: c}
 	c.User = &UserClient{client: c}
+	c.Bitlinks = &BitlinksClient{client: c}
 	return c
 }
 
-func (c *Client) NewRequest(method, path string, payload interface{}) (*http.Request, error) {
-	url := c.BaseURL + path
+// NewRequest creates an API request bound to ctx. path is either relative to BaseURL
+// or an absolute URL like pagination links returned by Bitly. Absolute Bitly API URLs
+// are rewritten onto BaseURL, so requests keep going through a configured proxy.
+func (c *Client) NewRequest(ctx context.Context, method, path string, payload interface{}) (*http.Request, error) {
+	url, err := c.resolveURL(path)
+	if err != nil {
+		return nil, err
+	}
 	body := new(bytes.Buffer)
 	if payload != nil {
 		err := json.NewEncoder(body).Encode(payload)
@@ -51,7 +72,7 @@ func (c *Client) NewRequest(method, path string, payload interface{}) (*http.Req
 		}
 	}
 
-	req, err := http.NewRequest(method, url, body)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
@@ -63,7 +84,58 @@ func (c *Client) NewRequest(method, path string, payload interface{}) (*http.Req
 	return req, nil
 }
 
-func (c *Client) Do(req *http.Request, obj interface{}) (*http.Response, error) {
+func (c *Client) resolveURL(path string) (string, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+	if !u.IsAbs() {
+		return c.BaseURL + path, nil
+	}
+	if strings.HasPrefix(path, strings.TrimRight(c.BaseURL, "/")+"/") {
+		return path, nil
+	}
+	if u.Host != defaultBaseHost {
+		return "", errors.Errorf("url %v does not belong to Bitly API", path)
+	}
+	rel := &url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery}
+	return c.BaseURL + rel.String(), nil
+}
+
+// Do sends req through the middleware chain and decodes the response body into obj.
+// If obj implements io.Writer the raw body is written to it.
+func (c *Client) Do(req *http.Request, obj interface{}) (*Response, error) {
+	response, err := c.chain().Do(req)
+	if err != nil {
+		return response, err
+	}
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return response, err
+	}
+
+	// If obj implements the io.Writer,
+	// the response body is decoded into v.
+	if obj != nil {
+		if w, ok := obj.(io.Writer); ok {
+			_, err = w.Write(data)
+		} else {
+			err = json.Unmarshal(data, obj)
+			if err == nil && c.StrictDecoding {
+				if fields := unknownFields(data, reflect.TypeOf(obj)); len(fields) > 0 {
+					err = &UnknownFieldsError{Fields: fields}
+				}
+			}
+		}
+	}
+
+	return response, err
+}
+
+// send is the innermost Doer, it executes req and returns Response with
+// buffered body, so middlewares may read it
+func (c *Client) send(req *http.Request) (*Response, error) {
 	if c.Debug {
 		log.Printf("Executing request (%v): %#v", req.URL, req)
 	}
@@ -77,22 +149,24 @@ func (c *Client) Do(req *http.Request, obj interface{}) (*http.Response, error)
 		log.Printf("Response received: %#v", resp)
 	}
 
-	err = CheckResponse(resp)
-	if err != nil {
-		return resp, err
+	response := newResponse(resp)
+	// 304 answers conditional requests made by Cache middleware, it is not an error
+	notModified := resp.StatusCode == http.StatusNotModified &&
+		(req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "")
+	if !notModified {
+		if err := CheckResponse(resp); err != nil {
+			return response, err
+		}
 	}
 
-	// If obj implements the io.Writer,
-	// the response body is decoded into v.
-	if obj != nil {
-		if w, ok := obj.(io.Writer); ok {
-			_, err = io.Copy(w, resp.Body)
-		} else {
-			err = json.NewDecoder(resp.Body).Decode(obj)
-		}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return response, err
 	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	response.Pagination = parsePagination(data)
 
-	return resp, err
+	return response, nil
 }
 
 // errorParameter is used for constructing error when one of parameters is empty
@@ -124,6 +198,56 @@ func (r *ErrorResponse) Error() string {
 		r.response.StatusCode, r.Message)
 }
 
+// StatusCode returns HTTP status code of the failed response
+func (r *ErrorResponse) StatusCode() int {
+	return r.response.StatusCode
+}
+
+// ErrorCode returns Bitly error code: the code of the first invalid field
+// if Bitly reported one, otherwise the message like INVALID_ARG_LONG_URL
+func (r *ErrorResponse) ErrorCode() string {
+	for _, e := range r.Errors {
+		if e.ErrorCode != "" {
+			return e.ErrorCode
+		}
+	}
+	return r.Message
+}
+
+// isTemporaryError reports whether err means Bitly could not be reached
+// or asked to retry later, including requests rejected by an open circuit.
+// Errors wrapped with github.com/pkg/errors are unwrapped first.
+func isTemporaryError(err error) bool {
+	switch e := errors.Cause(err).(type) {
+	case *ErrCircuitOpen:
+		return true
+	case *ErrorResponse:
+		code := e.response.StatusCode
+		return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+	case *url.Error:
+		// Invalid URLs and unsupported schemes fail the same way every time
+		if e.Timeout() {
+			return true
+		}
+		if _, ok := e.Err.(net.Error); ok {
+			return true
+		}
+		return e.Err == io.EOF || e.Err == io.ErrUnexpectedEOF
+	case net.Error:
+		return true
+	}
+	return false
+}
+
+// retryAfterError returns the time an error asks to wait before the next
+// attempt, it is zero for errors without such a hint
+func retryAfterError(err error) time.Duration {
+	if e, ok := errors.Cause(err).(*ErrCircuitOpen); ok {
+		return e.RetryAfter
+	}
+	return 0
+}
+
 func CheckResponse(resp *http.Response) error {
 	if code := resp.StatusCode; 200 <= code && code <= 299 {
 		return nil
@@ -132,13 +256,14 @@ func CheckResponse(resp *http.Response) error {
 	errorResponse := &ErrorResponse{}
 	errorResponse.response = resp
 	if err := json.NewDecoder(resp.Body).Decode(errorResponse); err != nil {
-		return err
+		// Proxies and load balancers in front of Bitly may answer with non JSON body
+		errorResponse.Message = http.StatusText(resp.StatusCode)
 	}
 	return errorResponse
 }
 
-func (c *Client) sendRequest(path string, payload, obj interface{}, method string) (*http.Response, error) {
-	req, err := c.NewRequest(method, path, payload)
+func (c *Client) sendRequest(ctx context.Context, path string, payload, obj interface{}, method string) (*Response, error) {
+	req, err := c.NewRequest(ctx, method, path, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -146,24 +271,24 @@ func (c *Client) sendRequest(path string, payload, obj interface{}, method strin
 	return c.Do(req, obj)
 }
 
-func (c *Client) get(path string, obj interface{}) (*http.Response, error) {
-	return c.sendRequest(path, nil, obj, "GET")
+func (c *Client) get(ctx context.Context, path string, obj interface{}) (*Response, error) {
+	return c.sendRequest(ctx, path, nil, obj, "GET")
 }
 
-func (c *Client) post(path string, payload, obj interface{}) (*http.Response, error) {
-	return c.sendRequest(path, payload, obj, "POST")
+func (c *Client) post(ctx context.Context, path string, payload, obj interface{}) (*Response, error) {
+	return c.sendRequest(ctx, path, payload, obj, "POST")
 }
 
-func (c *Client) put(path string, payload, obj interface{}) (*http.Response, error) {
-	return c.sendRequest(path, payload, obj, "PUT")
+func (c *Client) put(ctx context.Context, path string, payload, obj interface{}) (*Response, error) {
+	return c.sendRequest(ctx, path, payload, obj, "PUT")
 }
 
-func (c *Client) patch(path string, payload, obj interface{}) (*http.Response, error) {
-	return c.sendRequest(path, payload, obj, "PATCH")
+func (c *Client) patch(ctx context.Context, path string, payload, obj interface{}) (*Response, error) {
+	return c.sendRequest(ctx, path, payload, obj, "PATCH")
 }
 
-func (c *Client) delete(path string, payload interface{}, obj interface{}) (*http.Response, error) {
-	return c.sendRequest(path, payload, obj, "DELETE")
+func (c *Client) delete(ctx context.Context, path string, payload interface{}, obj interface{}) (*Response, error) {
+	return c.sendRequest(ctx, path, payload, obj, "DELETE")
 }
 
 func versioned(path string) string {
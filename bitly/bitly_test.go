@@ -1,8 +1,14 @@
 package bitly
 
 import (
+	"context"
+	"github.com/pkg/errors"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"testing"
+	"time"
 )
 
 func TestClient_NewRequest(t *testing.T) {
@@ -24,19 +30,87 @@ func TestClient_NewRequest(t *testing.T) {
 			wantURL:   "http://example.com/bar/foo",
 			wantError: "",
 		},
+		{
+			baseURL:   "http://example.com",
+			url:       "https://api-ssl.bitly.com/v4/groups/Ba1/bitlinks?page=2",
+			wantURL:   "http://example.com/v4/groups/Ba1/bitlinks?page=2",
+			wantError: "",
+		},
+		{
+			baseURL:   "http://example.com/bar",
+			url:       "https://api-ssl.bitly.com/v4/groups/Ba1/bitlinks?page=2&size=10",
+			wantURL:   "http://example.com/bar/v4/groups/Ba1/bitlinks?page=2&size=10",
+			wantError: "",
+		},
+		{
+			baseURL:   "https://api-ssl.bitly.com",
+			url:       "https://api-ssl.bitly.com/v4/user",
+			wantURL:   "https://api-ssl.bitly.com/v4/user",
+			wantError: "",
+		},
+		{
+			baseURL:   "http://example.com/bar",
+			url:       "http://example.com/bar/v4/user",
+			wantURL:   "http://example.com/bar/v4/user",
+			wantError: "",
+		},
+		{
+			baseURL:   "http://example.com",
+			url:       "https://evil.example.org/v4/user",
+			wantURL:   "",
+			wantError: "url https://evil.example.org/v4/user does not belong to Bitly API",
+		},
 	}
 	for _, tc := range testCases {
 		c := NewClient(http.DefaultClient)
 		c.BaseURL = tc.baseURL
-		req, err := c.NewRequest("GET", tc.url, nil)
+		req, err := c.NewRequest(context.Background(), "GET", tc.url, nil)
 		if tc.wantError == "" && err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 		if tc.wantError != "" && (err == nil || tc.wantError != err.Error()) {
 			t.Fatalf("want error %v got %v", tc.wantError, err)
 		}
+		if err != nil {
+			continue
+		}
 		if rawURL := req.URL.String(); rawURL != tc.wantURL {
 			t.Fatalf("got url %v want %v", rawURL, tc.wantURL)
 		}
 	}
 }
+
+func TestIsTemporaryError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	refusedURL := "http://" + l.Addr().String()
+	l.Close()
+	_, refused := http.Get(refusedURL)
+	_, unsupported := http.Get("ftp://example.com")
+
+	testCases := []struct {
+		desc string
+		err  error
+		want bool
+	}{
+		{"connection refused", refused, true},
+		{"timeout", &url.Error{Op: "Get", URL: "http://example.com", Err: &net.DNSError{IsTimeout: true}}, true},
+		{"connection closed", &url.Error{Op: "Get", URL: "http://example.com", Err: io.EOF}, true},
+		{"unsupported scheme", unsupported, false},
+		{"malformed url", &url.Error{Op: "parse", URL: "http://[::1", Err: errors.New("missing ']' in host")}, false},
+		{"other error", errors.New("other"), false},
+		{"wrapped timeout", errors.Wrap(&url.Error{Op: "Get", URL: "http://example.com", Err: &net.DNSError{IsTimeout: true}}, "get"), true},
+		{"wrapped open circuit", errors.Wrap(&ErrCircuitOpen{RetryAfter: time.Second}, "get"), true},
+		{"wrapped other error", errors.Wrap(errors.New("other"), "get"), false},
+	}
+	for _, tc := range testCases {
+		if got := isTemporaryError(tc.err); got != tc.want {
+			t.Errorf("%s: want temporary %v got %v for %v", tc.desc, tc.want, got, tc.err)
+		}
+	}
+	if got := retryAfterError(errors.Wrap(&ErrCircuitOpen{RetryAfter: time.Second}, "get")); got != time.Second {
+		t.Errorf("want retry after 1s of wrapped open circuit got %v", got)
+	}
+}
@@ -0,0 +1,32 @@
+package bitlymock
+
+import (
+	"context"
+	"github.com/lcd1232/go-bitly/bitly"
+)
+
+var _ bitly.BitlinksService = &BitlinksService{}
+
+// BitlinksService is a fake of bitly.BitlinksService
+type BitlinksService struct {
+	Recorder
+
+	ShortenFunc func(ctx context.Context, options *bitly.ShortenOptions) (*bitly.Bitlink, *bitly.Response, error)
+	UpdateFunc  func(ctx context.Context, bitlink string, options *bitly.BitlinkUpdateOptions) (*bitly.Bitlink, *bitly.Response, error)
+}
+
+func (m *BitlinksService) Shorten(ctx context.Context, options *bitly.ShortenOptions) (*bitly.Bitlink, *bitly.Response, error) {
+	m.record("Shorten", ctx, options)
+	if m.ShortenFunc == nil {
+		return nil, nil, ErrNotStubbed
+	}
+	return m.ShortenFunc(ctx, options)
+}
+
+func (m *BitlinksService) Update(ctx context.Context, bitlink string, options *bitly.BitlinkUpdateOptions) (*bitly.Bitlink, *bitly.Response, error) {
+	m.record("Update", ctx, bitlink, options)
+	if m.UpdateFunc == nil {
+		return nil, nil, ErrNotStubbed
+	}
+	return m.UpdateFunc(ctx, bitlink, options)
+}
@@ -0,0 +1,85 @@
+package bitlymock
+
+import (
+	"context"
+	"github.com/lcd1232/go-bitly/bitly"
+	"github.com/pkg/errors"
+	"strconv"
+)
+
+var _ bitly.GroupsService = &GroupsService{}
+
+// GroupsService is a fake of bitly.GroupsService
+type GroupsService struct {
+	Recorder
+
+	ListGroupsFunc                  func(ctx context.Context, organizationGUID string) (*bitly.GroupList, *bitly.Response, error)
+	GetGroupFunc                    func(ctx context.Context, groupGUID string) (*bitly.Group, *bitly.Response, error)
+	GetGroupPreferencesFunc         func(ctx context.Context, groupGUID string) (*bitly.GroupPreferences, *bitly.Response, error)
+	GetBitlinksByGroupFunc          func(ctx context.Context, groupGUID string, queryParams *bitly.GetBitlinksByGroupQueryParams) (*bitly.BitlinksByGroup, *bitly.Response, error)
+	GetBitlinksByGroupPaginatorFunc func(ctx context.Context, groupGUID string, queryParams *bitly.GetBitlinksByGroupQueryParams) (*bitly.BitlinksByGroupPaginator, error)
+}
+
+func (m *GroupsService) ListGroups(ctx context.Context, organizationGUID string) (*bitly.GroupList, *bitly.Response, error) {
+	m.record("ListGroups", ctx, organizationGUID)
+	if m.ListGroupsFunc == nil {
+		return nil, nil, ErrNotStubbed
+	}
+	return m.ListGroupsFunc(ctx, organizationGUID)
+}
+
+func (m *GroupsService) GetGroup(ctx context.Context, groupGUID string) (*bitly.Group, *bitly.Response, error) {
+	m.record("GetGroup", ctx, groupGUID)
+	if m.GetGroupFunc == nil {
+		return nil, nil, ErrNotStubbed
+	}
+	return m.GetGroupFunc(ctx, groupGUID)
+}
+
+func (m *GroupsService) GetGroupPreferences(ctx context.Context, groupGUID string) (*bitly.GroupPreferences, *bitly.Response, error) {
+	m.record("GetGroupPreferences", ctx, groupGUID)
+	if m.GetGroupPreferencesFunc == nil {
+		return nil, nil, ErrNotStubbed
+	}
+	return m.GetGroupPreferencesFunc(ctx, groupGUID)
+}
+
+func (m *GroupsService) GetBitlinksByGroup(ctx context.Context, groupGUID string, queryParams *bitly.GetBitlinksByGroupQueryParams) (*bitly.BitlinksByGroup, *bitly.Response, error) {
+	m.record("GetBitlinksByGroup", ctx, groupGUID, queryParams)
+	if m.GetBitlinksByGroupFunc == nil {
+		return nil, nil, ErrNotStubbed
+	}
+	return m.GetBitlinksByGroupFunc(ctx, groupGUID, queryParams)
+}
+
+func (m *GroupsService) GetBitlinksByGroupPaginator(ctx context.Context, groupGUID string, queryParams *bitly.GetBitlinksByGroupQueryParams) (*bitly.BitlinksByGroupPaginator, error) {
+	m.record("GetBitlinksByGroupPaginator", ctx, groupGUID, queryParams)
+	if m.GetBitlinksByGroupPaginatorFunc == nil {
+		return nil, ErrNotStubbed
+	}
+	return m.GetBitlinksByGroupPaginatorFunc(ctx, groupGUID, queryParams)
+}
+
+// PagesPaginator returns paginator over pages for GetBitlinksByGroupPaginatorFunc,
+// Pagination links of the pages are set so Next and Prev walk them in order
+func PagesPaginator(ctx context.Context, pages ...bitly.BitlinksByGroup) *bitly.BitlinksByGroupPaginator {
+	if len(pages) == 0 {
+		pages = []bitly.BitlinksByGroup{{}}
+	}
+	return bitly.NewBitlinksByGroupPaginator(ctx, "0", func(ctx context.Context, url string) (*bitly.BitlinksByGroup, error) {
+		i, err := strconv.Atoi(url)
+		if err != nil || i < 0 || i >= len(pages) {
+			return nil, errors.Errorf("bitlymock: unknown page %q", url)
+		}
+		page := pages[i]
+		page.Pagination.Page = i + 1
+		page.Pagination.Prev, page.Pagination.Next = "", ""
+		if i > 0 {
+			page.Pagination.Prev = strconv.Itoa(i - 1)
+		}
+		if i < len(pages)-1 {
+			page.Pagination.Next = strconv.Itoa(i + 1)
+		}
+		return &page, nil
+	})
+}
@@ -0,0 +1,167 @@
+// Package bitlymock provides fakes of the bitly service interfaces, so code
+// depending on Client.Groups, Client.User and Client.Bitlinks can be tested
+// without HTTP.
+//
+// Every fake records its calls and delegates to a per-method function field,
+// methods without a function return ErrNotStubbed:
+//
+//	c, mocks := bitlymock.NewClient()
+//	mocks.Groups.GetGroupFunc = func(ctx context.Context, guid string) (*bitly.Group, *bitly.Response, error) {
+//		return &bitly.Group{GUID: guid}, nil, nil
+//	}
+//	// ... exercise code using c ...
+//	mocks.Groups.AssertCalled(t, "GetGroup", ctx, "Ba1")
+package bitlymock
+
+import (
+	"fmt"
+	"github.com/lcd1232/go-bitly/bitly"
+	"github.com/pkg/errors"
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+var (
+	// ErrNotStubbed is returned by methods which function field is nil
+	ErrNotStubbed = errors.New("bitlymock: method is not stubbed")
+
+	errUnexpectedRequest = errors.New("bitlymock: unexpected HTTP request")
+)
+
+// Mocks holds fakes installed into the client returned by NewClient
+type Mocks struct {
+	Groups   *GroupsService
+	User     *UserService
+	Bitlinks *BitlinksService
+}
+
+// NewClient returns *bitly.Client which services are replaced by fakes.
+// HTTP requests made by the client outside of services fail.
+func NewClient() (*bitly.Client, *Mocks) {
+	mocks := &Mocks{
+		Groups:   &GroupsService{},
+		User:     &UserService{},
+		Bitlinks: &BitlinksService{},
+	}
+	c := bitly.NewClient(&http.Client{Transport: failingTransport{}})
+	c.Groups = mocks.Groups
+	c.User = mocks.User
+	c.Bitlinks = mocks.Bitlinks
+	return c, mocks
+}
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errUnexpectedRequest
+}
+
+// Call is a recorded method call
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// TestingT is the subset of testing.TB used by assertion helpers
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Recorder records calls of a fake, it is embedded into every fake
+type Recorder struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+func (r *Recorder) record(method string, args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, Call{Method: method, Args: args})
+}
+
+// Calls returns every recorded call in order
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// CallsTo returns recorded calls of method in order
+func (r *Recorder) CallsTo(method string) []Call {
+	var calls []Call
+	for _, call := range r.Calls() {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Reset forgets recorded calls
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+}
+
+// AssertCalled fails the test unless method was called with args,
+// without args any call of method matches
+func (r *Recorder) AssertCalled(t TestingT, method string, args ...interface{}) bool {
+	t.Helper()
+	calls := r.CallsTo(method)
+	for _, call := range calls {
+		if len(args) == 0 || reflect.DeepEqual(call.Args, args) {
+			return true
+		}
+	}
+	if len(calls) == 0 {
+		t.Errorf("want %s to be called", method)
+	} else {
+		t.Errorf("want %s to be called with %s got calls %s", method, formatArgs(args), formatCalls(calls))
+	}
+	return false
+}
+
+// AssertNotCalled fails the test if method was called
+func (r *Recorder) AssertNotCalled(t TestingT, method string) bool {
+	t.Helper()
+	if calls := r.CallsTo(method); len(calls) > 0 {
+		t.Errorf("want %s not to be called got calls %s", method, formatCalls(calls))
+		return false
+	}
+	return true
+}
+
+// AssertNumberOfCalls fails the test unless method was called n times
+func (r *Recorder) AssertNumberOfCalls(t TestingT, method string, n int) bool {
+	t.Helper()
+	if calls := r.CallsTo(method); len(calls) != n {
+		t.Errorf("want %s to be called %d times got %d", method, n, len(calls))
+		return false
+	}
+	return true
+}
+
+func formatArgs(args []interface{}) string {
+	s := "("
+	for i, arg := range args {
+		if i > 0 {
+			s += ", "
+		}
+		s += fmt.Sprintf("%#v", arg)
+	}
+	return s + ")"
+}
+
+func formatCalls(calls []Call) string {
+	s := ""
+	for i, call := range calls {
+		if i > 0 {
+			s += ", "
+		}
+		s += formatArgs(call.Args)
+	}
+	return s
+}
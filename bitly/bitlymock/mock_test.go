@@ -0,0 +1,179 @@
+package bitlymock
+
+import (
+	"context"
+	"fmt"
+	"github.com/lcd1232/go-bitly/bitly"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type fakeT struct {
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestNewClient(t *testing.T) {
+	c, mocks := NewClient()
+	mocks.Groups.GetGroupFunc = func(ctx context.Context, groupGUID string) (*bitly.Group, *bitly.Response, error) {
+		return &bitly.Group{GUID: groupGUID, Name: "test"}, nil, nil
+	}
+
+	group, _, err := c.Groups.GetGroup(context.Background(), "Ba1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(group, &bitly.Group{GUID: "Ba1", Name: "test"}) {
+		t.Fatalf("unexpected group %#v", group)
+	}
+	if _, _, err := c.User.Get(context.Background()); err != ErrNotStubbed {
+		t.Fatalf("want error %v got %v", ErrNotStubbed, err)
+	}
+	if _, _, err := c.Bitlinks.Shorten(context.Background(), &bitly.ShortenOptions{LongURL: "http://example.com"}); err != ErrNotStubbed {
+		t.Fatalf("want error %v got %v", ErrNotStubbed, err)
+	}
+
+	req, err := c.NewRequest(context.Background(), "GET", "/v4/user", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.Do(req, nil); err == nil || !strings.Contains(err.Error(), errUnexpectedRequest.Error()) {
+		t.Fatalf("want error %v got %v", errUnexpectedRequest, err)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	m := &BitlinksService{}
+	options := &bitly.BitlinkUpdateOptions{Title: bitly.String("new")}
+	m.Update(ctx, "bit.ly/a", options)
+	m.Update(ctx, "bit.ly/b", options)
+	m.Shorten(ctx, nil)
+
+	wantCalls := []Call{
+		{Method: "Update", Args: []interface{}{ctx, "bit.ly/a", options}},
+		{Method: "Update", Args: []interface{}{ctx, "bit.ly/b", options}},
+		{Method: "Shorten", Args: []interface{}{ctx, (*bitly.ShortenOptions)(nil)}},
+	}
+	if got := m.Calls(); !reflect.DeepEqual(wantCalls, got) {
+		t.Fatalf("want calls %#v got %#v", wantCalls, got)
+	}
+	if got := m.CallsTo("Update"); len(got) != 2 {
+		t.Fatalf("want 2 calls got %#v", got)
+	}
+
+	testCases := []struct {
+		desc       string
+		assert     func(t TestingT) bool
+		wantResult bool
+		wantError  string
+	}{
+		{
+			desc: "called with args",
+			assert: func(t TestingT) bool {
+				return m.AssertCalled(t, "Update", ctx, "bit.ly/b", &bitly.BitlinkUpdateOptions{Title: bitly.String("new")})
+			},
+			wantResult: true,
+		},
+		{
+			desc:       "called without args",
+			assert:     func(t TestingT) bool { return m.AssertCalled(t, "Shorten") },
+			wantResult: true,
+		},
+		{
+			desc:       "called with other args",
+			assert:     func(t TestingT) bool { return m.AssertCalled(t, "Update", ctx, "bit.ly/c", options) },
+			wantResult: false,
+			wantError:  `"bit.ly/c"`,
+		},
+		{
+			desc:       "not called",
+			assert:     func(t TestingT) bool { return m.AssertCalled(t, "Get") },
+			wantResult: false,
+			wantError:  "want Get to be called",
+		},
+		{
+			desc:       "assert not called",
+			assert:     func(t TestingT) bool { return m.AssertNotCalled(t, "Update") },
+			wantResult: false,
+			wantError:  "want Update not to be called",
+		},
+		{
+			desc:       "number of calls",
+			assert:     func(t TestingT) bool { return m.AssertNumberOfCalls(t, "Update", 2) },
+			wantResult: true,
+		},
+		{
+			desc:       "wrong number of calls",
+			assert:     func(t TestingT) bool { return m.AssertNumberOfCalls(t, "Shorten", 2) },
+			wantResult: false,
+			wantError:  "want Shorten to be called 2 times got 1",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ft := &fakeT{}
+			if got := tc.assert(ft); got != tc.wantResult {
+				t.Fatalf("want result %v got %v", tc.wantResult, got)
+			}
+			if tc.wantError == "" && len(ft.errors) > 0 {
+				t.Fatalf("unexpected errors: %v", ft.errors)
+			}
+			if tc.wantError != "" && (len(ft.errors) != 1 || !strings.Contains(ft.errors[0], tc.wantError)) {
+				t.Fatalf("want error %v got %v", tc.wantError, ft.errors)
+			}
+		})
+	}
+
+	m.Reset()
+	if got := m.Calls(); len(got) != 0 {
+		t.Fatalf("want no calls got %#v", got)
+	}
+}
+
+func TestPagesPaginator(t *testing.T) {
+	_, mocks := NewClient()
+	mocks.Groups.GetBitlinksByGroupPaginatorFunc = func(ctx context.Context, groupGUID string, queryParams *bitly.GetBitlinksByGroupQueryParams) (*bitly.BitlinksByGroupPaginator, error) {
+		return PagesPaginator(ctx,
+			bitly.BitlinksByGroup{Links: []bitly.Bitlink{{ID: "bit.ly/a"}, {ID: "bit.ly/b"}}},
+			bitly.BitlinksByGroup{Links: []bitly.Bitlink{{ID: "bit.ly/c"}}},
+		), nil
+	}
+
+	p, err := mocks.Groups.GetBitlinksByGroupPaginator(context.Background(), "Ba1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ids []string
+	for {
+		if err := p.Get(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, link := range p.Resp.Links {
+			ids = append(ids, link.ID)
+		}
+		if !p.Next() {
+			break
+		}
+	}
+	if want := []string{"bit.ly/a", "bit.ly/b", "bit.ly/c"}; !reflect.DeepEqual(want, ids) {
+		t.Fatalf("want links %v got %v", want, ids)
+	}
+	if !p.Prev() || p.Get() != nil || p.Resp.Pagination.Page != 1 {
+		t.Fatalf("want first page after Prev got %#v", p.Resp.Pagination)
+	}
+
+	var zero bitly.BitlinksByGroupPaginator
+	if err := zero.Get(); err == nil {
+		t.Fatalf("want error from zero paginator")
+	}
+	if zero.Next() {
+		t.Fatalf("want no next page of zero paginator")
+	}
+}
@@ -0,0 +1,41 @@
+package bitlymock
+
+import (
+	"context"
+	"github.com/lcd1232/go-bitly/bitly"
+)
+
+var _ bitly.UserService = &UserService{}
+
+// UserService is a fake of bitly.UserService
+type UserService struct {
+	Recorder
+
+	GetFunc       func(ctx context.Context) (*bitly.User, *bitly.Response, error)
+	UpdateFunc    func(ctx context.Context, options *bitly.UserUpdateOptions) (*bitly.User, *bitly.Response, error)
+	GetGroupsFunc func(ctx context.Context, login string) (*bitly.GroupList, *bitly.Response, error)
+}
+
+func (m *UserService) Get(ctx context.Context) (*bitly.User, *bitly.Response, error) {
+	m.record("Get", ctx)
+	if m.GetFunc == nil {
+		return nil, nil, ErrNotStubbed
+	}
+	return m.GetFunc(ctx)
+}
+
+func (m *UserService) Update(ctx context.Context, options *bitly.UserUpdateOptions) (*bitly.User, *bitly.Response, error) {
+	m.record("Update", ctx, options)
+	if m.UpdateFunc == nil {
+		return nil, nil, ErrNotStubbed
+	}
+	return m.UpdateFunc(ctx, options)
+}
+
+func (m *UserService) GetGroups(ctx context.Context, login string) (*bitly.GroupList, *bitly.Response, error) {
+	m.record("GetGroups", ctx, login)
+	if m.GetGroupsFunc == nil {
+		return nil, nil, ErrNotStubbed
+	}
+	return m.GetGroupsFunc(ctx, login)
+}
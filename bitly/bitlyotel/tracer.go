@@ -0,0 +1,85 @@
+// Package bitlyotel adapts OpenTelemetry tracing to bitly.Tracer:
+//
+//	c := bitly.NewClient(httpClient)
+//	c.Use(bitlyotel.Tracing(otel.GetTracerProvider()), bitly.Retry(bitly.RetryOptions{MaxRetries: 3}))
+//
+// Every service call becomes a client span, like bitly.Groups.GetBitlinksByGroup,
+// with method, templated path, status, Bitly error code and retries as attributes.
+// The bitly package does not import this one, so only programs using the adapter build OpenTelemetry.
+package bitlyotel
+
+import (
+	"context"
+	"fmt"
+	"github.com/lcd1232/go-bitly/bitly"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is the name of the OpenTelemetry tracer used for spans
+const InstrumentationName = "github.com/lcd1232/go-bitly/bitly"
+
+// Tracer is bitly.Tracer backed by OpenTelemetry
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ bitly.Tracer = &Tracer{}
+
+// NewTracer returns Tracer which starts spans with a tracer of provider
+func NewTracer(provider trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: provider.Tracer(InstrumentationName)}
+}
+
+// Tracing returns bitly.Tracing middleware with spans of provider
+func Tracing(provider trace.TracerProvider) bitly.Middleware {
+	return bitly.Tracing(NewTracer(provider))
+}
+
+// Start implements bitly.Tracer
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, bitly.Span) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, &Span{span: span}
+}
+
+// Span is bitly.Span backed by OpenTelemetry span
+type Span struct {
+	span trace.Span
+}
+
+// SetAttributes implements bitly.Span
+func (s *Span) SetAttributes(attributes ...bitly.Attribute) {
+	kvs := make([]attribute.KeyValue, 0, len(attributes))
+	for _, a := range attributes {
+		kvs = append(kvs, keyValue(a))
+	}
+	s.span.SetAttributes(kvs...)
+}
+
+// RecordError implements bitly.Span, the span status is set to Error
+func (s *Span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End implements bitly.Span
+func (s *Span) End() {
+	s.span.End()
+}
+
+func keyValue(a bitly.Attribute) attribute.KeyValue {
+	switch v := a.Value.(type) {
+	case string:
+		return attribute.String(a.Key, v)
+	case int:
+		return attribute.Int(a.Key, v)
+	case int64:
+		return attribute.Int64(a.Key, v)
+	case bool:
+		return attribute.Bool(a.Key, v)
+	case float64:
+		return attribute.Float64(a.Key, v)
+	}
+	return attribute.String(a.Key, fmt.Sprint(a.Value))
+}
@@ -0,0 +1,73 @@
+package bitlyotel
+
+import (
+	"context"
+	"github.com/lcd1232/go-bitly/bitly"
+	"github.com/lcd1232/go-bitly/bitly/bitlytest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"testing"
+)
+
+func TestTracing(t *testing.T) {
+	s := bitlytest.NewServer()
+	defer s.Close()
+	s.AddFault(bitlytest.Fault{Method: "GET", Path: "/v4/groups/" + bitlytest.DefaultGroupGUID, Status: http.StatusServiceUnavailable, Times: 1})
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	c := s.Client()
+	c.Use(Tracing(provider), bitly.Retry(bitly.RetryOptions{MaxRetries: 1, MinBackoff: 1}))
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+	if _, _, err := c.Groups.GetGroup(ctx, bitlytest.DefaultGroupGUID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parent.End()
+	if _, _, err := c.Groups.GetGroup(context.Background(), "Bunknown"); err == nil {
+		t.Fatalf("want error")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("want 3 spans got %d", len(spans))
+	}
+	group, missing := spans[0], spans[2]
+	if group.Name() != "bitly.Groups.GetGroup" || group.SpanKind() != trace.SpanKindClient {
+		t.Fatalf("unexpected span %v of kind %v", group.Name(), group.SpanKind())
+	}
+	if group.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("want span to be a child of parent span")
+	}
+	wantAttributes := map[attribute.Key]attribute.Value{
+		bitly.AttributeMethod:     attribute.StringValue("GET"),
+		bitly.AttributeRoute:      attribute.StringValue("/v4/groups/{group_guid}"),
+		bitly.AttributeStatusCode: attribute.IntValue(200),
+		bitly.AttributeRetries:    attribute.IntValue(1),
+	}
+	got := map[attribute.Key]attribute.Value{}
+	for _, kv := range group.Attributes() {
+		got[kv.Key] = kv.Value
+	}
+	for k, v := range wantAttributes {
+		if got[k] != v {
+			t.Fatalf("want attribute %v=%v got %v", k, v.Emit(), got[k].Emit())
+		}
+	}
+	if group.Status().Code != codes.Unset {
+		t.Fatalf("want unset status got %v", group.Status())
+	}
+
+	if missing.Status().Code != codes.Error || len(missing.Events()) != 1 {
+		t.Fatalf("want error status and event got %v %v", missing.Status(), missing.Events())
+	}
+	for _, kv := range missing.Attributes() {
+		if kv.Key == bitly.AttributeErrorCode && kv.Value.AsString() != "NOT_FOUND" {
+			t.Fatalf("want error code NOT_FOUND got %v", kv.Value.AsString())
+		}
+	}
+}
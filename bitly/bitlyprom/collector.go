@@ -0,0 +1,178 @@
+// Package bitlyprom exports bitly.Metrics to Prometheus:
+//
+//	collector := bitlyprom.NewCollector()
+//	prometheus.MustRegister(collector)
+//	c := bitly.NewClient(httpClient)
+//	c.Use(bitly.Instrument(collector), bitly.Retry(bitly.RetryOptions{MaxRetries: 3}))
+//
+// Metrics are labeled with the operation, like Groups.GetBitlinksByGroup.
+// The bitly package does not import this one, so only programs using the adapter build Prometheus.
+package bitlyprom
+
+import (
+	"github.com/lcd1232/go-bitly/bitly"
+	"github.com/prometheus/client_golang/prometheus"
+	"strconv"
+)
+
+const (
+	// ErrorCodeUnknown labels errors which are not Bitly API errors, like network failures
+	ErrorCodeUnknown = "unknown"
+	// ErrorCodeOther labels Bitly API errors with codes not in the known set,
+	// Bitly falls back to free text messages so they cannot be used as labels
+	ErrorCodeOther = "other"
+)
+
+// knownErrorCodes are error codes documented by Bitly, other codes are
+// labeled ErrorCodeOther to keep the number of series bounded
+var knownErrorCodes = map[string]bool{
+	"ALREADY_A_BITLY_LINK":        true,
+	"BAD_REQUEST":                 true,
+	"CONFLICT":                    true,
+	"FORBIDDEN":                   true,
+	"INTERNAL_ERROR":              true,
+	"INVALID_ARG_CUSTOM_BITLINK":  true,
+	"INVALID_ARG_DOMAIN":          true,
+	"INVALID_ARG_GROUP_GUID":      true,
+	"INVALID_ARG_LONG_URL":        true,
+	"INVALID_ARG_PAGINATION":      true,
+	"INVALID_ARG_TITLE":           true,
+	"INVALID_ARG_UNIT":            true,
+	"INVALID_ARG_UNITS":           true,
+	"INVALID_ARG_UNIT_REFERENCE":  true,
+	"MONTHLY_RATE_LIMIT_EXCEEDED": true,
+	"NOT_FOUND":                   true,
+	"RATE_LIMIT_EXCEEDED":         true,
+	"TEMPORARILY_UNAVAILABLE":     true,
+	"UNPROCESSABLE_ENTITY":        true,
+	"UPGRADE_REQUIRED":            true,
+	// error codes of invalid fields
+	"already_exists": true,
+	"invalid":        true,
+	"missing":        true,
+}
+
+// errorCodeLabel returns error_code label of code
+func errorCodeLabel(code string) string {
+	switch {
+	case code == "":
+		return ErrorCodeUnknown
+	case knownErrorCodes[code]:
+		return code
+	}
+	return ErrorCodeOther
+}
+
+// Options configures Collector
+type Options struct {
+	// Namespace prefixes metric names, it defaults to bitly
+	Namespace string
+	// Buckets of request duration histogram in seconds,
+	// they default to prometheus.DefBuckets
+	Buckets []float64
+}
+
+// Collector is bitly.Metrics and prometheus.Collector. It exports:
+//
+//	bitly_client_requests_total{operation,method,code}
+//	bitly_client_request_duration_seconds{operation}
+//	bitly_client_errors_total{operation,error_code}
+//	bitly_client_retries_total{operation}
+//	bitly_client_rate_limit_remaining{operation}
+//
+// error_code is a known Bitly error code, ErrorCodeOther or ErrorCodeUnknown.
+type Collector struct {
+	requests           *prometheus.CounterVec
+	duration           *prometheus.HistogramVec
+	errors             *prometheus.CounterVec
+	retries            *prometheus.CounterVec
+	rateLimitRemaining *prometheus.GaugeVec
+}
+
+var (
+	_ bitly.Metrics        = &Collector{}
+	_ prometheus.Collector = &Collector{}
+)
+
+// NewCollector returns Collector with default Options
+func NewCollector() *Collector {
+	return NewCollectorWithOptions(Options{})
+}
+
+// NewCollectorWithOptions returns Collector configured with options
+func NewCollectorWithOptions(options Options) *Collector {
+	if options.Namespace == "" {
+		options.Namespace = "bitly"
+	}
+	if options.Buckets == nil {
+		options.Buckets = prometheus.DefBuckets
+	}
+	const subsystem = "client"
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: options.Namespace,
+			Subsystem: subsystem,
+			Name:      "requests_total",
+			Help:      "Number of Bitly API calls by operation, method and HTTP status code.",
+		}, []string{"operation", "method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: options.Namespace,
+			Subsystem: subsystem,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of Bitly API calls including retries.",
+			Buckets:   options.Buckets,
+		}, []string{"operation"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: options.Namespace,
+			Subsystem: subsystem,
+			Name:      "errors_total",
+			Help:      "Number of failed Bitly API calls by Bitly error code.",
+		}, []string{"operation", "error_code"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: options.Namespace,
+			Subsystem: subsystem,
+			Name:      "retries_total",
+			Help:      "Number of retried Bitly API requests.",
+		}, []string{"operation"}),
+		rateLimitRemaining: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: options.Namespace,
+			Subsystem: subsystem,
+			Name:      "rate_limit_remaining",
+			Help:      "Requests left in the current rate limit window as reported by Bitly.",
+		}, []string{"operation"}),
+	}
+}
+
+// ObserveRequest implements bitly.Metrics
+func (c *Collector) ObserveRequest(stats bitly.RequestStats) {
+	op := stats.Operation.Name
+	c.requests.WithLabelValues(op, stats.Method, strconv.Itoa(stats.StatusCode)).Inc()
+	c.duration.WithLabelValues(op).Observe(stats.Duration.Seconds())
+	if stats.Retries > 0 {
+		c.retries.WithLabelValues(op).Add(float64(stats.Retries))
+	}
+	if stats.Err != nil {
+		c.errors.WithLabelValues(op, errorCodeLabel(stats.ErrorCode)).Inc()
+	}
+	if stats.HasRateLimit {
+		c.rateLimitRemaining.WithLabelValues(op).Set(float64(stats.RateLimit.Remaining))
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.duration.Describe(ch)
+	c.errors.Describe(ch)
+	c.retries.Describe(ch)
+	c.rateLimitRemaining.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.duration.Collect(ch)
+	c.errors.Collect(ch)
+	c.retries.Collect(ch)
+	c.rateLimitRemaining.Collect(ch)
+}
@@ -0,0 +1,114 @@
+package bitlyprom
+
+import (
+	"context"
+	"github.com/lcd1232/go-bitly/bitly"
+	"github.com/lcd1232/go-bitly/bitly/bitlytest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollector(t *testing.T) {
+	s := bitlytest.NewServer()
+	defer s.Close()
+	s.SetRateLimit(10, time.Hour)
+	s.AddFault(bitlytest.Fault{Method: "GET", Path: "/v4/user", Status: http.StatusServiceUnavailable, Times: 1})
+
+	collector := NewCollector()
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector)
+	c := s.Client()
+	c.Use(bitly.Instrument(collector), bitly.Retry(bitly.RetryOptions{MaxRetries: 1, MinBackoff: time.Millisecond}))
+
+	ctx := context.Background()
+	if _, _, err := c.User.Get(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := c.Groups.GetGroup(ctx, bitlytest.DefaultGroupGUID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := c.Groups.GetGroup(ctx, "Bunknown"); err == nil {
+		t.Fatalf("want error")
+	}
+
+	testCases := []struct {
+		desc   string
+		metric prometheus.Collector
+		want   float64
+	}{
+		{desc: "user requests", metric: collector.requests.WithLabelValues("User.Get", "GET", "200"), want: 1},
+		{desc: "group requests", metric: collector.requests.WithLabelValues("Groups.GetGroup", "GET", "200"), want: 1},
+		{desc: "missing group requests", metric: collector.requests.WithLabelValues("Groups.GetGroup", "GET", "404"), want: 1},
+		{desc: "retries", metric: collector.retries.WithLabelValues("User.Get"), want: 1},
+		{desc: "errors", metric: collector.errors.WithLabelValues("Groups.GetGroup", "NOT_FOUND"), want: 1},
+		{desc: "rate limit remaining", metric: collector.rateLimitRemaining.WithLabelValues("Groups.GetGroup"), want: 6},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := testutil.ToFloat64(tc.metric); got != tc.want {
+				t.Fatalf("want %v got %v", tc.want, got)
+			}
+		})
+	}
+
+	if n := testutil.CollectAndCount(collector, "bitly_client_request_duration_seconds"); n != 2 {
+		t.Fatalf("want 2 duration histograms got %d", n)
+	}
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP bitly_client_retries_total Number of retried Bitly API requests.
+# TYPE bitly_client_retries_total counter
+bitly_client_retries_total{operation="User.Get"} 1
+`), "bitly_client_retries_total"); err != nil {
+		t.Fatalf("unexpected metrics: %v", err)
+	}
+}
+
+func TestCollector_UnknownError(t *testing.T) {
+	collector := NewCollectorWithOptions(Options{Namespace: "test"})
+	collector.ObserveRequest(bitly.RequestStats{
+		Operation: bitly.Operation{Name: "Bitlinks.Shorten"},
+		Method:    "POST",
+		Err:       context.DeadlineExceeded,
+	})
+	if got := testutil.ToFloat64(collector.errors.WithLabelValues("Bitlinks.Shorten", ErrorCodeUnknown)); got != 1 {
+		t.Fatalf("want 1 unknown error got %v", got)
+	}
+	if got := testutil.ToFloat64(collector.requests.WithLabelValues("Bitlinks.Shorten", "POST", "0")); got != 1 {
+		t.Fatalf("want 1 request got %v", got)
+	}
+	if n := testutil.CollectAndCount(collector, "test_client_rate_limit_remaining"); n != 0 {
+		t.Fatalf("want no rate limit gauge got %d", n)
+	}
+}
+
+func TestCollector_ErrorCodeLabel(t *testing.T) {
+	collector := NewCollector()
+	for _, code := range []string{"NOT_FOUND", "Service Unavailable", "something went wrong for bit.ly/abc", "INVALID_ARG_LONG_URL"} {
+		collector.ObserveRequest(bitly.RequestStats{
+			Operation: bitly.Operation{Name: "Bitlinks.Shorten"},
+			Method:    "POST",
+			Err:       context.DeadlineExceeded,
+			ErrorCode: code,
+		})
+	}
+	testCases := []struct {
+		code string
+		want float64
+	}{
+		{"NOT_FOUND", 1},
+		{"INVALID_ARG_LONG_URL", 1},
+		{ErrorCodeOther, 2},
+	}
+	for _, tc := range testCases {
+		if got := testutil.ToFloat64(collector.errors.WithLabelValues("Bitlinks.Shorten", tc.code)); got != tc.want {
+			t.Errorf("want %v errors labeled %s got %v", tc.want, tc.code, got)
+		}
+	}
+	if n := testutil.CollectAndCount(collector, "bitly_client_errors_total"); n != 3 {
+		t.Fatalf("want 3 error series got %d", n)
+	}
+}
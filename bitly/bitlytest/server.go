@@ -0,0 +1,967 @@
+// Package bitlytest provides an in-memory fake of the Bitly v4 API for tests.
+//
+// The fake keeps users, organizations, groups and Bitlinks in memory, so
+// requests made through the returned *bitly.Client observe each other:
+//
+//	s := bitlytest.NewServer()
+//	defer s.Close()
+//	c := s.Client()
+//	link, _, err := c.Bitlinks.Shorten(context.Background(), &bitly.ShortenOptions{LongURL: "https://example.com"})
+//
+// Faults and rate limits can be injected to test error handling.
+package bitlytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lcd1232/go-bitly/bitly"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Token is the OAuth token accepted by the server
+	Token = "bitlytest-token"
+	// DefaultOrganizationGUID is the organization created by NewServer
+	DefaultOrganizationGUID = "Oa1b2c3d4e5"
+	// DefaultGroupGUID is the group created by NewServer
+	DefaultGroupGUID = "Ba1b2c3d4e5"
+	// DefaultDomain is the domain used for Bitlinks when none is requested
+	DefaultDomain = "bit.ly"
+	// DefaultLogin is the login of the authenticated user
+	DefaultLogin = "bitlytest"
+
+	apiURL          = "https://api-ssl.bitly.com"
+	timeFormat      = "2006-01-02T15:04:05-0700"
+	defaultPageSize = 50
+	hashAlphabet    = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+)
+
+// Organization is an organization stored by the server
+type Organization struct {
+	GUID    string
+	Name    string
+	Created time.Time
+}
+
+// Group is a group stored by the server
+type Group struct {
+	GUID             string
+	OrganizationGUID string
+	Name             string
+	BSDS             []string
+	DomainPreference string
+	Created          time.Time
+	Modified         time.Time
+}
+
+// Bitlink is a Bitlink stored by the server
+type Bitlink struct {
+	ID             string
+	GroupGUID      string
+	LongURL        string
+	Title          string
+	Tags           []string
+	Archived       bool
+	CustomBitlinks []string
+	Created        time.Time
+	Modified       time.Time
+	// Clicks are times of recorded clicks, see AddClicks
+	Clicks []time.Time
+}
+
+// Fault makes matching requests fail, see AddFault
+type Fault struct {
+	// Method matches the request method, empty matches every method
+	Method string
+	// Path matches requests which path starts with it, empty matches every path
+	Path string
+	// Status and Message are returned instead of the regular response
+	Status  int
+	Message string
+	// Times is how many requests fail, zero means until ClearFaults is called
+	Times int
+}
+
+// Server is a fake Bitly API server
+type Server struct {
+	// URL is the base URL of the server
+	URL string
+	// Now returns current time used for timestamps and click buckets
+	Now func() time.Time
+
+	server *httptest.Server
+
+	mu            sync.Mutex
+	user          bitly.User
+	organizations []*Organization
+	groups        []*Group
+	bitlinks      []*Bitlink
+	faults        []*Fault
+	hashes        int
+	requests      int
+
+	rateLimit     int
+	rateWindow    time.Duration
+	rateUsed      int
+	rateResetTime time.Time
+}
+
+// NewServer starts a server with the default user, organization and group
+func NewServer() *Server {
+	s := &Server{Now: time.Now}
+	now := s.Now()
+	s.user = bitly.User{
+		Login:    DefaultLogin,
+		Name:     DefaultLogin,
+		IsActive: true,
+		Created:  bitly.JSONDate(now),
+		Modified: bitly.JSONDate(now),
+		Emails:   []bitly.Email{{Email: DefaultLogin + "@example.com", IsPrimary: true, IsVerified: true}},
+	}
+	s.organizations = []*Organization{{GUID: DefaultOrganizationGUID, Name: DefaultLogin, Created: now}}
+	s.groups = []*Group{{
+		GUID:             DefaultGroupGUID,
+		OrganizationGUID: DefaultOrganizationGUID,
+		Name:             DefaultLogin,
+		BSDS:             []string{},
+		DomainPreference: DefaultDomain,
+		Created:          now,
+		Modified:         now,
+	}}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+	return s
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Client returns *bitly.Client authenticated with Token and pointed at the server
+func (s *Server) Client() *bitly.Client {
+	httpClient := &http.Client{Transport: &credentialsTransport{
+		credentials: bitly.NewOauthTokenCredentials(Token),
+		transport:   s.server.Client().Transport,
+	}}
+	c := bitly.NewClient(httpClient)
+	c.BaseURL = s.URL
+	return c
+}
+
+// AddOrganization stores an organization
+func (s *Server) AddOrganization(org Organization) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if org.Created.IsZero() {
+		org.Created = s.Now()
+	}
+	s.organizations = append(s.organizations, &org)
+}
+
+// AddGroup stores a group, OrganizationGUID defaults to DefaultOrganizationGUID
+func (s *Server) AddGroup(group Group) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if group.OrganizationGUID == "" {
+		group.OrganizationGUID = DefaultOrganizationGUID
+	}
+	if group.DomainPreference == "" {
+		group.DomainPreference = DefaultDomain
+	}
+	if group.BSDS == nil {
+		group.BSDS = []string{}
+	}
+	if group.Created.IsZero() {
+		group.Created = s.Now()
+	}
+	if group.Modified.IsZero() {
+		group.Modified = group.Created
+	}
+	s.groups = append(s.groups, &group)
+}
+
+// AddBitlink stores a Bitlink, an empty ID gets a new hash on DefaultDomain
+// and an empty GroupGUID defaults to DefaultGroupGUID
+func (s *Server) AddBitlink(link Bitlink) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if link.GroupGUID == "" {
+		link.GroupGUID = DefaultGroupGUID
+	}
+	if link.ID == "" {
+		link.ID = DefaultDomain + "/" + s.newHash()
+	}
+	if link.Created.IsZero() {
+		link.Created = s.Now()
+	}
+	if link.Modified.IsZero() {
+		link.Modified = link.Created
+	}
+	s.bitlinks = append(s.bitlinks, &link)
+	return link.ID
+}
+
+// Bitlink returns a copy of a stored Bitlink
+func (s *Server) Bitlink(id string) (Bitlink, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if link := s.findBitlink(id); link != nil {
+		return *link, true
+	}
+	return Bitlink{}, false
+}
+
+// AddClicks records n clicks of a Bitlink at time at
+func (s *Server) AddClicks(id string, at time.Time, n int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link := s.findBitlink(id)
+	if link == nil {
+		return fmt.Errorf("bitlink %s not found", id)
+	}
+	for i := 0; i < n; i++ {
+		link.Clicks = append(link.Clicks, at)
+	}
+	return nil
+}
+
+// AddFault makes requests matching f fail
+func (s *Server) AddFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes every fault
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// SetRateLimit allows limit requests per window, further requests are
+// answered with 429 until the window resets. Zero limit disables rate limiting.
+func (s *Server) SetRateLimit(limit int, window time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit = limit
+	s.rateWindow = window
+	s.rateUsed = 0
+	s.rateResetTime = time.Time{}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Bitly-Request-Id", fmt.Sprintf("bitlytest-%d", s.requests))
+
+	if r.Header.Get("Authorization") != "Bearer "+Token {
+		writeError(w, http.StatusForbidden, "FORBIDDEN")
+		return
+	}
+	if !s.allowRequest(w) {
+		writeError(w, http.StatusTooManyRequests, "RATE_LIMIT_EXCEEDED")
+		return
+	}
+	if f := s.matchFault(r); f != nil {
+		writeError(w, f.Status, f.Message)
+		return
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case path == "/v4/user":
+		s.handleUser(w, r)
+	case path == "/v4/organizations":
+		s.handleOrganizations(w, r)
+	case strings.HasPrefix(path, "/v4/organizations/"):
+		s.handleOrganization(w, r, strings.TrimPrefix(path, "/v4/organizations/"))
+	case path == "/v4/groups":
+		s.handleGroups(w, r)
+	case strings.HasPrefix(path, "/v4/groups/"):
+		s.handleGroup(w, r, strings.TrimPrefix(path, "/v4/groups/"))
+	case path == "/v4/shorten" || path == "/v4/bitlinks":
+		s.handleShorten(w, r)
+	case path == "/v4/expand":
+		s.handleExpand(w, r)
+	case path == "/v4/custom_bitlinks":
+		s.handleCustomBitlinks(w, r)
+	case strings.HasPrefix(path, "/v4/bitlinks/"):
+		s.handleBitlink(w, r, strings.TrimPrefix(path, "/v4/bitlinks/"))
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND")
+	}
+}
+
+func (s *Server) allowRequest(w http.ResponseWriter) bool {
+	if s.rateLimit <= 0 {
+		return true
+	}
+	now := s.Now()
+	if s.rateResetTime.IsZero() || !now.Before(s.rateResetTime) {
+		s.rateUsed = 0
+		s.rateResetTime = now.Add(s.rateWindow)
+	}
+	allowed := s.rateUsed < s.rateLimit
+	if allowed {
+		s.rateUsed++
+	}
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(s.rateLimit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(s.rateLimit-s.rateUsed))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(s.rateResetTime.Unix(), 10))
+	return allowed
+}
+
+func (s *Server) matchFault(r *http.Request) *Fault {
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if !strings.HasPrefix(r.URL.Path, f.Path) {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+func (s *Server) handleUser(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+	case "PATCH":
+		options := &bitly.UserUpdateOptions{}
+		if !decodeBody(w, r, options) {
+			return
+		}
+		s.user.Name = options.Name
+		s.user.Modified = bitly.JSONDate(s.Now())
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.user)
+}
+
+func (s *Server) handleOrganizations(w http.ResponseWriter, r *http.Request) {
+	orgs := []interface{}{}
+	for _, org := range s.organizations {
+		orgs = append(orgs, organizationJSON(org))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"organizations": orgs})
+}
+
+func (s *Server) handleOrganization(w http.ResponseWriter, r *http.Request, guid string) {
+	for _, org := range s.organizations {
+		if org.GUID == guid {
+			writeJSON(w, http.StatusOK, organizationJSON(org))
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "NOT_FOUND")
+}
+
+func (s *Server) handleGroups(w http.ResponseWriter, r *http.Request) {
+	orgGUID := r.URL.Query().Get("organization_guid")
+	groups := []interface{}{}
+	for _, group := range s.groups {
+		if orgGUID == "" || group.OrganizationGUID == orgGUID {
+			groups = append(groups, groupJSON(group))
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"groups": groups})
+}
+
+func (s *Server) handleGroup(w http.ResponseWriter, r *http.Request, path string) {
+	parts := strings.SplitN(path, "/", 2)
+	group := s.findGroup(parts[0])
+	if group == nil {
+		writeError(w, http.StatusNotFound, "NOT_FOUND")
+		return
+	}
+	resource := ""
+	if len(parts) == 2 {
+		resource = parts[1]
+	}
+	switch resource {
+	case "":
+		writeJSON(w, http.StatusOK, groupJSON(group))
+	case "preferences":
+		if r.Method == "PATCH" {
+			prefs := map[string]string{}
+			if !decodeBody(w, r, &prefs) {
+				return
+			}
+			if domain, ok := prefs["domain_preference"]; ok {
+				group.DomainPreference = domain
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]string{
+			"group_guid":        group.GUID,
+			"domain_preference": group.DomainPreference,
+		})
+	case "tags":
+		writeJSON(w, http.StatusOK, map[string][]string{"tags": s.groupTags(group.GUID)})
+	case "bitlinks":
+		s.handleGroupBitlinks(w, r, group)
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND")
+	}
+}
+
+func (s *Server) handleGroupBitlinks(w http.ResponseWriter, r *http.Request, group *Group) {
+	q := r.URL.Query()
+	size, page := defaultPageSize, 1
+	if v := q.Get("size"); v != "" {
+		size, _ = strconv.Atoi(v)
+	}
+	if v := q.Get("page"); v != "" {
+		page, _ = strconv.Atoi(v)
+	}
+	if size <= 0 || page <= 0 {
+		writeError(w, http.StatusBadRequest, "INVALID_ARG_PAGINATION")
+		return
+	}
+
+	links := []*Bitlink{}
+	for _, link := range s.bitlinks {
+		if link.GroupGUID == group.GUID && matchBitlink(link, q) {
+			links = append(links, link)
+		}
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		return links[i].Created.After(links[j].Created)
+	})
+
+	pagination := map[string]interface{}{
+		"total": len(links),
+		"size":  size,
+		"page":  page,
+		"prev":  "",
+		"next":  "",
+	}
+	pageURL := func(page int) string {
+		pq := r.URL.Query()
+		pq.Set("page", strconv.Itoa(page))
+		pq.Set("size", strconv.Itoa(size))
+		return apiURL + r.URL.Path + "?" + pq.Encode()
+	}
+	if page > 1 {
+		pagination["prev"] = pageURL(page - 1)
+	}
+	if page*size < len(links) {
+		pagination["next"] = pageURL(page + 1)
+	}
+
+	items := []interface{}{}
+	for i := (page - 1) * size; i < page*size && i < len(links); i++ {
+		items = append(items, bitlinkJSON(links[i]))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"links": items, "pagination": pagination})
+}
+
+func (s *Server) handleShorten(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED")
+		return
+	}
+	options := &struct {
+		LongURL   string   `json:"long_url"`
+		Domain    string   `json:"domain"`
+		GroupGUID string   `json:"group_guid"`
+		Title     string   `json:"title"`
+		Tags      []string `json:"tags"`
+	}{}
+	if !decodeBody(w, r, options) {
+		return
+	}
+	if !strings.HasPrefix(options.LongURL, "http://") && !strings.HasPrefix(options.LongURL, "https://") {
+		writeError(w, http.StatusBadRequest, "INVALID_ARG_LONG_URL")
+		return
+	}
+	if options.GroupGUID == "" {
+		options.GroupGUID = DefaultGroupGUID
+	}
+	group := s.findGroup(options.GroupGUID)
+	if group == nil {
+		writeError(w, http.StatusForbidden, "FORBIDDEN")
+		return
+	}
+	if options.Domain == "" {
+		options.Domain = group.DomainPreference
+	}
+
+	// Shortening the same long url in a group returns the existing Bitlink
+	for _, link := range s.bitlinks {
+		if link.GroupGUID == group.GUID && link.LongURL == options.LongURL && strings.HasPrefix(link.ID, options.Domain+"/") {
+			writeJSON(w, http.StatusOK, bitlinkJSON(link))
+			return
+		}
+	}
+
+	now := s.Now()
+	link := &Bitlink{
+		ID:        options.Domain + "/" + s.newHash(),
+		GroupGUID: group.GUID,
+		LongURL:   options.LongURL,
+		Title:     options.Title,
+		Tags:      options.Tags,
+		Created:   now,
+		Modified:  now,
+	}
+	s.bitlinks = append(s.bitlinks, link)
+	writeJSON(w, http.StatusCreated, bitlinkJSON(link))
+}
+
+func (s *Server) handleExpand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED")
+		return
+	}
+	options := &struct {
+		BitlinkID string `json:"bitlink_id"`
+	}{}
+	if !decodeBody(w, r, options) {
+		return
+	}
+	link := s.findBitlink(options.BitlinkID)
+	if link == nil {
+		writeError(w, http.StatusNotFound, "NOT_FOUND")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"id":         link.ID,
+		"link":       "https://" + link.ID,
+		"long_url":   link.LongURL,
+		"created_at": link.Created.Format(timeFormat),
+	})
+}
+
+// handleCustomBitlinks adds a custom back-half, like bit.ly/spring, to a Bitlink
+func (s *Server) handleCustomBitlinks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED")
+		return
+	}
+	options := &struct {
+		CustomBitlink string `json:"custom_bitlink"`
+		BitlinkID     string `json:"bitlink_id"`
+	}{}
+	if !decodeBody(w, r, options) {
+		return
+	}
+	link := s.findBitlink(options.BitlinkID)
+	if link == nil {
+		writeError(w, http.StatusNotFound, "NOT_FOUND")
+		return
+	}
+	custom := strings.TrimPrefix(strings.TrimPrefix(options.CustomBitlink, "https://"), "http://")
+	if i := strings.Index(custom, "/"); i <= 0 || i == len(custom)-1 {
+		writeError(w, http.StatusBadRequest, "INVALID_ARG_CUSTOM_BITLINK")
+		return
+	}
+	if s.findBitlink(custom) != nil {
+		writeError(w, http.StatusConflict, "ALREADY_A_BITLY_LINK")
+		return
+	}
+	link.CustomBitlinks = append(link.CustomBitlinks, custom)
+	link.Modified = s.Now()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"custom_bitlink": "https://" + custom,
+		"bitlink":        bitlinkJSON(link),
+	})
+}
+
+func (s *Server) handleBitlink(w http.ResponseWriter, r *http.Request, path string) {
+	resource := ""
+	for _, suffix := range []string{"/clicks/summary", "/clicks"} {
+		if strings.HasSuffix(path, suffix) {
+			resource = suffix
+			path = strings.TrimSuffix(path, suffix)
+			break
+		}
+	}
+	link := s.findBitlink(path)
+	if link == nil {
+		writeError(w, http.StatusNotFound, "NOT_FOUND")
+		return
+	}
+
+	switch resource {
+	case "/clicks", "/clicks/summary":
+		s.handleClicks(w, r, link, resource == "/clicks/summary")
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+	case "PATCH":
+		options := &struct {
+			Title    *string   `json:"title"`
+			Archived *bool     `json:"archived"`
+			Tags     *[]string `json:"tags"`
+			LongURL  *string   `json:"long_url"`
+		}{}
+		if !decodeBody(w, r, options) {
+			return
+		}
+		if options.Title != nil {
+			link.Title = *options.Title
+		}
+		if options.Archived != nil {
+			link.Archived = *options.Archived
+		}
+		if options.Tags != nil {
+			link.Tags = *options.Tags
+		}
+		if options.LongURL != nil {
+			link.LongURL = *options.LongURL
+		}
+		link.Modified = s.Now()
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED")
+		return
+	}
+	writeJSON(w, http.StatusOK, bitlinkJSON(link))
+}
+
+func (s *Server) handleClicks(w http.ResponseWriter, r *http.Request, link *Bitlink, summary bool) {
+	q := r.URL.Query()
+	unit := q.Get("unit")
+	if unit == "" {
+		unit = "day"
+	}
+	units := -1
+	if v := q.Get("units"); v != "" {
+		units, _ = strconv.Atoi(v)
+	}
+	reference := s.Now()
+	if v := q.Get("unit_reference"); v != "" {
+		t, err := time.Parse(timeFormat, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ARG_UNIT_REFERENCE")
+			return
+		}
+		reference = t
+	}
+	start := truncate(reference, unit)
+	if start.IsZero() || units == 0 || units < -1 {
+		writeError(w, http.StatusBadRequest, "INVALID_ARG_UNIT")
+		return
+	}
+	if units == -1 {
+		// All units up to the oldest click
+		units = 1
+		for _, click := range link.Clicks {
+			for click.Before(shift(start, unit, 1-units)) {
+				units++
+			}
+		}
+	}
+
+	buckets := []interface{}{}
+	total := 0
+	for i := 0; i < units; i++ {
+		from := shift(start, unit, -i)
+		to := shift(from, unit, 1)
+		clicks := 0
+		for _, click := range link.Clicks {
+			if !click.Before(from) && click.Before(to) {
+				clicks++
+			}
+		}
+		total += clicks
+		buckets = append(buckets, map[string]interface{}{"clicks": clicks, "date": from.Format(timeFormat)})
+	}
+
+	result := map[string]interface{}{
+		"units":          units,
+		"unit":           unit,
+		"unit_reference": reference.Format(timeFormat),
+	}
+	if summary {
+		result["total_clicks"] = total
+	} else {
+		result["link_clicks"] = buckets
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) findGroup(guid string) *Group {
+	for _, group := range s.groups {
+		if group.GUID == guid {
+			return group
+		}
+	}
+	return nil
+}
+
+func (s *Server) findBitlink(id string) *Bitlink {
+	id = strings.TrimPrefix(strings.TrimPrefix(id, "https://"), "http://")
+	for _, link := range s.bitlinks {
+		if link.ID == id {
+			return link
+		}
+		for _, custom := range link.CustomBitlinks {
+			if custom == id {
+				return link
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) groupTags(guid string) []string {
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, link := range s.bitlinks {
+		if link.GroupGUID != guid {
+			continue
+		}
+		for _, tag := range link.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// newHash returns deterministic unique hash of a new Bitlink
+func (s *Server) newHash() string {
+	s.hashes++
+	n := s.hashes + 1000000
+	hash := ""
+	for n > 0 {
+		hash = string(hashAlphabet[n%len(hashAlphabet)]) + hash
+		n /= len(hashAlphabet)
+	}
+	return hash
+}
+
+func matchBitlink(link *Bitlink, q map[string][]string) bool {
+	get := func(key string) string {
+		if v := q[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	if !matchOption(get("archived"), link.Archived, "off") {
+		return false
+	}
+	if !matchOption(get("custom_bitlink"), len(link.CustomBitlinks) > 0, "both") {
+		return false
+	}
+	if keyword := strings.ToLower(get("keyword")); keyword != "" {
+		found := false
+		for _, tag := range link.Tags {
+			found = found || strings.ToLower(tag) == keyword
+		}
+		if !found && !strings.Contains(strings.ToLower(link.Title), keyword) {
+			return false
+		}
+	}
+	if query := strings.ToLower(get("query")); query != "" {
+		if !strings.Contains(strings.ToLower(link.Title), query) &&
+			!strings.Contains(strings.ToLower(link.LongURL), query) &&
+			!strings.Contains(strings.ToLower(link.ID), query) {
+			return false
+		}
+	}
+	for _, tag := range q["tags"] {
+		found := false
+		for _, linkTag := range link.Tags {
+			found = found || linkTag == tag
+		}
+		if !found {
+			return false
+		}
+	}
+	if v := get("created_before"); v != "" {
+		if ts, err := strconv.ParseInt(v, 10, 64); err == nil && !link.Created.Before(time.Unix(ts, 0)) {
+			return false
+		}
+	}
+	if v := get("created_after"); v != "" {
+		if ts, err := strconv.ParseInt(v, 10, 64); err == nil && !link.Created.After(time.Unix(ts, 0)) {
+			return false
+		}
+	}
+	if v := get("modified_after"); v != "" {
+		if t, ok := parseTime(v); ok && !link.Modified.After(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchOption reports whether value satisfies on/off/both query option
+func matchOption(option string, value bool, def string) bool {
+	if option == "" {
+		option = def
+	}
+	switch option {
+	case "on":
+		return value
+	case "off":
+		return !value
+	}
+	return true
+}
+
+func parseTime(v string) (time.Time, bool) {
+	if ts, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(ts, 0), true
+	}
+	for _, layout := range []string{timeFormat, time.RFC3339} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func truncate(t time.Time, unit string) time.Time {
+	switch unit {
+	case "minute":
+		return t.Truncate(time.Minute)
+	case "hour":
+		return t.Truncate(time.Hour)
+	case "day":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case "week":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Time{}
+}
+
+func shift(t time.Time, unit string, n int) time.Time {
+	switch unit {
+	case "minute":
+		return t.Add(time.Duration(n) * time.Minute)
+	case "hour":
+		return t.Add(time.Duration(n) * time.Hour)
+	case "day":
+		return t.AddDate(0, 0, n)
+	case "week":
+		return t.AddDate(0, 0, 7*n)
+	case "month":
+		return t.AddDate(0, n, 0)
+	}
+	return t
+}
+
+func organizationJSON(org *Organization) map[string]interface{} {
+	return map[string]interface{}{
+		"guid":      org.GUID,
+		"name":      org.Name,
+		"is_active": true,
+		"tier":      "free",
+		"created":   org.Created.Format(timeFormat),
+		"modified":  org.Created.Format(timeFormat),
+		"references": map[string]string{
+			"groups": apiURL + "/v4/groups?organization_guid=" + org.GUID,
+		},
+	}
+}
+
+func groupJSON(group *Group) map[string]interface{} {
+	return map[string]interface{}{
+		"guid":              group.GUID,
+		"organization_guid": group.OrganizationGUID,
+		"name":              group.Name,
+		"bsds":              group.BSDS,
+		"is_active":         true,
+		"role":              "org-admin",
+		"created":           group.Created.Format(timeFormat),
+		"modified":          group.Modified.Format(timeFormat),
+		"references": map[string]string{
+			"organization": apiURL + "/v4/organizations/" + group.OrganizationGUID,
+		},
+	}
+}
+
+func bitlinkJSON(link *Bitlink) map[string]interface{} {
+	tags := link.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	custom := link.CustomBitlinks
+	if custom == nil {
+		custom = []string{}
+	}
+	return map[string]interface{}{
+		"id":              link.ID,
+		"link":            "https://" + link.ID,
+		"long_url":        link.LongURL,
+		"title":           link.Title,
+		"archived":        link.Archived,
+		"tags":            tags,
+		"custom_bitlinks": custom,
+		"deeplinks":       []interface{}{},
+		"created_at":      link.Created.Format(timeFormat),
+		"created_by":      DefaultLogin,
+		"references": map[string]string{
+			"group": apiURL + "/v4/groups/" + link.GroupGUID,
+		},
+	}
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "INVALID_JSON")
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{
+		"message":     message,
+		"description": http.StatusText(status),
+	})
+}
+
+// credentialsTransport adds headers of bitly.Credentials to every request
+type credentialsTransport struct {
+	credentials bitly.Credentials
+	transport   http.RoundTripper
+}
+
+func (t *credentialsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req2 := new(http.Request)
+	*req2 = *req
+	req2.Header = make(http.Header, len(req.Header))
+	for k, s := range req.Header {
+		req2.Header[k] = append([]string(nil), s...)
+	}
+	for k, v := range t.credentials.Headers() {
+		req2.Header.Set(k, v)
+	}
+	return t.transport.RoundTrip(req2)
+}
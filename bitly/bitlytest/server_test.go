@@ -0,0 +1,399 @@
+package bitlytest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/lcd1232/go-bitly/bitly"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer_User(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	c := s.Client()
+
+	u, resp, err := c.User.Get(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.Login != DefaultLogin {
+		t.Fatalf("want login %v got %v", DefaultLogin, u.Login)
+	}
+	if resp.RequestID == "" {
+		t.Fatalf("want request id")
+	}
+
+	if _, _, err := c.User.Update(context.Background(), &bitly.UserUpdateOptions{Name: "new name"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u, _, err = c.User.Get(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.Name != "new name" {
+		t.Fatalf("want name %v got %v", "new name", u.Name)
+	}
+}
+
+func TestServer_Groups(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddOrganization(Organization{GUID: "Oother", Name: "other"})
+	s.AddGroup(Group{GUID: "Bother", OrganizationGUID: "Oother", Name: "other", BSDS: []string{"example.co"}})
+	c := s.Client()
+
+	testCases := []struct {
+		desc             string
+		organizationGUID string
+		wantGUIDs        []string
+	}{
+		{
+			desc:      "all groups",
+			wantGUIDs: []string{DefaultGroupGUID, "Bother"},
+		},
+		{
+			desc:             "organization groups",
+			organizationGUID: "Oother",
+			wantGUIDs:        []string{"Bother"},
+		},
+		{
+			desc:             "unknown organization",
+			organizationGUID: "Ounknown",
+			wantGUIDs:        nil,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			groups, _, err := c.Groups.ListGroups(context.Background(), tc.organizationGUID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, group := range groups.Groups {
+				got = append(got, group.GUID)
+			}
+			if !reflect.DeepEqual(tc.wantGUIDs, got) {
+				t.Fatalf("want groups %v got %v", tc.wantGUIDs, got)
+			}
+		})
+	}
+
+	group, _, err := c.Groups.GetGroup(context.Background(), "Bother")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if group.Name != "other" || !reflect.DeepEqual(group.BSDS, []string{"example.co"}) {
+		t.Fatalf("unexpected group %#v", group)
+	}
+	prefs, _, err := c.Groups.GetGroupPreferences(context.Background(), DefaultGroupGUID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if prefs.DomainPreference != DefaultDomain {
+		t.Fatalf("want domain %v got %v", DefaultDomain, prefs.DomainPreference)
+	}
+	if _, _, err := c.Groups.GetGroup(context.Background(), "Bunknown"); err == nil || !strings.Contains(err.Error(), "404 NOT_FOUND") {
+		t.Fatalf("want not found error got %v", err)
+	}
+}
+
+func TestServer_Bitlinks(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	start := time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	s.Now = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+	c := s.Client()
+
+	var ids []string
+	for _, longURL := range []string{"https://example.com/1", "https://example.com/2", "https://example.com/3"} {
+		link, _, err := c.Bitlinks.Shorten(context.Background(), &bitly.ShortenOptions{LongURL: longURL})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, link.ID)
+	}
+	again, _, err := c.Bitlinks.Shorten(context.Background(), &bitly.ShortenOptions{LongURL: "https://example.com/1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again.ID != ids[0] {
+		t.Fatalf("want existing bitlink %v got %v", ids[0], again.ID)
+	}
+	if _, _, err := c.Bitlinks.Shorten(context.Background(), &bitly.ShortenOptions{LongURL: "example"}); err == nil || !strings.Contains(err.Error(), "INVALID_ARG_LONG_URL") {
+		t.Fatalf("want invalid long url error got %v", err)
+	}
+
+	link, _, err := c.Bitlinks.Update(context.Background(), ids[1], &bitly.BitlinkUpdateOptions{Title: bitly.String("second"), Tags: bitly.Strings([]string{"news"}), Archived: bitly.Bool(true)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if link.Title != "second" || !link.Archived {
+		t.Fatalf("unexpected link %#v", link)
+	}
+
+	testCases := []struct {
+		desc    string
+		params  *bitly.GetBitlinksByGroupQueryParams
+		wantIDs []string
+	}{
+		{
+			desc:    "not archived by default, newest first",
+			params:  nil,
+			wantIDs: []string{ids[2], ids[0]},
+		},
+		{
+			desc:    "archived only",
+			params:  &bitly.GetBitlinksByGroupQueryParams{Archived: bitly.OnOption},
+			wantIDs: []string{ids[1]},
+		},
+		{
+			desc:    "tags",
+			params:  &bitly.GetBitlinksByGroupQueryParams{Archived: bitly.BothOption, Tags: []string{"news"}},
+			wantIDs: []string{ids[1]},
+		},
+		{
+			desc:    "query",
+			params:  &bitly.GetBitlinksByGroupQueryParams{Archived: bitly.BothOption, Query: "example.com/3"},
+			wantIDs: []string{ids[2]},
+		},
+		{
+			desc:    "created after",
+			params:  &bitly.GetBitlinksByGroupQueryParams{Archived: bitly.BothOption, CreatedAfter: int(start.Add(90 * time.Second).Unix())},
+			wantIDs: []string{ids[2], ids[1]},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			links, _, err := c.Groups.GetBitlinksByGroup(context.Background(), DefaultGroupGUID, tc.params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, link := range links.Links {
+				got = append(got, link.ID)
+			}
+			if !reflect.DeepEqual(tc.wantIDs, got) {
+				t.Fatalf("want links %v got %v", tc.wantIDs, got)
+			}
+		})
+	}
+
+	tags := struct {
+		Tags []string `json:"tags"`
+	}{}
+	req, err := c.NewRequest(context.Background(), "GET", "/v4/groups/"+DefaultGroupGUID+"/tags", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.Do(req, &tags); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(tags.Tags, []string{"news"}) {
+		t.Fatalf("want tags %v got %v", []string{"news"}, tags.Tags)
+	}
+
+	expanded := struct {
+		LongURL string `json:"long_url"`
+	}{}
+	req, err = c.NewRequest(context.Background(), "POST", "/v4/expand", map[string]string{"bitlink_id": ids[2]})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.Do(req, &expanded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expanded.LongURL != "https://example.com/3" {
+		t.Fatalf("want long url %v got %v", "https://example.com/3", expanded.LongURL)
+	}
+}
+
+func TestServer_CustomBitlinks(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	id := s.AddBitlink(Bitlink{LongURL: "https://example.com"})
+	s.AddBitlink(Bitlink{ID: "bit.ly/taken", LongURL: "https://example.com/taken"})
+	c := s.Client()
+
+	testCases := []struct {
+		desc    string
+		custom  string
+		bitlink string
+		wantErr string
+	}{
+		{desc: "added", custom: "bit.ly/spring", bitlink: id},
+		{desc: "taken by a Bitlink", custom: "bit.ly/taken", bitlink: id, wantErr: "409 ALREADY_A_BITLY_LINK"},
+		{desc: "taken by a custom back-half", custom: "https://bit.ly/spring", bitlink: "bit.ly/taken", wantErr: "409 ALREADY_A_BITLY_LINK"},
+		{desc: "missing back-half", custom: "bit.ly/", bitlink: id, wantErr: "400 INVALID_ARG_CUSTOM_BITLINK"},
+		{desc: "missing Bitlink", custom: "bit.ly/autumn", bitlink: "bit.ly/missing", wantErr: "404 NOT_FOUND"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			body := map[string]string{"custom_bitlink": tc.custom, "bitlink_id": tc.bitlink}
+			_, err := c.Call(context.Background(), "POST", "/custom_bitlinks", nil, body, nil)
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("want error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+	if link, ok := s.Bitlink("bit.ly/spring"); !ok || link.ID != id {
+		t.Fatalf("want Bitlink found by its custom back-half got %#v", link)
+	}
+}
+
+func TestServer_Pagination(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	for i := 0; i < 5; i++ {
+		s.AddBitlink(Bitlink{LongURL: "https://example.com", Created: time.Unix(int64(1000-i), 0)})
+	}
+	c := s.Client()
+
+	p, err := c.Groups.GetBitlinksByGroupPaginator(context.Background(), DefaultGroupGUID, &bitly.GetBitlinksByGroupQueryParams{Size: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var pages []int
+	count := 0
+	for {
+		if err := p.Get(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		pages = append(pages, len(p.Resp.Links))
+		count += len(p.Resp.Links)
+		if !p.Next() {
+			break
+		}
+	}
+	if !reflect.DeepEqual(pages, []int{2, 2, 1}) || count != 5 {
+		t.Fatalf("want pages %v got %v", []int{2, 2, 1}, pages)
+	}
+}
+
+func TestServer_Clicks(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	reference := time.Date(2018, 7, 19, 12, 0, 0, 0, time.UTC)
+	s.Now = func() time.Time { return reference }
+	id := s.AddBitlink(Bitlink{LongURL: "https://example.com"})
+	s.AddClicks(id, reference.Add(-time.Hour), 3)
+	s.AddClicks(id, reference.AddDate(0, 0, -2), 2)
+	c := s.Client()
+
+	clicks := struct {
+		LinkClicks []struct {
+			Clicks int    `json:"clicks"`
+			Date   string `json:"date"`
+		} `json:"link_clicks"`
+	}{}
+	req, err := c.NewRequest(context.Background(), "GET", "/v4/bitlinks/"+id+"/clicks?unit=day&units=-1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.Do(req, &clicks); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []int
+	for _, bucket := range clicks.LinkClicks {
+		got = append(got, bucket.Clicks)
+	}
+	if !reflect.DeepEqual(got, []int{3, 0, 2}) {
+		t.Fatalf("want clicks %v got %v", []int{3, 0, 2}, got)
+	}
+	if clicks.LinkClicks[0].Date != "2018-07-19T00:00:00+0000" {
+		t.Fatalf("unexpected bucket date %v", clicks.LinkClicks[0].Date)
+	}
+
+	summary := struct {
+		TotalClicks int `json:"total_clicks"`
+	}{}
+	req, err = c.NewRequest(context.Background(), "GET", "/v4/bitlinks/"+id+"/clicks/summary?unit=day&units=1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.Do(req, &summary); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.TotalClicks != 3 {
+		t.Fatalf("want total clicks 3 got %v", summary.TotalClicks)
+	}
+}
+
+func TestServer_Faults(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	c := s.Client()
+
+	s.AddFault(Fault{Method: "GET", Path: "/v4/groups", Status: http.StatusServiceUnavailable, Message: "unavailable", Times: 1})
+	if _, _, err := c.Groups.ListGroups(context.Background(), ""); err == nil || !strings.Contains(err.Error(), "503 unavailable") {
+		t.Fatalf("want unavailable error got %v", err)
+	}
+	if _, _, err := c.Groups.ListGroups(context.Background(), ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s.AddFault(Fault{Status: http.StatusInternalServerError, Message: "broken"})
+	if _, _, err := c.User.Get(context.Background()); err == nil || !strings.Contains(err.Error(), "500 broken") {
+		t.Fatalf("want broken error got %v", err)
+	}
+	s.ClearFaults()
+	if _, _, err := c.User.Get(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	now := time.Unix(1531998000, 0)
+	s.Now = func() time.Time { return now }
+	s.SetRateLimit(2, time.Minute)
+	c := s.Client()
+
+	_, resp, err := c.User.Get(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.RateLimit.Limit != 2 || resp.RateLimit.Remaining != 1 || !resp.RateLimit.Reset.Equal(now.Add(time.Minute)) {
+		t.Fatalf("unexpected rate limit %#v", resp.RateLimit)
+	}
+	if _, _, err := c.User.Get(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, resp, err = c.User.Get(context.Background())
+	if err == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("want rate limit error got %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	if _, _, err := c.User.Get(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestServer_Unauthorized(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	resp, err := http.Post(s.URL+"/v4/shorten", "application/json", bytes.NewBufferString(`{"long_url":"https://example.com"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body := map[string]string{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode != http.StatusForbidden || body["message"] != "FORBIDDEN" {
+		t.Fatalf("want forbidden got %v %v", resp.StatusCode, body)
+	}
+}
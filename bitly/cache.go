@@ -0,0 +1,227 @@
+package bitly
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheEntry is a cached response
+type CacheEntry struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// Expires is the time the entry is fresh until, stale entries
+	// are revalidated with ETag and Last-Modified validators
+	Expires time.Time
+}
+
+// CacheStorage stores cached responses, implementations must be safe for concurrent use
+type CacheStorage interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+	Delete(key string)
+}
+
+// MemoryCache is CacheStorage which keeps entries in memory
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]*CacheEntry
+}
+
+// NewMemoryCache returns empty MemoryCache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: map[string]*CacheEntry{}}
+}
+
+// Get implements CacheStorage
+func (m *MemoryCache) Get(key string) (*CacheEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[key]
+	return entry, ok
+}
+
+// Set implements CacheStorage
+func (m *MemoryCache) Set(key string, entry *CacheEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = entry
+}
+
+// Delete implements CacheStorage
+func (m *MemoryCache) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+}
+
+type cacheableKey struct{}
+
+type bypassCacheKey struct{}
+
+// withCacheable marks requests of ctx as cacheable by Cache middleware
+func withCacheable(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheableKey{}, true)
+}
+
+// BypassCache returns ctx which makes Cache middleware skip cached responses,
+// the fresh response still updates the cache
+func BypassCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassCacheKey{}, true)
+}
+
+// CacheOptions configures Cache middleware
+type CacheOptions struct {
+	// Storage of cached responses, it defaults to NewMemoryCache()
+	Storage CacheStorage
+}
+
+// Cache returns Middleware which caches GET responses of GetGroup,
+// GetGroupPreferences and User.Get. Cache-Control max-age, no-cache and
+// no-store are honored, stale responses are revalidated with If-None-Match
+// and If-Modified-Since. Requests with other methods invalidate the cached
+// response of their URL. Entries are keyed by URL, so a storage must not be
+// shared by clients with different credentials.
+func Cache(options CacheOptions) Middleware {
+	storage := options.Storage
+	if storage == nil {
+		storage = NewMemoryCache()
+	}
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*Response, error) {
+			key := req.URL.String()
+			if req.Method != "GET" {
+				storage.Delete(key)
+				return next.Do(req)
+			}
+			if cacheable, _ := req.Context().Value(cacheableKey{}).(bool); !cacheable {
+				return next.Do(req)
+			}
+
+			var entry *CacheEntry
+			if bypass, _ := req.Context().Value(bypassCacheKey{}).(bool); !bypass {
+				entry, _ = storage.Get(key)
+			}
+			if entry != nil && time.Now().Before(entry.Expires) {
+				return entry.response(req), nil
+			}
+
+			req2 := req
+			if entry != nil {
+				var err error
+				if req2, err = cloneRequest(req); err != nil {
+					return nil, err
+				}
+				if etag := entry.Header.Get("ETag"); etag != "" {
+					req2.Header.Set("If-None-Match", etag)
+				}
+				if modified := entry.Header.Get("Last-Modified"); modified != "" {
+					req2.Header.Set("If-Modified-Since", modified)
+				}
+			}
+			resp, err := next.Do(req2)
+			if err == nil && entry != nil && resp.StatusCode == http.StatusNotModified {
+				updated := *entry
+				updated.Expires = expires(resp.Header, time.Now())
+				storage.Set(key, &updated)
+				return updated.response(req), nil
+			}
+			if err != nil {
+				return resp, err
+			}
+			if fresh, ok := newCacheEntry(resp); ok {
+				storage.Set(key, fresh)
+			}
+			return resp, nil
+		})
+	}
+}
+
+// newCacheEntry returns entry of resp unless it must not be stored or
+// can not be used without validators
+func newCacheEntry(resp *Response) (*CacheEntry, bool) {
+	directives := cacheControl(resp.Header)
+	if _, ok := directives["no-store"]; ok {
+		return nil, false
+	}
+	entry := &CacheEntry{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Expires:    expires(resp.Header, time.Now()),
+	}
+	hasValidator := resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+	if !hasValidator && !entry.Expires.After(time.Now()) {
+		return nil, false
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	entry.Body = body
+	return entry, true
+}
+
+// response returns Response built from the entry for req
+func (e *CacheEntry) response(req *http.Request) *Response {
+	resp := newResponse(&http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	})
+	resp.Pagination = parsePagination(e.Body)
+	resp.Cached = true
+	return resp
+}
+
+// expires returns the time a response with header h received at now is fresh until
+func expires(h http.Header, now time.Time) time.Time {
+	directives := cacheControl(h)
+	if _, ok := directives["no-cache"]; ok {
+		return now
+	}
+	if maxAge, ok := directives["max-age"]; ok {
+		seconds, err := strconv.Atoi(maxAge)
+		if err != nil || seconds <= 0 {
+			return now
+		}
+		return now.Add(time.Duration(seconds) * time.Second)
+	}
+	if v := h.Get("Expires"); v != "" {
+		if t, err := http.ParseTime(v); err == nil {
+			return t
+		}
+	}
+	return now
+}
+
+// cacheControl parses Cache-Control header into directives and their values
+func cacheControl(h http.Header) map[string]string {
+	directives := map[string]string{}
+	for _, value := range h.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.TrimSpace(directive)
+			if directive == "" {
+				continue
+			}
+			name, arg := directive, ""
+			if i := strings.Index(directive, "="); i >= 0 {
+				name, arg = directive[:i], strings.Trim(directive[i+1:], `"`)
+			}
+			directives[strings.ToLower(name)] = arg
+		}
+	}
+	return directives
+}
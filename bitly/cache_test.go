@@ -0,0 +1,146 @@
+package bitly
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	notModified := map[string]int{}
+	lastModified := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC).Format(http.TimeFormat)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		key := r.Method + " " + r.URL.Path
+		requests[key]++
+		switch key {
+		case "GET /v4/groups/Ba1":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				notModified[key]++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Cache-Control", "private, no-cache")
+			w.Write([]byte(`{"guid":"Ba1","name":"cached"}`))
+		case "GET /v4/groups/Ba1/preferences":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Write([]byte(`{"group_guid":"Ba1","domain_preference":"bit.ly"}`))
+		case "GET /v4/groups/Bnostore":
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(`{"guid":"Bnostore"}`))
+		case "GET /v4/groups/Ba1/bitlinks":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Write([]byte(`{"links":[]}`))
+		case "GET /v4/user":
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				notModified[key]++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Last-Modified", lastModified)
+			w.Write([]byte(`{"login":"test"}`))
+		case "PATCH /v4/user":
+			w.Write([]byte(`{"login":"test","name":"new"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL
+	c.Use(Cache(CacheOptions{}))
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		group, resp, err := c.Groups.GetGroup(ctx, "Ba1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if group.Name != "cached" || resp.StatusCode != 200 || resp.Cached != (i > 0) {
+			t.Fatalf("unexpected group %#v from cache %v", group, resp.Cached)
+		}
+
+		prefs, resp, err := c.Groups.GetGroupPreferences(ctx, "Ba1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if prefs.DomainPreference != "bit.ly" || resp.Cached != (i > 0) {
+			t.Fatalf("unexpected preferences %#v from cache %v", prefs, resp.Cached)
+		}
+
+		user, resp, err := c.User.Get(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if user.Login != "test" || resp.Cached != (i > 0) {
+			t.Fatalf("unexpected user %#v from cache %v", user, resp.Cached)
+		}
+
+		if _, resp, err := c.Groups.GetGroup(ctx, "Bnostore"); err != nil || resp.Cached {
+			t.Fatalf("want no-store response not to be cached got %v %v", resp.Cached, err)
+		}
+		if _, resp, err := c.Groups.GetBitlinksByGroup(ctx, "Ba1", nil); err != nil || resp.Cached {
+			t.Fatalf("want bitlinks not to be cached got %v %v", resp.Cached, err)
+		}
+	}
+
+	if _, resp, err := c.Groups.GetGroupPreferences(BypassCache(ctx), "Ba1"); err != nil || resp.Cached {
+		t.Fatalf("want bypassed request to reach server got %v %v", resp.Cached, err)
+	}
+	if _, _, err := c.User.Update(ctx, &UserUpdateOptions{Name: "new"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, resp, err := c.User.Get(ctx); err != nil || resp.Cached {
+		t.Fatalf("want update to invalidate user got %v %v", resp.Cached, err)
+	}
+
+	wantRequests := map[string]int{
+		"GET /v4/groups/Ba1":             2,
+		"GET /v4/groups/Ba1/preferences": 2,
+		"GET /v4/groups/Bnostore":        2,
+		"GET /v4/groups/Ba1/bitlinks":    2,
+		"GET /v4/user":                   3,
+		"PATCH /v4/user":                 1,
+	}
+	if !reflect.DeepEqual(wantRequests, requests) {
+		t.Fatalf("want requests %v got %v", wantRequests, requests)
+	}
+	wantNotModified := map[string]int{"GET /v4/groups/Ba1": 1, "GET /v4/user": 1}
+	if !reflect.DeepEqual(wantNotModified, notModified) {
+		t.Fatalf("want not modified %v got %v", wantNotModified, notModified)
+	}
+}
+
+func TestExpires(t *testing.T) {
+	now := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	testCases := []struct {
+		desc   string
+		header http.Header
+		want   time.Time
+	}{
+		{desc: "no headers", header: http.Header{}, want: now},
+		{desc: "max-age", header: http.Header{"Cache-Control": {"public, max-age=30"}}, want: now.Add(30 * time.Second)},
+		{desc: "quoted max-age", header: http.Header{"Cache-Control": {`max-age="30"`}}, want: now.Add(30 * time.Second)},
+		{desc: "invalid max-age", header: http.Header{"Cache-Control": {"max-age=soon"}}, want: now},
+		{desc: "no-cache wins", header: http.Header{"Cache-Control": {"max-age=30", "no-cache"}}, want: now},
+		{desc: "expires", header: http.Header{"Expires": {"Wed, 02 Jan 2019 04:04:05 GMT"}}, want: now.Add(time.Hour)},
+		{desc: "max-age wins over expires", header: http.Header{"Cache-Control": {"max-age=30"}, "Expires": {"Wed, 02 Jan 2019 04:04:05 GMT"}}, want: now.Add(30 * time.Second)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := expires(tc.header, now); !got.Equal(tc.want) {
+				t.Fatalf("want %v got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package bitly
+
+import (
+	"context"
+	"github.com/google/go-querystring/query"
+	"net/url"
+	"strings"
+)
+
+// Call sends a request to any Bitly endpoint, it is useful for endpoints
+// without a service method. The request goes through the middleware chain
+// like requests of services, so authentication and retries apply, and
+// failures are returned as *ErrorResponse.
+//
+// path is relative to the API version, like /groups/Ba1/tags. Paths starting
+// with /v4/ and absolute pagination links from Response.Pagination are used as is.
+// params is url.Values or a struct with url tags, like GetBitlinksByGroupQueryParams,
+// it is added to the query of path. body is encoded as JSON and the response is
+// decoded into out like Do does.
+func (c *Client) Call(ctx context.Context, method, path string, params, body, out interface{}) (*Response, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsAbs() && u.Path != "/"+apiVersion && !strings.HasPrefix(u.Path, "/"+apiVersion+"/") {
+		u.Path = versioned(u.Path)
+	}
+	if params != nil {
+		values, ok := params.(url.Values)
+		if !ok {
+			if values, err = query.Values(params); err != nil {
+				return nil, err
+			}
+		}
+		q := u.Query()
+		for k, v := range values {
+			q[k] = append(q[k], v...)
+		}
+		u.RawQuery = q.Encode()
+	}
+	ctx = withOperation(ctx, "Call", u.Path)
+	return c.sendRequest(ctx, u.String(), body, out, strings.ToUpper(method))
+}
@@ -0,0 +1,155 @@
+package bitly
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_Call(t *testing.T) {
+	var gotMethod, gotURL, gotBody, gotAuth string
+	var gotOperation Operation
+	failures := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v4/flaky" && failures == 0 {
+			failures++
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		gotMethod, gotURL, gotBody, gotAuth = r.Method, r.URL.String(), string(body), r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/v4/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"NOT_FOUND"}`))
+		case "/v4/groups/Ba1/bitlinks":
+			w.Write([]byte(`{"links":[{"id":"bit.ly/a"}],"pagination":{"next":"https://api-ssl.bitly.com/v4/groups/Ba1/bitlinks?page=2","page":1}}`))
+		default:
+			w.Write([]byte(`{"tags":["a","b"]}`))
+		}
+	}))
+	defer s.Close()
+
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL
+	c.Use(
+		Authenticate(NewOauthTokenCredentials("token")),
+		Retry(RetryOptions{MaxRetries: 1, MinBackoff: time.Millisecond}),
+		func(next Doer) Doer {
+			return DoerFunc(func(req *http.Request) (*Response, error) {
+				gotOperation, _ = OperationFromContext(req.Context())
+				return next.Do(req)
+			})
+		},
+	)
+	ctx := context.Background()
+
+	testCases := []struct {
+		desc          string
+		method        string
+		path          string
+		params        interface{}
+		body          interface{}
+		wantMethod    string
+		wantURL       string
+		wantBody      string
+		wantOperation Operation
+		wantErr       string
+	}{
+		{
+			desc:          "relative path is versioned",
+			method:        "get",
+			path:          "groups/Ba1/tags",
+			wantMethod:    "GET",
+			wantURL:       "/v4/groups/Ba1/tags",
+			wantOperation: Operation{Name: "Call", Path: "/v4/groups/Ba1/tags"},
+		},
+		{
+			desc:       "versioned path with query struct",
+			method:     "GET",
+			path:       "/v4/groups/Ba1/tags?sort=asc",
+			params:     &GetBitlinksByGroupQueryParams{Size: 10, Tags: []string{"x", "y"}},
+			wantMethod: "GET",
+			wantURL:    "/v4/groups/Ba1/tags?size=10&sort=asc&tags=x&tags=y",
+		},
+		{
+			desc:       "url values and body",
+			method:     "POST",
+			path:       "/bitlinks",
+			params:     url.Values{"a": {"1"}},
+			body:       map[string]string{"long_url": "http://example.com"},
+			wantMethod: "POST",
+			wantURL:    "/v4/bitlinks?a=1",
+			wantBody:   `{"long_url":"http://example.com"}` + "\n",
+		},
+		{
+			desc:       "absolute pagination link",
+			method:     "GET",
+			path:       "https://api-ssl.bitly.com/v4/groups/Ba1/bitlinks?page=2",
+			wantMethod: "GET",
+			wantURL:    "/v4/groups/Ba1/bitlinks?page=2",
+		},
+		{
+			desc:       "retried",
+			method:     "GET",
+			path:       "/flaky",
+			wantMethod: "GET",
+			wantURL:    "/v4/flaky",
+		},
+		{
+			desc:    "error response",
+			method:  "GET",
+			path:    "/missing",
+			wantErr: "404 NOT_FOUND",
+		},
+		{
+			desc:    "invalid params",
+			method:  "GET",
+			path:    "/tags",
+			params:  "size=1",
+			wantErr: "query: Values() expects struct input",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			gotMethod, gotURL, gotBody, gotAuth = "", "", "", ""
+			_, err := c.Call(ctx, tc.method, tc.path, tc.params, tc.body, nil)
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("want error %v got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if gotMethod != tc.wantMethod || gotURL != tc.wantURL || gotBody != tc.wantBody || gotAuth != "Bearer token" {
+				t.Fatalf("unexpected request %s %s %q auth %q", gotMethod, gotURL, gotBody, gotAuth)
+			}
+			if tc.wantOperation.Name != "" && gotOperation != tc.wantOperation {
+				t.Fatalf("want operation %v got %v", tc.wantOperation, gotOperation)
+			}
+		})
+	}
+
+	out := &BitlinksByGroup{}
+	resp, err := c.Call(ctx, "GET", "/groups/Ba1/bitlinks", nil, nil, out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(out.Links, []Bitlink{{ID: "bit.ly/a"}}) || resp.Pagination == nil || resp.Pagination.Page != 1 {
+		t.Fatalf("unexpected links %#v and pagination %#v", out.Links, resp.Pagination)
+	}
+	if _, err := c.Call(ctx, "GET", resp.Pagination.Next, nil, nil, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotURL != "/v4/groups/Ba1/bitlinks?page=2" {
+		t.Fatalf("want next page requested got %v", gotURL)
+	}
+}
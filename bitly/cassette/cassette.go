@@ -0,0 +1,320 @@
+// Package cassette provides an http.RoundTripper which records HTTP
+// interactions to a file and replays them later, so tests written against
+// the live Bitly API can run offline.
+//
+// In ModeRecord requests go through the underlying transport and every
+// request/response pair is kept in memory until Save writes the cassette.
+// Authorization headers, access_token query parameters and configured
+// secrets are scrubbed before anything is stored.
+//
+// In ModeReplay responses are served from the cassette, requests are matched
+// on method, path, query and body; the host is ignored so the cassette works
+// with any base URL.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Mode is the way Transport handles requests
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette file
+	ModeReplay Mode = iota
+	// ModeRecord sends requests and records interactions
+	ModeRecord
+)
+
+const redacted = "REDACTED"
+
+var (
+	// ErrInteractionNotFound is returned in ModeReplay when the cassette has no matching request
+	ErrInteractionNotFound = errors.New("cassette: interaction not found")
+
+	defaultScrubHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+	defaultScrubParams  = []string{"access_token"}
+)
+
+// Request is a recorded request
+type Request struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// Response is a recorded response
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Interaction is a recorded request/response pair
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type cassetteFile struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Transport records or replays HTTP interactions
+type Transport struct {
+	path string
+	mode Mode
+
+	// Transport is used to send requests in ModeRecord.
+	// It will default to http.DefaultTransport if nil.
+	Transport http.RoundTripper
+	// Secrets are replaced with REDACTED in recorded URLs, headers and bodies
+	Secrets []string
+
+	mu           sync.Mutex
+	interactions []Interaction
+	replayed     []bool
+}
+
+// New returns Transport for the cassette at path. In ModeReplay the
+// cassette is loaded immediately and must exist.
+func New(path string, mode Mode) (*Transport, error) {
+	t := &Transport{path: path, mode: mode}
+	if mode != ModeReplay {
+		return t, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &cassetteFile{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, errors.Wrapf(err, "cassette: invalid file %s", path)
+	}
+	t.interactions = f.Interactions
+	t.replayed = make([]bool, len(f.Interactions))
+	return t, nil
+}
+
+// Exists reports whether cassette file at path exists
+func Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Mode returns the mode of the transport
+func (t *Transport) Mode() Mode {
+	return t.mode
+}
+
+// Client returns *http.Client which uses the transport
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// Interactions returns recorded or loaded interactions
+func (t *Transport) Interactions() []Interaction {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Interaction(nil), t.interactions...)
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := t.scrubRequest(req, body)
+
+	if t.mode == ModeReplay {
+		return t.replay(req, recorded)
+	}
+	return t.record(req, body, recorded)
+}
+
+// Save writes recorded interactions to the cassette file, it does nothing in ModeReplay
+func (t *Transport) Save() error {
+	if t.mode != ModeRecord {
+		return nil
+	}
+	t.mu.Lock()
+	data, err := json.MarshalIndent(&cassetteFile{Interactions: t.interactions}, "", "  ")
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(t.path, append(data, '\n'), 0600)
+}
+
+func (t *Transport) record(req *http.Request, body []byte, recorded Request) (*http.Response, error) {
+	// The body of req is consumed already, send a copy with the buffered body
+	req2 := req.Clone(req.Context())
+	if body != nil {
+		req2.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	resp, err := t.transport().RoundTrip(req2)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	headers := t.scrubHeaders(resp.Header)
+	interaction := Interaction{
+		Request: recorded,
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Headers:    headers,
+			Body:       t.scrub(string(data)),
+		},
+	}
+	t.mu.Lock()
+	t.interactions = append(t.interactions, interaction)
+	t.mu.Unlock()
+	return resp, nil
+}
+
+func (t *Transport) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// The first unused match is served, so repeated requests replay in order,
+	// once all matches are used the last one is served again
+	found := -1
+	for i, interaction := range t.interactions {
+		if !match(interaction.Request, recorded) {
+			continue
+		}
+		found = i
+		if !t.replayed[i] {
+			break
+		}
+	}
+	if found == -1 {
+		return nil, errors.Wrapf(ErrInteractionNotFound, "%s %s", recorded.Method, recorded.URL)
+	}
+	t.replayed[found] = true
+
+	r := t.interactions[found].Response
+	headers := http.Header{}
+	for k, v := range r.Headers {
+		headers[k] = append([]string(nil), v...)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        headers,
+		Body:          ioutil.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}, nil
+}
+
+func (t *Transport) scrubRequest(req *http.Request, body []byte) Request {
+	u := *req.URL
+	q := u.Query()
+	for _, param := range defaultScrubParams {
+		if _, ok := q[param]; ok {
+			q.Set(param, redacted)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return Request{
+		Method:  req.Method,
+		URL:     t.scrub(u.String()),
+		Headers: t.scrubHeaders(req.Header),
+		Body:    t.scrub(string(body)),
+	}
+}
+
+func (t *Transport) scrubHeaders(h http.Header) http.Header {
+	scrubbed := http.Header{}
+	for k, v := range h {
+		values := make([]string, len(v))
+		for i := range v {
+			values[i] = t.scrub(v[i])
+		}
+		scrubbed[k] = values
+	}
+	for _, name := range defaultScrubHeaders {
+		if _, ok := scrubbed[http.CanonicalHeaderKey(name)]; ok {
+			scrubbed.Set(name, redacted)
+		}
+	}
+	return scrubbed
+}
+
+func (t *Transport) scrub(s string) string {
+	for _, secret := range t.Secrets {
+		if secret != "" {
+			s = strings.Replace(s, secret, redacted, -1)
+		}
+	}
+	return s
+}
+
+func (t *Transport) transport() http.RoundTripper {
+	if t.Transport != nil {
+		return t.Transport
+	}
+	return http.DefaultTransport
+}
+
+// match compares requests on method, path, query and body
+func match(recorded, req Request) bool {
+	if recorded.Method != req.Method {
+		return false
+	}
+	ru, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return false
+	}
+	if ru.Path != u.Path || ru.Query().Encode() != u.Query().Encode() {
+		return false
+	}
+	return equalBody(recorded.Body, req.Body)
+}
+
+// equalBody compares JSON bodies ignoring formatting and other bodies as is
+func equalBody(a, b string) bool {
+	if strings.TrimSpace(a) == strings.TrimSpace(b) {
+		return true
+	}
+	var av, bv interface{}
+	if json.Unmarshal([]byte(a), &av) != nil || json.Unmarshal([]byte(b), &bv) != nil {
+		return false
+	}
+	ac, _ := json.Marshal(av)
+	bc, _ := json.Marshal(bv)
+	return bytes.Equal(ac, bc)
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	defer req.Body.Close()
+	return ioutil.ReadAll(req.Body)
+}
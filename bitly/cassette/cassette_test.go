@@ -0,0 +1,180 @@
+package cassette
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func doRequest(t *testing.T, c *http.Client, method, url, body string) (int, string, error) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer secret-token")
+	resp, err := c.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return resp.StatusCode, string(data), nil
+}
+
+func TestTransport_RecordReplay(t *testing.T) {
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/v4/user":
+			fmt.Fprintf(w, `{"login":"test","call":%d}`, calls)
+		case "/v4/shorten":
+			body, _ := ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"NOT_FOUND","token":"secret-token"}`))
+		}
+	}))
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorder, err := New(path, ModeRecord)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	recorder.Secrets = []string{"secret-token"}
+	c := recorder.Client()
+	requests := []struct {
+		method string
+		url    string
+		body   string
+	}{
+		{method: "GET", url: "/v4/user?b=2&a=1&access_token=secret"},
+		{method: "GET", url: "/v4/user?b=2&a=1&access_token=secret"},
+		{method: "POST", url: "/v4/shorten", body: `{"long_url":"http://example.com"}`},
+		{method: "GET", url: "/v4/unknown"},
+	}
+	var recorded []string
+	for _, r := range requests {
+		status, body, err := doRequest(t, c, r.method, s.URL+r.url, r.body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		recorded = append(recorded, fmt.Sprintf("%d %s", status, body))
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(data), "secret") || strings.Contains(string(data), "Bearer") {
+		t.Fatalf("cassette contains credentials: %s", data)
+	}
+
+	player, err := New(path, ModeReplay)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c = player.Client()
+	testCases := []struct {
+		desc    string
+		method  string
+		url     string
+		body    string
+		want    string
+		wantErr string
+	}{
+		{
+			desc:   "first repeated request",
+			method: "GET",
+			url:    "http://other.example.com/v4/user?a=1&b=2&access_token=other",
+			want:   recorded[0],
+		},
+		{
+			desc:   "second repeated request",
+			method: "GET",
+			url:    "http://other.example.com/v4/user?a=1&b=2&access_token=other",
+			want:   recorded[1],
+		},
+		{
+			desc:   "used requests are served again",
+			method: "GET",
+			url:    "http://other.example.com/v4/user?a=1&b=2&access_token=other",
+			want:   recorded[1],
+		},
+		{
+			desc:   "json body formatting is ignored",
+			method: "POST",
+			url:    "http://other.example.com/v4/shorten",
+			body:   `{ "long_url": "http://example.com" }`,
+			want:   recorded[2],
+		},
+		{
+			desc:   "error response",
+			method: "GET",
+			url:    "http://other.example.com/v4/unknown",
+			want:   `404 {"message":"NOT_FOUND","token":"REDACTED"}`,
+		},
+		{
+			desc:    "other body",
+			method:  "POST",
+			url:     "http://other.example.com/v4/shorten",
+			body:    `{"long_url":"http://example.org"}`,
+			wantErr: "cassette: interaction not found",
+		},
+		{
+			desc:    "other query",
+			method:  "GET",
+			url:     "http://other.example.com/v4/user?a=2&b=2&access_token=other",
+			wantErr: "cassette: interaction not found",
+		},
+		{
+			desc:    "other method",
+			method:  "DELETE",
+			url:     "http://other.example.com/v4/unknown",
+			wantErr: "cassette: interaction not found",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			status, body, err := doRequest(t, c, tc.method, tc.url, tc.body)
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("want error %v got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := fmt.Sprintf("%d %s", status, body); got != tc.want {
+				t.Fatalf("want response %v got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNew_MissingCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.json")
+	if Exists(path) {
+		t.Fatalf("want cassette %v not to exist", path)
+	}
+	if _, err := New(path, ModeReplay); err == nil {
+		t.Fatalf("want error for missing cassette")
+	}
+	if _, err := New(path, ModeRecord); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+package bitly
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCircuitFailureRatio     = 0.5
+	defaultCircuitMinRequests      = 10
+	defaultCircuitWindow           = time.Minute
+	defaultCircuitCoolDown         = 30 * time.Second
+	defaultCircuitHalfOpenRequests = 1
+)
+
+// CircuitState is the state of a circuit
+type CircuitState int
+
+const (
+	// CircuitClosed lets requests through and counts failures
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects requests with *ErrCircuitOpen until the cool-down passes
+	CircuitOpen
+	// CircuitHalfOpen lets trial requests through, they close or reopen the circuit
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// CircuitScope decides which requests share a circuit
+type CircuitScope int
+
+const (
+	// CircuitScopeGlobal uses one circuit for all requests of the client
+	CircuitScopeGlobal CircuitScope = iota
+	// CircuitScopeEndpoint uses a circuit per Operation, like Groups.GetGroup
+	CircuitScopeEndpoint
+)
+
+// ErrCircuitOpen is returned by CircuitBreaker middleware when the circuit
+// rejects a request, the request is not sent to Bitly
+type ErrCircuitOpen struct {
+	// Endpoint is the Operation name of the circuit, it is empty for CircuitScopeGlobal
+	Endpoint string
+	// RetryAfter is the time left until the circuit lets a trial request through
+	RetryAfter time.Duration
+}
+
+func (e *ErrCircuitOpen) Error() string {
+	if e.Endpoint == "" {
+		return fmt.Sprintf("bitly: circuit open, retry after %v", e.RetryAfter)
+	}
+	return fmt.Sprintf("bitly: circuit open for %s, retry after %v", e.Endpoint, e.RetryAfter)
+}
+
+// CircuitBreakerOptions configures CircuitBreaker middleware
+type CircuitBreakerOptions struct {
+	// Scope of circuits, it defaults to CircuitScopeGlobal
+	Scope CircuitScope
+	// FailureRatio of requests in Window which opens the circuit, it defaults to 0.5.
+	// Network errors, 429 and 5xx responses are failures.
+	FailureRatio float64
+	// MinRequests in Window before FailureRatio is checked, it defaults to 10
+	MinRequests int
+	// Window is the interval failures are counted in, it defaults to 1m
+	Window time.Duration
+	// CoolDown is the time the circuit stays open, it defaults to 30s
+	CoolDown time.Duration
+	// HalfOpenRequests is the number of successful trial requests which close
+	// the circuit, it defaults to 1
+	HalfOpenRequests int
+	// OnStateChange is called when a circuit changes its state,
+	// endpoint is empty for CircuitScopeGlobal
+	OnStateChange func(endpoint string, from, to CircuitState)
+}
+
+type circuit struct {
+	state       CircuitState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	// trials in flight and succeeded in half-open state
+	trials    int
+	successes int
+}
+
+// circuitOutcome is the outcome of a request let through by a circuit
+type circuitOutcome int
+
+const (
+	circuitSucceeded circuitOutcome = iota
+	circuitFailed
+	// circuitCancelled requests are counted neither as successes nor failures
+	circuitCancelled
+)
+
+type stateChange struct {
+	endpoint string
+	from, to CircuitState
+}
+
+type circuitBreaker struct {
+	options CircuitBreakerOptions
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// CircuitBreaker returns Middleware which stops sending requests to Bitly when
+// too many of them fail. Once FailureRatio of requests fail the circuit opens and
+// requests fail fast with *ErrCircuitOpen, after CoolDown trial requests are let
+// through to check if Bitly recovered. Use it after Retry, so every attempt counts.
+func CircuitBreaker(options CircuitBreakerOptions) Middleware {
+	if options.FailureRatio <= 0 {
+		options.FailureRatio = defaultCircuitFailureRatio
+	}
+	if options.MinRequests <= 0 {
+		options.MinRequests = defaultCircuitMinRequests
+	}
+	if options.Window <= 0 {
+		options.Window = defaultCircuitWindow
+	}
+	if options.CoolDown <= 0 {
+		options.CoolDown = defaultCircuitCoolDown
+	}
+	if options.HalfOpenRequests <= 0 {
+		options.HalfOpenRequests = defaultCircuitHalfOpenRequests
+	}
+	b := &circuitBreaker{options: options, circuits: map[string]*circuit{}}
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*Response, error) {
+			endpoint := ""
+			if options.Scope == CircuitScopeEndpoint {
+				endpoint = requestOperation(req).Name
+			}
+			trial, err := b.allow(endpoint)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := next.Do(req)
+			outcome := circuitSucceeded
+			switch {
+			case err != nil && req.Context().Err() != nil:
+				// Requests cancelled by the caller say nothing about Bitly
+				outcome = circuitCancelled
+			case err != nil && isTemporaryError(err):
+				outcome = circuitFailed
+			}
+			b.done(endpoint, trial, outcome)
+			return resp, err
+		})
+	}
+}
+
+// allow reports whether a request to endpoint may be sent and whether it is a trial
+func (b *circuitBreaker) allow(endpoint string) (bool, error) {
+	var changes []stateChange
+	defer func() { b.notify(changes) }()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	c := b.circuit(endpoint, now)
+	if c.state == CircuitOpen {
+		if wait := c.openedAt.Add(b.options.CoolDown).Sub(now); wait > 0 {
+			return false, &ErrCircuitOpen{Endpoint: endpoint, RetryAfter: wait}
+		}
+		changes = append(changes, b.setState(endpoint, c, CircuitHalfOpen, now))
+	}
+	if c.state == CircuitHalfOpen {
+		if c.trials+c.successes >= b.options.HalfOpenRequests {
+			return false, &ErrCircuitOpen{Endpoint: endpoint}
+		}
+		c.trials++
+		return true, nil
+	}
+	return false, nil
+}
+
+// done records the outcome of a request allowed by allow, a cancelled trial
+// frees its slot so the circuit stays half-open for the next one
+func (b *circuitBreaker) done(endpoint string, trial bool, outcome circuitOutcome) {
+	var changes []stateChange
+	defer func() { b.notify(changes) }()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	c := b.circuit(endpoint, now)
+	switch {
+	case trial && c.state == CircuitHalfOpen:
+		c.trials--
+		if outcome == circuitCancelled {
+			return
+		}
+		if outcome == circuitFailed {
+			changes = append(changes, b.setState(endpoint, c, CircuitOpen, now))
+			return
+		}
+		c.successes++
+		if c.successes >= b.options.HalfOpenRequests {
+			changes = append(changes, b.setState(endpoint, c, CircuitClosed, now))
+		}
+	case !trial && c.state == CircuitClosed && outcome != circuitCancelled:
+		c.requests++
+		if outcome == circuitFailed {
+			c.failures++
+		}
+		if c.requests >= b.options.MinRequests && float64(c.failures)/float64(c.requests) >= b.options.FailureRatio {
+			changes = append(changes, b.setState(endpoint, c, CircuitOpen, now))
+		}
+	}
+}
+
+// circuit returns circuit of endpoint, counts of closed circuits are reset every Window
+func (b *circuitBreaker) circuit(endpoint string, now time.Time) *circuit {
+	c, ok := b.circuits[endpoint]
+	if !ok {
+		c = &circuit{windowStart: now}
+		b.circuits[endpoint] = c
+	}
+	if c.state == CircuitClosed && now.Sub(c.windowStart) >= b.options.Window {
+		c.windowStart, c.requests, c.failures = now, 0, 0
+	}
+	return c
+}
+
+func (b *circuitBreaker) setState(endpoint string, c *circuit, state CircuitState, now time.Time) stateChange {
+	change := stateChange{endpoint: endpoint, from: c.state, to: state}
+	c.state = state
+	c.windowStart, c.requests, c.failures = now, 0, 0
+	c.trials, c.successes = 0, 0
+	if state == CircuitOpen {
+		c.openedAt = now
+	}
+	return change
+}
+
+// notify calls OnStateChange outside of the lock, so callbacks may use the client
+func (b *circuitBreaker) notify(changes []stateChange) {
+	if b.options.OnStateChange == nil {
+		return
+	}
+	for _, change := range changes {
+		b.options.OnStateChange(change.endpoint, change.from, change.to)
+	}
+}
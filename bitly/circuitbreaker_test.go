@@ -0,0 +1,241 @@
+package bitly
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// failingServer answers requests to paths with the prefix set by fail with its status
+type failingServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	prefix   string
+	requests int
+}
+
+func newFailingServer() *failingServer {
+	s := &failingServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		if s.status != 0 && strings.HasPrefix(r.URL.Path, s.prefix) {
+			w.WriteHeader(s.status)
+			w.Write([]byte(`{"message":"FAILED"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	return s
+}
+
+func (s *failingServer) fail(prefix string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prefix, s.status = prefix, status
+}
+
+func (s *failingServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+type stateChanges struct {
+	mu      sync.Mutex
+	changes []string
+}
+
+func (c *stateChanges) record(endpoint string, from, to CircuitState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changes = append(c.changes, fmt.Sprintf("%s %v->%v", endpoint, from, to))
+}
+
+func (c *stateChanges) get() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.changes...)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	s := newFailingServer()
+	defer s.Close()
+
+	changes := &stateChanges{}
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL
+	c.Use(CircuitBreaker(CircuitBreakerOptions{
+		FailureRatio:  0.5,
+		MinRequests:   4,
+		CoolDown:      50 * time.Millisecond,
+		OnStateChange: changes.record,
+	}))
+	ctx := context.Background()
+	getUser := func() error {
+		_, _, err := c.User.Get(ctx)
+		return err
+	}
+
+	// Client errors are not failures, 1 of 4 failed keeps the circuit closed
+	s.fail("", http.StatusBadRequest)
+	for i := 0; i < 2; i++ {
+		if err := getUser(); err == nil {
+			t.Fatalf("want error")
+		}
+	}
+	s.fail("", http.StatusServiceUnavailable)
+	getUser()
+	s.fail("", 0)
+	if err := getUser(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := changes.get(); len(got) != 0 {
+		t.Fatalf("client errors must not open circuit, got changes %v", got)
+	}
+
+	// 3 of 6 failed opens the circuit
+	s.fail("", http.StatusBadGateway)
+	for i := 0; i < 4; i++ {
+		getUser()
+	}
+	requests := s.count()
+	err := getUser()
+	circuitErr, ok := err.(*ErrCircuitOpen)
+	if !ok || circuitErr.Endpoint != "" || circuitErr.RetryAfter <= 0 {
+		t.Fatalf("want *ErrCircuitOpen got %#v", err)
+	}
+	if s.count() != requests {
+		t.Fatalf("open circuit must not send requests")
+	}
+
+	// Failed trial reopens the circuit
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := getUser().(*ErrorResponse); !ok {
+		t.Fatalf("want trial request to reach server")
+	}
+	if _, ok := getUser().(*ErrCircuitOpen); !ok {
+		t.Fatalf("want circuit to reopen after failed trial")
+	}
+
+	// Successful trial closes the circuit
+	time.Sleep(60 * time.Millisecond)
+	s.fail("", 0)
+	if err := getUser(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := getUser(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		" closed->open",
+		" open->half-open",
+		" half-open->open",
+		" open->half-open",
+		" half-open->closed",
+	}
+	if got := changes.get(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("want changes %v got %v", want, got)
+	}
+}
+
+func TestCircuitBreaker_EndpointScope(t *testing.T) {
+	s := newFailingServer()
+	defer s.Close()
+
+	changes := &stateChanges{}
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL
+	c.Use(CircuitBreaker(CircuitBreakerOptions{
+		Scope:         CircuitScopeEndpoint,
+		MinRequests:   2,
+		OnStateChange: changes.record,
+	}))
+	ctx := context.Background()
+
+	s.fail("/v4/groups", http.StatusInternalServerError)
+	for i := 0; i < 2; i++ {
+		c.Groups.GetGroup(ctx, "Ba1")
+	}
+	_, _, err := c.Groups.GetGroup(ctx, "Ba2")
+	if circuitErr, ok := err.(*ErrCircuitOpen); !ok || circuitErr.Endpoint != "Groups.GetGroup" {
+		t.Fatalf("want *ErrCircuitOpen of Groups.GetGroup got %#v", err)
+	}
+	if !strings.Contains(err.Error(), "circuit open for Groups.GetGroup") {
+		t.Fatalf("unexpected error message %v", err)
+	}
+	if _, _, err := c.Groups.GetGroupPreferences(ctx, "Ba1"); err == nil {
+		t.Fatalf("want other endpoint to reach server")
+	} else if _, ok := err.(*ErrCircuitOpen); ok {
+		t.Fatalf("want other endpoint circuit closed got %v", err)
+	}
+	if _, _, err := c.User.Get(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := []string{"Groups.GetGroup closed->open"}, changes.get(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("want changes %v got %v", want, got)
+	}
+}
+
+func TestCircuitBreaker_CancelledRequests(t *testing.T) {
+	s := newFailingServer()
+	defer s.Close()
+
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL
+	c.Use(CircuitBreaker(CircuitBreakerOptions{MinRequests: 1}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 3; i++ {
+		if _, _, err := c.User.Get(ctx); err == nil {
+			t.Fatalf("want error")
+		}
+	}
+	if _, _, err := c.User.Get(context.Background()); err != nil {
+		t.Fatalf("cancelled requests must not open circuit, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_CancelledTrial(t *testing.T) {
+	s := newFailingServer()
+	defer s.Close()
+
+	changes := &stateChanges{}
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL
+	c.Use(CircuitBreaker(CircuitBreakerOptions{
+		MinRequests:   1,
+		CoolDown:      20 * time.Millisecond,
+		OnStateChange: changes.record,
+	}))
+
+	s.fail("", http.StatusServiceUnavailable)
+	c.User.Get(context.Background())
+	time.Sleep(30 * time.Millisecond)
+	s.fail("", 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := c.User.Get(ctx); err == nil {
+		t.Fatalf("want error of cancelled trial")
+	}
+	if want, got := []string{" closed->open", " open->half-open"}, changes.get(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("cancelled trial must keep circuit half-open, want changes %v got %v", want, got)
+	}
+	if _, _, err := c.User.Get(context.Background()); err != nil {
+		t.Fatalf("want trial slot released, got %v", err)
+	}
+	if want, got := []string{" closed->open", " open->half-open", " half-open->closed"}, changes.get(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("want changes %v got %v", want, got)
+	}
+}
@@ -0,0 +1,176 @@
+package bitly
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// UnknownFieldsError is returned by Do in strict decoding mode, see Client.StrictDecoding,
+// when the response has fields the model does not know. The model is decoded
+// anyway and service methods return it along with the error.
+type UnknownFieldsError struct {
+	// Fields are paths of unknown fields, like links[0].is_deleted
+	Fields []string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	return fmt.Sprintf("bitly: response has unknown fields: %s", strings.Join(e.Fields, ", "))
+}
+
+// decoded reports whether the model passed to Do is decoded despite err, Groups
+// and Bitlinks methods return the model with *UnknownFieldsError and nil otherwise
+func decoded(err error) bool {
+	if err == nil {
+		return true
+	}
+	_, ok := err.(*UnknownFieldsError)
+	return ok
+}
+
+// knownFieldsCache maps reflect.Type of a struct to lower cased JSON names of its fields
+var knownFieldsCache sync.Map
+
+// knownFields returns JSON names of fields of struct type t, lower cased
+// because encoding/json matches names case-insensitively
+func knownFields(t reflect.Type) map[string]reflect.Type {
+	if fields, ok := knownFieldsCache.Load(t); ok {
+		return fields.(map[string]reflect.Type)
+	}
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for k, v := range knownFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = f.Type
+	}
+	knownFieldsCache.Store(t, fields)
+	return fields
+}
+
+// unmarshalExtra decodes data into v, a pointer to a struct without JSON methods,
+// and returns fields of data v does not know
+func unmarshalExtra(data []byte, v interface{}) (map[string]json.RawMessage, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	known := knownFields(reflect.TypeOf(v).Elem())
+	for k := range raw {
+		if _, ok := known[strings.ToLower(k)]; ok {
+			delete(raw, k)
+		}
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return raw, nil
+}
+
+// marshalExtra encodes v, a struct without JSON methods, and appends extra
+// fields which v does not have, sorted by name
+func marshalExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+	known := knownFields(reflect.TypeOf(v))
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		if _, ok := known[strings.ToLower(k)]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	buf := bytes.NewBuffer(data[:len(data)-1])
+	for _, k := range keys {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		if err := json.Compact(buf, extra[k]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// unknownFields returns paths of fields of data which type t does not know
+func unknownFields(data []byte, t reflect.Type) []string {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil
+	}
+	var fields []string
+	collectUnknownFields(raw, t, "", &fields)
+	sort.Strings(fields)
+	return fields
+}
+
+func collectUnknownFields(raw interface{}, t reflect.Type, path string, fields *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Struct:
+			known := knownFields(t)
+			for k, value := range v {
+				ft, ok := known[strings.ToLower(k)]
+				if !ok {
+					*fields = append(*fields, joinPath(path, k))
+					continue
+				}
+				collectUnknownFields(value, ft, joinPath(path, k), fields)
+			}
+		case reflect.Map:
+			for k, value := range v {
+				collectUnknownFields(value, t.Elem(), joinPath(path, k), fields)
+			}
+		}
+	case []interface{}:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return
+		}
+		for i, value := range v {
+			collectUnknownFields(value, t.Elem(), fmt.Sprintf("%s[%d]", path, i), fields)
+		}
+	}
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
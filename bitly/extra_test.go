@@ -0,0 +1,203 @@
+package bitly
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestExtra_RoundTrip(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		data      string
+		model     interface{}
+		wantExtra map[string]json.RawMessage
+		want      string
+	}{
+		{
+			desc:      "group",
+			data:      `{"guid":"Ba1","name":"test","is_deleted":false,"limits":{"links":100}}`,
+			model:     &Group{},
+			wantExtra: map[string]json.RawMessage{"is_deleted": json.RawMessage(`false`), "limits": json.RawMessage(`{"links":100}`)},
+			want:      `{"references":null,"name":"test","bsds":null,"is_active":false,"created":null,"modified":null,"organization_guid":"","role":"","guid":"Ba1","is_deleted":false,"limits":{"links":100}}`,
+		},
+		{
+			desc:  "known fields only",
+			data:  `{"group_guid":"Ba1","domain_preference":"bit.ly"}`,
+			model: &GroupPreferences{},
+			want:  `{"group_guid":"Ba1","domain_preference":"bit.ly"}`,
+		},
+		{
+			desc:      "field names are case insensitive",
+			data:      `{"Login":"test","EMAILS":[],"Locale":"en"}`,
+			model:     &User{},
+			wantExtra: map[string]json.RawMessage{"Locale": json.RawMessage(`"en"`)},
+			want:      `{"name":"","created":null,"modified":null,"login":"test","is_active":false,"is_2fa_enabled":false,"emails":[],"is_sso_user":false,"Locale":"en"}`,
+		},
+		{
+			desc:      "email",
+			data:      `{"email":"test@example.com","is_primary":true,"is_verified":true,"verified_at":"2019-01-01"}`,
+			model:     &Email{},
+			wantExtra: map[string]json.RawMessage{"verified_at": json.RawMessage(`"2019-01-01"`)},
+			want:      `{"email":"test@example.com","is_primary":true,"is_verified":true,"verified_at":"2019-01-01"}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := json.Unmarshal([]byte(tc.data), tc.model); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			extra := reflect.ValueOf(tc.model).Elem().FieldByName("Extra").Interface().(map[string]json.RawMessage)
+			if !reflect.DeepEqual(tc.wantExtra, extra) {
+				t.Fatalf("want extra %s got %s", tc.wantExtra, extra)
+			}
+			data, err := json.Marshal(tc.model)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(data) != tc.want {
+				t.Fatalf("want json %s got %s", tc.want, data)
+			}
+		})
+	}
+}
+
+func TestExtra_Nested(t *testing.T) {
+	data := `{"id":"bit.ly/a","deeplinks":[{"guid":"d1","os":"ios","brand_guid":"Br1"}],"is_deleted":true}`
+	link := &Bitlink{}
+	if err := json.Unmarshal([]byte(data), link); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(link.Extra["is_deleted"]) != "true" || string(link.DeepLinks[0].Extra["brand_guid"]) != `"Br1"` {
+		t.Fatalf("unexpected extra %s and %s", link.Extra, link.DeepLinks[0].Extra)
+	}
+	link.Extra["title"] = json.RawMessage(`"ignored"`)
+	got, err := json.Marshal(link)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(got, &fields); err != nil {
+		t.Fatalf("invalid json %s: %v", got, err)
+	}
+	deepLink := fields["deeplinks"].([]interface{})[0].(map[string]interface{})
+	if fields["is_deleted"] != true || fields["title"] != "" || deepLink["brand_guid"] != "Br1" {
+		t.Fatalf("unexpected json %s", got)
+	}
+}
+
+func TestClient_StrictDecoding(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"links":[{"id":"bit.ly/a","references":{"group":"g"},"deeplinks":[{"guid":"d1","brand_guid":"Br1"}]},{"id":"bit.ly/b","is_deleted":true}],"pagination":{"page":1,"search_after":"x"},"total":2}`))
+	}))
+	defer s.Close()
+
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL
+	links, _, err := c.Groups.GetBitlinksByGroup(context.Background(), "Ba1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(links.Links) != 2 {
+		t.Fatalf("unexpected links %#v", links)
+	}
+
+	c.StrictDecoding = true
+	out := &BitlinksByGroup{}
+	req, err := c.NewRequest(context.Background(), "GET", "/v4/groups/Ba1/bitlinks", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = c.Do(req, out)
+	unknown, ok := err.(*UnknownFieldsError)
+	if !ok {
+		t.Fatalf("want *UnknownFieldsError got %v", err)
+	}
+	want := []string{"links[0].deeplinks[0].brand_guid", "links[1].is_deleted", "pagination.search_after", "total"}
+	if !reflect.DeepEqual(want, unknown.Fields) {
+		t.Fatalf("want fields %v got %v", want, unknown.Fields)
+	}
+	if len(out.Links) != 2 || out.Links[1].ID != "bit.ly/b" {
+		t.Fatalf("want response decoded got %#v", out)
+	}
+	if _, _, err := c.Groups.GetGroupPreferences(context.Background(), "Ba1"); err == nil {
+		t.Fatalf("want unknown fields error from service method")
+	}
+}
+
+func TestClient_StrictDecodingServices(t *testing.T) {
+	var failing int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"INVALID_ARG"}`))
+			return
+		}
+		w.Write([]byte(`{"id":"bit.ly/a","login":"user","guid":"Ba1","unknown_field":true}`))
+	}))
+	defer s.Close()
+
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL
+	c.StrictDecoding = true
+	ctx := context.Background()
+
+	testCases := []struct {
+		desc string
+		call func() (interface{}, error)
+	}{
+		{"User.Get", func() (interface{}, error) { m, _, err := c.User.Get(ctx); return m, err }},
+		{"User.Update", func() (interface{}, error) { m, _, err := c.User.Update(ctx, &UserUpdateOptions{}); return m, err }},
+		{"User.GetGroups", func() (interface{}, error) { m, _, err := c.User.GetGroups(ctx, "user"); return m, err }},
+		{"Groups.ListGroups", func() (interface{}, error) { m, _, err := c.Groups.ListGroups(ctx, ""); return m, err }},
+		{"Groups.GetGroup", func() (interface{}, error) { m, _, err := c.Groups.GetGroup(ctx, "Ba1"); return m, err }},
+		{"Groups.GetGroupPreferences", func() (interface{}, error) { m, _, err := c.Groups.GetGroupPreferences(ctx, "Ba1"); return m, err }},
+		{"Groups.GetBitlinksByGroup", func() (interface{}, error) { m, _, err := c.Groups.GetBitlinksByGroup(ctx, "Ba1", nil); return m, err }},
+		{"Groups.GetBitlinksByGroupPaginator", func() (interface{}, error) {
+			p, err := c.Groups.GetBitlinksByGroupPaginator(ctx, "Ba1", nil)
+			if err != nil {
+				return nil, err
+			}
+			var page *BitlinksByGroup
+			if err = p.Get(); decoded(err) {
+				page = p.Resp
+			}
+			return page, err
+		}},
+		{"Bitlinks.Shorten", func() (interface{}, error) {
+			m, _, err := c.Bitlinks.Shorten(ctx, &ShortenOptions{LongURL: "http://example.com"})
+			return m, err
+		}},
+		{"Bitlinks.Update", func() (interface{}, error) {
+			m, _, err := c.Bitlinks.Update(ctx, "bit.ly/a", &BitlinkUpdateOptions{})
+			return m, err
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			atomic.StoreInt32(&failing, 0)
+			model, err := tc.call()
+			if _, ok := err.(*UnknownFieldsError); !ok {
+				t.Fatalf("want *UnknownFieldsError got %v", err)
+			}
+			if reflect.ValueOf(model).IsNil() {
+				t.Fatalf("want model decoded along with unknown fields error")
+			}
+
+			atomic.StoreInt32(&failing, 1)
+			model, err = tc.call()
+			if _, ok := err.(*ErrorResponse); !ok {
+				t.Fatalf("want *ErrorResponse got %v", err)
+			}
+			// User methods return an empty model with errors, the others nil
+			if isNil := reflect.ValueOf(model).IsNil(); isNil == strings.HasPrefix(tc.desc, "User.") {
+				t.Fatalf("unexpected model with error %#v", model)
+			}
+		})
+	}
+}
@@ -1,71 +1,171 @@
 package bitly
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/google/go-querystring/query"
 	"strings"
 )
 
 type GroupsService interface {
-	ListGroups(string) (*groupsResponse, error)
-	GetGroup(string) (*groupResponse, error)
-	GetGroupPreferences(string) (*groupPrefResponse, error)
-	GetBitlinksByGroup(GroupGUID string, queryParams *GetBitlinksByGroupQueryParams) (*getBitlinksByGroupResponse, error)
+	ListGroups(ctx context.Context, OrganizationGUID string) (*GroupList, *Response, error)
+	GetGroup(ctx context.Context, GroupGUID string) (*Group, *Response, error)
+	GetGroupPreferences(ctx context.Context, GroupGUID string) (*GroupPreferences, *Response, error)
+	GetBitlinksByGroup(ctx context.Context, GroupGUID string, queryParams *GetBitlinksByGroupQueryParams) (*BitlinksByGroup, *Response, error)
+	GetBitlinksByGroupPaginator(ctx context.Context, GroupGUID string, queryParams *GetBitlinksByGroupQueryParams) (*BitlinksByGroupPaginator, error)
 }
 
 type GroupsClient struct {
 	client *Client
 }
 
-type groupPrefResponse struct {
+// GroupPreferences is returned by GetGroupPreferences
+type GroupPreferences struct {
 	GroupGUID        string `json:"group_guid"`
 	DomainPreference string `json:"domain_preference"`
+
+	// Extra holds fields returned by Bitly which this package does not know
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, unknown fields are kept in Extra
+func (p *GroupPreferences) UnmarshalJSON(data []byte) error {
+	type groupPreferences GroupPreferences
+	extra, err := unmarshalExtra(data, (*groupPreferences)(p))
+	p.Extra = extra
+	return err
 }
 
-type groupResponse struct {
+// MarshalJSON implements json.Marshaler
+func (p GroupPreferences) MarshalJSON() ([]byte, error) {
+	type groupPreferences GroupPreferences
+	return marshalExtra(groupPreferences(p), p.Extra)
+}
+
+// Group is a Bitly group
+type Group struct {
 	References       map[string]string `json:"references"`
 	Name             string            `json:"name"`
 	BSDS             []string          `json:"bsds"`
 	IsActive         bool              `json:"is_active"`
-	Created          string            `json:"created"`
-	Modified         string            `json:"modified"`
+	Created          JSONDate          `json:"created"`
+	Modified         JSONDate          `json:"modified"`
 	OrganizationGUID string            `json:"organization_guid"`
 	Role             string            `json:"role"`
 	GUID             string            `json:"guid"`
+
+	// Extra holds fields returned by Bitly which this package does not know
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, unknown fields are kept in Extra
+func (g *Group) UnmarshalJSON(data []byte) error {
+	type group Group
+	extra, err := unmarshalExtra(data, (*group)(g))
+	g.Extra = extra
+	return err
+}
+
+// MarshalJSON implements json.Marshaler
+func (g Group) MarshalJSON() ([]byte, error) {
+	type group Group
+	return marshalExtra(group(g), g.Extra)
+}
+
+// DeepLink is a mobile app deep link attached to a Bitlink
+type DeepLink struct {
+	BitLink     string   `json:"bitlink"`
+	InstallURL  string   `json:"install_url"`
+	Created     JSONDate `json:"created"`
+	AppURIPath  string   `json:"app_uri_path"`
+	Modified    JSONDate `json:"modified"`
+	InstallType string   `json:"install_type"`
+	AppGUID     string   `json:"app_guid"`
+	GUID        string   `json:"guid"`
+	OS          string   `json:"os"`
+
+	// Extra holds fields returned by Bitly which this package does not know
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, unknown fields are kept in Extra
+func (d *DeepLink) UnmarshalJSON(data []byte) error {
+	type deepLink DeepLink
+	extra, err := unmarshalExtra(data, (*deepLink)(d))
+	d.Extra = extra
+	return err
+}
+
+// MarshalJSON implements json.Marshaler
+func (d DeepLink) MarshalJSON() ([]byte, error) {
+	type deepLink DeepLink
+	return marshalExtra(deepLink(d), d.Extra)
 }
 
-type deepLink struct {
-	BitLink     string `json:"bitlink"`
-	InstallURL  string `json:"install_url"`
-	Created     string `json:"created"`
-	AppURIPath  string `json:"app_uri_path"`
-	Modified    string `json:"modified"`
-	InstallType string `json:"install_type"`
-	AppGUID     string `json:"app_guid"`
-	GUID        string `json:"guid"`
-	OS          string `json:"os"`
+// Bitlink is a shortened link
+type Bitlink struct {
+	References     map[string]string `json:"references"`
+	ID             string            `json:"id"`
+	Link           string            `json:"link"`
+	CustomBitlinks []string          `json:"custom_bitlinks"`
+	Archived       bool              `json:"archived"`
+	Tags           []string          `json:"tags"`
+	CreatedAt      JSONDate          `json:"created_at"`
+	CreatedBy      string            `json:"created_by"`
+	Title          string            `json:"title"`
+	DeepLinks      []DeepLink        `json:"deeplinks"`
+	LongURL        string            `json:"long_url"`
+	ClientID       string            `json:"client_id"`
+
+	// Extra holds fields returned by Bitly which this package does not know
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, unknown fields are kept in Extra
+func (b *Bitlink) UnmarshalJSON(data []byte) error {
+	type bitlink Bitlink
+	extra, err := unmarshalExtra(data, (*bitlink)(b))
+	b.Extra = extra
+	return err
 }
 
-type linkResponse struct {
-	References map[string]string `json:"references"`
-	Archived   bool              `json:"archived"`
-	Tags       []string          `json:"tags"`
-	CreatedAt  string            `json:"created_at"`
-	CreatedBy  string            `json:"created_by"`
-	Title      string            `json:"title"`
-	DeepLinks  []deepLink        `json:"deep_links"`
-	LongURL    string            `json:"long_url"`
-	ClientID   string            `json:"client_id"`
+// MarshalJSON implements json.Marshaler
+func (b Bitlink) MarshalJSON() ([]byte, error) {
+	type bitlink Bitlink
+	return marshalExtra(bitlink(b), b.Extra)
 }
 
-type getBitlinksByGroupObject struct {
+// BitlinksByGroupPageFunc fetches the page of Bitlinks at url, the first url
+// is the one given to NewBitlinksByGroupPaginator and the others are
+// Pagination links of fetched pages. A page returned with an error is kept.
+type BitlinksByGroupPageFunc func(ctx context.Context, url string) (*BitlinksByGroup, error)
+
+// BitlinksByGroupPaginator walks pages of Bitlinks of a Group, see GetBitlinksByGroupPaginator
+type BitlinksByGroupPaginator struct {
+	ctx      context.Context
 	url      string
-	Resp     *getBitlinksByGroupResponse
+	Resp     *BitlinksByGroup
 	isLoaded bool
-	client   *Client
+	fetch    BitlinksByGroupPageFunc
+}
+
+// NewBitlinksByGroupPaginator returns paginator which loads pages with fetch
+// starting at url, it lets fakes of GroupsService return paginators over
+// stubbed pages
+func NewBitlinksByGroupPaginator(ctx context.Context, url string, fetch BitlinksByGroupPageFunc) *BitlinksByGroupPaginator {
+	return &BitlinksByGroupPaginator{
+		ctx:   ctx,
+		url:   url,
+		Resp:  &BitlinksByGroup{},
+		fetch: fetch,
+	}
 }
 
-func (o *getBitlinksByGroupObject) Next() bool {
+func (o *BitlinksByGroupPaginator) Next() bool {
+	if o.Resp == nil {
+		return false
+	}
 	if nextURL := o.Resp.Pagination.Next; nextURL != "" {
 		o.url = nextURL
 		o.isLoaded = false
@@ -74,7 +174,10 @@ func (o *getBitlinksByGroupObject) Next() bool {
 	return false
 }
 
-func (o *getBitlinksByGroupObject) Prev() bool {
+func (o *BitlinksByGroupPaginator) Prev() bool {
+	if o.Resp == nil {
+		return false
+	}
 	if prevURL := o.Resp.Pagination.Prev; prevURL != "" {
 		o.url = prevURL
 		o.isLoaded = false
@@ -83,17 +186,29 @@ func (o *getBitlinksByGroupObject) Prev() bool {
 	return false
 }
 
-func (o *getBitlinksByGroupObject) Get() error {
+// Get loads the current page into Resp. Pagination links returned by Bitly are
+// absolute, they are resolved against Client.BaseURL by NewRequest.
+func (o *BitlinksByGroupPaginator) Get() error {
 	if o.isLoaded {
 		return nil
 	}
-	_, err := o.client.get(o.url, o.Resp)
+	if o.fetch == nil {
+		return errPaginatorNotInitialized
+	}
+	resp, err := o.fetch(o.ctx, o.url)
+	if resp == nil {
+		return err
+	}
+	// The page is kept with *UnknownFieldsError of strict decoding
+	o.Resp = resp
+	o.isLoaded = true
 	return err
 }
 
-type getBitlinksByGroupResponse struct {
+// BitlinksByGroup is a page of Bitlinks returned by GetBitlinksByGroup
+type BitlinksByGroup struct {
 	Pagination Paginate
-	Links      []linkResponse
+	Links      []Bitlink
 }
 
 // GetBitlinksByGroupQueryParams used by sending query parameters to
@@ -105,12 +220,12 @@ type GetBitlinksByGroupQueryParams struct {
 	CreatedBefore   int         `url:"created_before,omitempty"`
 	CreatedAfter    int         `url:"created_after,omitempty"`
 	ModifiedAfter   string      `url:"modified_after,omitempty"`
-	Archived        queryOption `url:"archived,omitempty"`
-	DeepLinks       queryOption `url:"deeplinks,omitempty"`
-	DomainDeepLinks queryOption `url:"domain_deeplinks,omitempty"`
+	Archived        QueryOption `url:"archived,omitempty"`
+	DeepLinks       QueryOption `url:"deeplinks,omitempty"`
+	DomainDeepLinks QueryOption `url:"domain_deeplinks,omitempty"`
 	CampaignGUID    string      `url:"campaign_guid,omitempty"`
 	ChannelGUID     string      `url:"channel_guid,omitempty"`
-	CustomBitlink   queryOption `url:"custom_bitlink,omitempty"`
+	CustomBitlink   QueryOption `url:"custom_bitlink,omitempty"`
 	Tags            []string    `url:"tags,omitempty"`
 	EncodingLogin   []string    `url:"encoding_login,omitempty"`
 }
@@ -123,83 +238,131 @@ func groupPath(GroupGUID string) string {
 	return strings.TrimRight(fmt.Sprintf("/groups/%s", GroupGUID), "/")
 }
 
-type groupsResponse struct {
-	Groups []groupResponse `json:"groups"`
+// GroupList is returned by ListGroups
+type GroupList struct {
+	Groups []Group `json:"groups"`
 }
 
-func (gc *GroupsClient) ListGroups(OrganizationGUID string) (*groupsResponse, error) {
+// ListGroups returns Groups of the user, optionally limited to an Organization
+//
+// see - http://dev.bitly.com/v4/#operation/getGroups
+func (gc *GroupsClient) ListGroups(ctx context.Context, OrganizationGUID string) (*GroupList, *Response, error) {
+	ctx = withOperation(ctx, "Groups.ListGroups", "/v4/groups")
 	q, err := query.Values(&listGroupsParams{OrganizationGUID})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	path, err := buildURL(versioned(groupPath("")), q)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	groupsResp := &groupsResponse{}
+	groupsResp := &GroupList{}
 
-	_, err = gc.client.get(path, groupsResp)
-	if err != nil {
-		return nil, err
+	resp, err := gc.client.get(ctx, path, groupsResp)
+	if !decoded(err) {
+		return nil, resp, err
 	}
-
-	return groupsResp, nil
+	return groupsResp, resp, err
 }
 
 // GetGroup returns Group info
 //
 // see - http://dev.bitly.com/v4/#operation/getGroup
-func (gc *GroupsClient) GetGroup(GroupGUID string) (*groupResponse, error) {
+func (gc *GroupsClient) GetGroup(ctx context.Context, GroupGUID string) (*Group, *Response, error) {
+	ctx = withCacheable(withOperation(ctx, "Groups.GetGroup", "/v4/groups/{group_guid}"))
 	path := versioned(groupPath(GroupGUID))
-	groupResp := &groupResponse{}
+	groupResp := &Group{}
 
-	_, err := gc.client.get(path, groupResp)
-	if err != nil {
-		return nil, err
+	resp, err := gc.client.get(ctx, path, groupResp)
+	if !decoded(err) {
+		return nil, resp, err
 	}
-
-	return groupResp, nil
+	return groupResp, resp, err
 }
 
 // GetGroupPreferences returns Group preferences
 //
 // see - http://dev.bitly.com/v4/#operation/getGroupPreferences
-func (gc *GroupsClient) GetGroupPreferences(GroupGUID string) (*groupPrefResponse, error) {
+func (gc *GroupsClient) GetGroupPreferences(ctx context.Context, GroupGUID string) (*GroupPreferences, *Response, error) {
+	ctx = withCacheable(withOperation(ctx, "Groups.GetGroupPreferences", "/v4/groups/{group_guid}/preferences"))
 	path := versioned(groupPath(GroupGUID) + "/preferences")
-	groupPrefResp := &groupPrefResponse{}
+	groupPrefResp := &GroupPreferences{}
 
-	_, err := gc.client.get(path, groupPrefResp)
-	if err != nil {
-		return nil, err
+	resp, err := gc.client.get(ctx, path, groupPrefResp)
+	if !decoded(err) {
+		return nil, resp, err
 	}
-
-	return groupPrefResp, nil
+	return groupPrefResp, resp, err
 }
 
 // GetBitlinksByGroup retrieves a paginated collection of Bitlinks for a Group
 //
 // see - http://dev.bitly.com/v4/#operation/getBitlinksByGroup
-func (gc *GroupsClient) GetBitlinksByGroup(GroupGUID string, queryParams *GetBitlinksByGroupQueryParams) (*getBitlinksByGroupResponse, error) {
-	getBitlinksByGroupResp := &getBitlinksByGroupResponse{}
-	var path string
-	if queryParams != nil {
-		q, err := query.Values(queryParams)
-		if err != nil {
+func (gc *GroupsClient) GetBitlinksByGroup(ctx context.Context, GroupGUID string, queryParams *GetBitlinksByGroupQueryParams) (*BitlinksByGroup, *Response, error) {
+	ctx = withOperation(ctx, "Groups.GetBitlinksByGroup", getBitlinksByGroupTemplate)
+	path, err := getBitlinksByGroupPath(GroupGUID, queryParams)
+	if err != nil {
+		return nil, nil, err
+	}
+	getBitlinksByGroupResp := &BitlinksByGroup{}
+	resp, err := gc.client.get(ctx, path, getBitlinksByGroupResp)
+	if !decoded(err) {
+		return nil, resp, err
+	}
+	return getBitlinksByGroupResp, resp, err
+}
+
+// GetBitlinksByGroupPaginator returns Paginator over Bitlinks of a Group,
+// call Get to load the first page and Next to move to the following one.
+// ctx is used for every page request.
+func (gc *GroupsClient) GetBitlinksByGroupPaginator(ctx context.Context, GroupGUID string, queryParams *GetBitlinksByGroupQueryParams) (*BitlinksByGroupPaginator, error) {
+	path, err := getBitlinksByGroupPath(GroupGUID, queryParams)
+	if err != nil {
+		return nil, err
+	}
+	ctx = withOperation(ctx, "Groups.GetBitlinksByGroup", getBitlinksByGroupTemplate)
+	return NewBitlinksByGroupPaginator(ctx, path, func(ctx context.Context, url string) (*BitlinksByGroup, error) {
+		resp := &BitlinksByGroup{}
+		_, err := gc.client.get(ctx, url, resp)
+		if !decoded(err) {
 			return nil, err
 		}
-		path, err = buildURL(versioned(groupPath(GroupGUID)+"/bitlinks"), q)
-		if err != nil {
+		return resp, err
+	}), nil
+}
+
+// AllBitlinksByGroup returns every Bitlink of a Group, archived or not, by
+// walking all pages of GetBitlinksByGroupPaginator
+func AllBitlinksByGroup(ctx context.Context, groups GroupsService, GroupGUID string) ([]Bitlink, error) {
+	params := &GetBitlinksByGroupQueryParams{Size: 100, Archived: BothOption}
+	paginator, err := groups.GetBitlinksByGroupPaginator(ctx, GroupGUID, params)
+	if err != nil {
+		return nil, err
+	}
+	var links []Bitlink
+	for {
+		if err := paginator.Get(); err != nil {
 			return nil, err
 		}
-	} else {
-		path = versioned(groupPath(GroupGUID) + "/bitlinks")
+		links = append(links, paginator.Resp.Links...)
+		if !paginator.Next() {
+			return links, nil
+		}
+	}
+}
+
+const getBitlinksByGroupTemplate = "/v4/groups/{group_guid}/bitlinks"
+
+func getBitlinksByGroupPath(GroupGUID string, queryParams *GetBitlinksByGroupQueryParams) (string, error) {
+	path := versioned(groupPath(GroupGUID) + "/bitlinks")
+	if queryParams == nil {
+		return path, nil
 	}
-	_, err := gc.client.get(path, getBitlinksByGroupResp)
+	q, err := query.Values(queryParams)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-
-	return getBitlinksByGroupResp, nil
+	return buildURL(path, q)
 }
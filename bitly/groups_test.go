@@ -1,11 +1,14 @@
 package bitly
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestGroupsClient_ListGroups(t *testing.T) {
@@ -15,7 +18,7 @@ func TestGroupsClient_ListGroups(t *testing.T) {
 		responseBody     string
 		organizationGUID string
 		wantErr          string
-		wantResult       *groupsResponse
+		wantResult       *GroupList
 	}{
 		{
 			desc:             "ok response",
@@ -23,11 +26,11 @@ func TestGroupsClient_ListGroups(t *testing.T) {
 			responseBody:     `{"groups":[{"created":"2012-12-18T18:14:53+0000","modified":"2016-11-11T21:04:26+0000","bsds":[],"guid":"BcciiJcGgDF","organization_guid":"OssccSr9D4j","name":"test","is_active":true,"role":"org-admin","references":{"organization":"https://api-ssl.bitly.com/v4/organizations/OssccSr9D4j"}}]}`,
 			organizationGUID: "",
 			wantErr:          "",
-			wantResult: &groupsResponse{
-				[]groupResponse{
+			wantResult: &GroupList{
+				[]Group{
 					{
-						Created:          "2012-12-18T18:14:53+0000",
-						Modified:         "2016-11-11T21:04:26+0000",
+						Created:          JSONDate(time.Date(2012, 12, 18, 18, 14, 53, 0, time.UTC)),
+						Modified:         JSONDate(time.Date(2016, 11, 11, 21, 4, 26, 0, time.UTC)),
 						BSDS:             []string{},
 						GUID:             "BcciiJcGgDF",
 						OrganizationGUID: "OssccSr9D4j",
@@ -47,8 +50,8 @@ func TestGroupsClient_ListGroups(t *testing.T) {
 			responseBody:     `{"groups":[]}`,
 			organizationGUID: "test",
 			wantErr:          "",
-			wantResult: &groupsResponse{
-				[]groupResponse{},
+			wantResult: &GroupList{
+				[]Group{},
 			},
 		},
 		{
@@ -90,7 +93,7 @@ func TestGroupsClient_ListGroups(t *testing.T) {
 			c := NewClient(http.DefaultClient)
 			c.BaseURL = s.URL
 
-			got, err := c.Groups.ListGroups(tc.organizationGUID)
+			got, _, err := c.Groups.ListGroups(context.Background(), tc.organizationGUID)
 			if tc.wantErr == "" && err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
@@ -113,7 +116,7 @@ func TestGroupsClient_GetBitlinksByGroup(t *testing.T) {
 		queryParams  *GetBitlinksByGroupQueryParams
 		wantURL      string
 		wantErr      string
-		wantResult   *getBitlinksByGroupResponse
+		wantResult   *BitlinksByGroup
 	}{
 		{
 			desc:         "ok response",
@@ -123,7 +126,7 @@ func TestGroupsClient_GetBitlinksByGroup(t *testing.T) {
 			queryParams:  nil,
 			wantURL:      "/v4/groups/BcciiJcGgDF/bitlinks",
 			wantErr:      "",
-			wantResult: &getBitlinksByGroupResponse{
+			wantResult: &BitlinksByGroup{
 				Pagination: Paginate{
 					Total: 2,
 					Size:  50,
@@ -131,26 +134,34 @@ func TestGroupsClient_GetBitlinksByGroup(t *testing.T) {
 					Page:  1,
 					Next:  "",
 				},
-				Links: []linkResponse{
+				Links: []Bitlink{
 					{
-						References: map[string]string{"group": "https://api-ssl.bitly.com/v4/groups/BcciiJsSgCZ"},
-						Archived:   false,
-						Tags:       []string{},
-						CreatedAt:  "2012-12-18T20:16:46+0000",
-						CreatedBy:  "test",
-						Title:      "Example.com Main Page",
-						LongURL:    "http://example.com/",
-						ClientID:   "36b72d37f23e9e247e0aa40083841c92163c5c2f",
+						References:     map[string]string{"group": "https://api-ssl.bitly.com/v4/groups/BcciiJsSgCZ"},
+						ID:             "bit.ly/F3zBa5",
+						Link:           "http://bit.ly/F3zBa5",
+						CustomBitlinks: []string{},
+						Archived:       false,
+						Tags:           []string{},
+						CreatedAt:      JSONDate(time.Date(2012, 12, 18, 20, 16, 46, 0, time.UTC)),
+						CreatedBy:      "test",
+						Title:          "Example.com Main Page",
+						DeepLinks:      []DeepLink{},
+						LongURL:        "http://example.com/",
+						ClientID:       "36b72d37f23e9e247e0aa40083841c92163c5c2f",
 					},
 					{
-						References: map[string]string{"group": "https://api-ssl.bitly.com/v4/groups/BcciiJsSgCZ"},
-						Archived:   false,
-						Tags:       []string{},
-						CreatedAt:  "2012-12-18T18:15:00+0000",
-						CreatedBy:  "test",
-						Title:      "All about Pufferfish",
-						LongURL:    "http://animals.nationalgeographic.com/animals/fish/pufferfish/",
-						ClientID:   "36b72d37f23e9e247e0aa40083841c92163c5c2f",
+						References:     map[string]string{"group": "https://api-ssl.bitly.com/v4/groups/BcciiJsSgCZ"},
+						ID:             "on.natgeo.com/WmsHnP",
+						Link:           "http://on.natgeo.com/WmsHnP",
+						CustomBitlinks: []string{},
+						Archived:       false,
+						Tags:           []string{},
+						CreatedAt:      JSONDate(time.Date(2012, 12, 18, 18, 15, 0, 0, time.UTC)),
+						CreatedBy:      "test",
+						Title:          "All about Pufferfish",
+						DeepLinks:      []DeepLink{},
+						LongURL:        "http://animals.nationalgeographic.com/animals/fish/pufferfish/",
+						ClientID:       "36b72d37f23e9e247e0aa40083841c92163c5c2f",
 					},
 				},
 			},
@@ -173,7 +184,7 @@ func TestGroupsClient_GetBitlinksByGroup(t *testing.T) {
 			c := NewClient(http.DefaultClient)
 			c.BaseURL = s.URL
 
-			got, err := c.Groups.GetBitlinksByGroup(tc.groupGUID, tc.queryParams)
+			got, _, err := c.Groups.GetBitlinksByGroup(context.Background(), tc.groupGUID, tc.queryParams)
 			if tc.wantErr == "" && err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
@@ -186,3 +197,128 @@ func TestGroupsClient_GetBitlinksByGroup(t *testing.T) {
 		})
 	}
 }
+
+func TestGroupsClient_GetBitlinksByGroupPaginator(t *testing.T) {
+	pages := map[string]string{
+		"1": `{"links":[{"id":"bit.ly/a"},{"id":"bit.ly/b"}],"pagination":{"prev":"","next":"https://api-ssl.bitly.com/v4/groups/Ba1/bitlinks?page=2&size=2","size":2,"page":1,"total":5}}`,
+		"2": `{"links":[{"id":"bit.ly/c"},{"id":"bit.ly/d"}],"pagination":{"prev":"https://api-ssl.bitly.com/v4/groups/Ba1/bitlinks?page=1&size=2","next":"https://api-ssl.bitly.com/v4/groups/Ba1/bitlinks?page=3&size=2","size":2,"page":2,"total":5}}`,
+		"3": `{"links":[{"id":"bit.ly/e"}],"pagination":{"prev":"https://api-ssl.bitly.com/v4/groups/Ba1/bitlinks?page=2&size=2","next":"","size":2,"page":3,"total":5}}`,
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/proxy/v4/groups/Ba1/bitlinks" {
+			t.Fatalf("invalid request path: %q", r.URL.Path)
+		}
+		if size := r.URL.Query().Get("size"); size != "2" {
+			t.Fatalf("invalid size: %q", size)
+		}
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			page = "1"
+		}
+		w.Write([]byte(pages[page]))
+	}))
+	defer s.Close()
+
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL + "/proxy"
+
+	p, err := c.Groups.GetBitlinksByGroupPaginator(context.Background(), "Ba1", &GetBitlinksByGroupQueryParams{Size: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for {
+		if err := p.Get(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, link := range p.Resp.Links {
+			got = append(got, link.ID)
+		}
+		if !p.Next() {
+			break
+		}
+	}
+	want := []string{"bit.ly/a", "bit.ly/b", "bit.ly/c", "bit.ly/d", "bit.ly/e"}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want links %v got %v", want, got)
+	}
+
+	if !p.Prev() {
+		t.Fatalf("want previous page")
+	}
+	if err := p.Get(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Resp.Pagination.Page != 2 {
+		t.Fatalf("want page 2 got %v", p.Resp.Pagination.Page)
+	}
+}
+
+func TestBitlink_DeepLinks(t *testing.T) {
+	// Shaped like the documented response of GET /v4/bitlinks/{bitlink},
+	// Bitly sends deep links under the deeplinks key
+	data := `{"references":{"group":"https://api-ssl.bitly.com/v4/groups/Ba1bc23dE4F"},"link":"https://bit.ly/2Ld3Bx9","id":"bit.ly/2Ld3Bx9",` +
+		`"long_url":"https://example.com/","title":"Example","archived":false,"created_at":"2019-01-01T00:00:00+0000","created_by":"bitlyapiuser",` +
+		`"client_id":"a5e8cebb233c5d07e5c553e917dffb92fec5264d","custom_bitlinks":["bit.ly/example"],"tags":["spring"],` +
+		`"deeplinks":[{"guid":"Ba1bc23dE4F","bitlink":"bit.ly/2Ld3Bx9","app_uri_path":"/store?id=123456",` +
+		`"install_url":"https://play.google.com/store/apps/details?id=com.bitly.app","app_guid":"Ba1bc23dE4F","os":"android",` +
+		`"install_type":"promote_install","created":"2019-01-01T00:00:00+0000","modified":"2019-01-02T00:00:00+0000"}]}`
+	link := &Bitlink{}
+	if err := json.Unmarshal([]byte(data), link); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []DeepLink{{
+		BitLink:     "bit.ly/2Ld3Bx9",
+		InstallURL:  "https://play.google.com/store/apps/details?id=com.bitly.app",
+		Created:     JSONDate(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)),
+		AppURIPath:  "/store?id=123456",
+		Modified:    JSONDate(time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)),
+		InstallType: "promote_install",
+		AppGUID:     "Ba1bc23dE4F",
+		GUID:        "Ba1bc23dE4F",
+		OS:          "android",
+	}}
+	if !reflect.DeepEqual(want, link.DeepLinks) || len(link.Extra) != 0 {
+		t.Fatalf("want deep links %#v got %#v, extra %s", want, link.DeepLinks, link.Extra)
+	}
+
+	encoded, err := json.Marshal(link)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(encoded), `"deeplinks":[{`) {
+		t.Fatalf("want deep links encoded under deeplinks got %s", encoded)
+	}
+}
+
+func TestAllBitlinksByGroup(t *testing.T) {
+	pages := map[string]string{
+		"1": `{"links":[{"id":"bit.ly/a"},{"id":"bit.ly/b","archived":true}],"pagination":{"next":"https://api-ssl.bitly.com/v4/groups/Ba1/bitlinks?page=2&size=100&archived=both","page":1,"total":3}}`,
+		"2": `{"links":[{"id":"bit.ly/c"}],"pagination":{"prev":"https://api-ssl.bitly.com/v4/groups/Ba1/bitlinks?page=1&size=100&archived=both","page":2,"total":3}}`,
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if archived := r.URL.Query().Get("archived"); archived != "both" {
+			t.Fatalf("invalid archived: %q", archived)
+		}
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			page = "1"
+		}
+		w.Write([]byte(pages[page]))
+	}))
+	defer s.Close()
+
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL
+	links, err := AllBitlinksByGroup(context.Background(), c.Groups, "Ba1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, link := range links {
+		got = append(got, link.ID)
+	}
+	if want := []string{"bit.ly/a", "bit.ly/b", "bit.ly/c"}; !reflect.DeepEqual(want, got) {
+		t.Fatalf("want links %v got %v", want, got)
+	}
+}
@@ -0,0 +1,167 @@
+package bitly
+
+import (
+	"github.com/pkg/errors"
+	"strings"
+	"time"
+)
+
+// maxPageSize is the largest page Bitly returns
+const maxPageSize = 100
+
+// LinkFilterBuilder builds GetBitlinksByGroupQueryParams from time.Time values
+// and validated options, see LinkFilter
+type LinkFilterBuilder struct {
+	params GetBitlinksByGroupQueryParams
+	err    error
+}
+
+// LinkFilter starts a filter of Bitlinks of a group:
+//
+//	params, err := bitly.LinkFilter().
+//		CreatedBetween(start, end).
+//		WithTags("spring").
+//		OnlyArchived().
+//		PageSize(50).
+//		Build()
+//	links, _, err := client.Groups.GetBitlinksByGroup(ctx, groupGUID, params)
+//
+// Invalid values are reported by Build, so no request is sent with them.
+func LinkFilter() *LinkFilterBuilder {
+	return &LinkFilterBuilder{}
+}
+
+// fail records the first invalid value
+func (f *LinkFilterBuilder) fail(method, format string, args ...interface{}) *LinkFilterBuilder {
+	if f.err == nil {
+		f.err = errors.Errorf("bitly: LinkFilter."+method+": "+format, args...)
+	}
+	return f
+}
+
+// CreatedBetween keeps Bitlinks created after start and before end
+func (f *LinkFilterBuilder) CreatedBetween(start, end time.Time) *LinkFilterBuilder {
+	if !start.Before(end) {
+		return f.fail("CreatedBetween", "start %s is not before end %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	return f.CreatedAfter(start).CreatedBefore(end)
+}
+
+// CreatedAfter keeps Bitlinks created after t
+func (f *LinkFilterBuilder) CreatedAfter(t time.Time) *LinkFilterBuilder {
+	if reason := checkFilterTime(t); reason != "" {
+		return f.fail("CreatedAfter", "%s", reason)
+	}
+	f.params.CreatedAfter = int(t.Unix())
+	return f
+}
+
+// CreatedBefore keeps Bitlinks created before t
+func (f *LinkFilterBuilder) CreatedBefore(t time.Time) *LinkFilterBuilder {
+	if reason := checkFilterTime(t); reason != "" {
+		return f.fail("CreatedBefore", "%s", reason)
+	}
+	f.params.CreatedBefore = int(t.Unix())
+	return f
+}
+
+// ModifiedSince keeps Bitlinks modified after t
+func (f *LinkFilterBuilder) ModifiedSince(t time.Time) *LinkFilterBuilder {
+	if reason := checkFilterTime(t); reason != "" {
+		return f.fail("ModifiedSince", "%s", reason)
+	}
+	f.params.ModifiedAfter = JSONDate(t.UTC()).String()
+	return f
+}
+
+// checkFilterTime returns why t cannot be sent, Bitly takes Unix timestamps
+func checkFilterTime(t time.Time) string {
+	if t.IsZero() {
+		return "time is zero"
+	}
+	if t.Unix() <= 0 {
+		return "time " + t.Format(time.RFC3339) + " is not after the Unix epoch"
+	}
+	return ""
+}
+
+// WithTags keeps Bitlinks which have every tag
+func (f *LinkFilterBuilder) WithTags(tags ...string) *LinkFilterBuilder {
+	for _, tag := range tags {
+		if strings.TrimSpace(tag) == "" {
+			return f.fail("WithTags", "tag is empty")
+		}
+	}
+	f.params.Tags = append(f.params.Tags, tags...)
+	return f
+}
+
+// Keyword keeps Bitlinks with the custom keyword
+func (f *LinkFilterBuilder) Keyword(keyword string) *LinkFilterBuilder {
+	f.params.Keyword = keyword
+	return f
+}
+
+// Query keeps Bitlinks matching query, like a part of the long URL or title
+func (f *LinkFilterBuilder) Query(query string) *LinkFilterBuilder {
+	f.params.Query = query
+	return f
+}
+
+// OnlyArchived keeps archived Bitlinks, Bitly lists active ones by default
+func (f *LinkFilterBuilder) OnlyArchived() *LinkFilterBuilder {
+	f.params.Archived = OnOption
+	return f
+}
+
+// IncludeArchived keeps both archived and active Bitlinks
+func (f *LinkFilterBuilder) IncludeArchived() *LinkFilterBuilder {
+	f.params.Archived = BothOption
+	return f
+}
+
+// CustomOnly keeps Bitlinks with custom back-halves
+func (f *LinkFilterBuilder) CustomOnly() *LinkFilterBuilder {
+	f.params.CustomBitlink = OnOption
+	return f
+}
+
+// WithDeepLinks keeps Bitlinks with deep links
+func (f *LinkFilterBuilder) WithDeepLinks() *LinkFilterBuilder {
+	f.params.DeepLinks = OnOption
+	return f
+}
+
+// PageSize sets the number of Bitlinks per page, from 1 to 100
+func (f *LinkFilterBuilder) PageSize(n int) *LinkFilterBuilder {
+	if n < 1 || n > maxPageSize {
+		return f.fail("PageSize", "size %d is not between 1 and %d", n, maxPageSize)
+	}
+	f.params.Size = n
+	return f
+}
+
+// Page sets the page to get, pages start with 1
+func (f *LinkFilterBuilder) Page(n int) *LinkFilterBuilder {
+	if n < 1 {
+		return f.fail("Page", "page %d is not positive", n)
+	}
+	f.params.Page = n
+	return f
+}
+
+// Build returns the query parameters or the first invalid value.
+// Ranges set by separate CreatedAfter and CreatedBefore calls are checked too.
+func (f *LinkFilterBuilder) Build() (*GetBitlinksByGroupQueryParams, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.params.CreatedAfter != 0 && f.params.CreatedBefore != 0 && f.params.CreatedAfter >= f.params.CreatedBefore {
+		return nil, errors.Errorf("bitly: LinkFilter: created after %s is not before created before %s",
+			time.Unix(int64(f.params.CreatedAfter), 0).UTC().Format(time.RFC3339),
+			time.Unix(int64(f.params.CreatedBefore), 0).UTC().Format(time.RFC3339))
+	}
+	params := f.params
+	params.Tags = append([]string(nil), f.params.Tags...)
+	return &params, nil
+}
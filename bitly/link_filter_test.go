@@ -0,0 +1,96 @@
+package bitly
+
+import (
+	"github.com/google/go-querystring/query"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLinkFilter(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)
+	modified := time.Date(2020, 1, 15, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+
+	params, err := LinkFilter().
+		CreatedBetween(start, end).
+		ModifiedSince(modified).
+		WithTags("spring", "sale").
+		Keyword("promo").
+		OnlyArchived().
+		CustomOnly().
+		PageSize(50).
+		Page(2).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	values, err := query.Values(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "archived=on&created_after=1577836800&created_before=1580515200&custom_bitlink=on&keyword=promo" +
+		"&modified_after=2020-01-15T11%3A00%3A00%2B0000&page=2&size=50&tags=spring&tags=sale"
+	if got := values.Encode(); got != want {
+		t.Fatalf("want query %s got %s", want, got)
+	}
+
+	params, err = LinkFilter().IncludeArchived().Build()
+	if err != nil || params.Archived != BothOption {
+		t.Fatalf("want archived both got %v %v", params, err)
+	}
+}
+
+func TestLinkFilter_Errors(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	testCases := []struct {
+		desc    string
+		filter  *LinkFilterBuilder
+		wantErr string
+	}{
+		{
+			desc:    "reversed range",
+			filter:  LinkFilter().CreatedBetween(end, start),
+			wantErr: "LinkFilter.CreatedBetween: start 2020-01-01T01:00:00Z is not before end 2020-01-01T00:00:00Z",
+		},
+		{
+			desc:    "reversed separate bounds",
+			filter:  LinkFilter().CreatedAfter(end).CreatedBefore(start),
+			wantErr: "created after 2020-01-01T01:00:00Z is not before created before 2020-01-01T00:00:00Z",
+		},
+		{
+			desc:    "zero time",
+			filter:  LinkFilter().ModifiedSince(time.Time{}),
+			wantErr: "LinkFilter.ModifiedSince: time is zero",
+		},
+		{
+			desc:    "time before epoch",
+			filter:  LinkFilter().CreatedAfter(time.Date(1969, 1, 1, 0, 0, 0, 0, time.UTC)),
+			wantErr: "LinkFilter.CreatedAfter: time 1969-01-01T00:00:00Z is not after the Unix epoch",
+		},
+		{
+			desc:    "empty tag",
+			filter:  LinkFilter().WithTags("a", " "),
+			wantErr: "LinkFilter.WithTags: tag is empty",
+		},
+		{
+			desc:    "page size too large",
+			filter:  LinkFilter().PageSize(101),
+			wantErr: "LinkFilter.PageSize: size 101 is not between 1 and 100",
+		},
+		{
+			desc:    "first error is kept",
+			filter:  LinkFilter().Page(0).PageSize(0),
+			wantErr: "LinkFilter.Page: page 0 is not positive",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			params, err := tc.filter.Build()
+			if err == nil || !strings.HasSuffix(err.Error(), tc.wantErr) || params != nil {
+				t.Fatalf("want error %v got %v %v", tc.wantErr, params, err)
+			}
+		})
+	}
+}
@@ -3,19 +3,30 @@ package bitly
 import (
 	"context"
 	"fmt"
+	"github.com/lcd1232/go-bitly/bitly/cassette"
 	"golang.org/x/oauth2"
+	"log"
+	"net/http"
 	"os"
 	"strings"
 	"testing"
 )
 
+const defaultCassettePath = "testdata/live.json"
+
 var (
 	bitlyLiveTest bool
 	bitlyToken    string
 	bitlyBaseURL  string
 	bitlyClient   *Client
+	bitlyCassette *cassette.Transport
 )
 
+// Live tests are driven by environment variables:
+//
+//	BITLY_CASSETTE=record with BITLY_TOKEN runs them against Bitly and saves the session
+//	BITLY_CASSETTE=replay runs them offline from the saved session
+//	BITLY_CASSETTE_PATH overrides the session file, testdata/live.json by default
 func init() {
 	bitlyToken = os.Getenv("BITLY_TOKEN")
 	bitlyBaseURL = os.Getenv("BITLY_BASE_URL")
@@ -23,30 +34,68 @@ func init() {
 	if bitlyBaseURL == "" {
 		bitlyBaseURL = defaultBaseURL
 	}
+	cassettePath := os.Getenv("BITLY_CASSETTE_PATH")
+	if cassettePath == "" {
+		cassettePath = defaultCassettePath
+	}
 
-	if len(bitlyToken) > 0 {
+	var httpClient *http.Client
+	switch strings.ToLower(os.Getenv("BITLY_CASSETTE")) {
+	case "record":
+		if len(bitlyToken) == 0 {
+			log.Fatal("BITLY_TOKEN is required to record a cassette")
+		}
+		bitlyCassette, _ = cassette.New(cassettePath, cassette.ModeRecord)
+		bitlyCassette.Secrets = []string{bitlyToken}
 		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: bitlyToken})
-		tc := oauth2.NewClient(context.Background(), ts)
-		bitlyClient = NewClient(tc)
+		httpClient = &http.Client{Transport: &oauth2.Transport{Source: ts, Base: bitlyCassette}}
+	case "replay":
+		var err error
+		bitlyCassette, err = cassette.New(cassettePath, cassette.ModeReplay)
+		if err != nil {
+			log.Fatalf("cannot load cassette: %v", err)
+		}
+		httpClient = bitlyCassette.Client()
+	default:
+		if len(bitlyToken) > 0 {
+			ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: bitlyToken})
+			httpClient = oauth2.NewClient(context.Background(), ts)
+		}
+	}
+
+	if httpClient != nil {
+		bitlyLiveTest = bitlyCassette != nil
+		bitlyClient = NewClient(httpClient)
 		bitlyClient.BaseURL = bitlyBaseURL
 		bitlyClient.UserAgent = fmt.Sprintf("%v +livetest", bitlyClient.UserAgent)
 		bitlyClient.Debug = bitlyDebug
 	}
 }
 
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if bitlyCassette != nil {
+		if err := bitlyCassette.Save(); err != nil {
+			log.Printf("cannot save cassette: %v", err)
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
+
 func TestLive_ListGroups(t *testing.T) {
 	if !bitlyLiveTest {
 		t.Skip("skipping live test")
 	}
 
-	groupsResp, err := bitlyClient.Groups.ListGroups("")
+	groupsResp, _, err := bitlyClient.Groups.ListGroups(context.Background(), "")
 	if err != nil {
 		t.Fatalf("Live Groups.ListGroups() returned error: %v", err)
 	}
 	for _, group := range groupsResp.Groups {
 		t.Logf("GUID: %v\n", group.GUID)
 		t.Logf("Organization GUID: %v\n", group.OrganizationGUID)
-		groupResp, err := bitlyClient.Groups.GetGroup(group.GUID)
+		groupResp, _, err := bitlyClient.Groups.GetGroup(context.Background(), group.GUID)
 		if err != nil {
 			t.Fatalf("Live Groups.GetGroup(%v) returned error: %v", group.GUID, err)
 		}
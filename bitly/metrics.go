@@ -0,0 +1,65 @@
+package bitly
+
+import (
+	"net/http"
+	"time"
+)
+
+// RequestStats describes a finished service call
+type RequestStats struct {
+	// Operation is the service call, requests created with NewRequest
+	// directly are named Do and keep their URL path
+	Operation Operation
+	Method    string
+	// StatusCode is zero when Bitly was not reached
+	StatusCode int
+	// Duration covers all attempts when Instrument is used before Retry
+	Duration time.Duration
+	// Retries is the number of retries made by Retry middleware
+	Retries int
+	// Err is the error of the call and ErrorCode is its Bitly error code,
+	// ErrorCode is empty for errors which are not *ErrorResponse
+	Err       error
+	ErrorCode string
+	// RateLimit is reported by the last response, HasRateLimit is false
+	// when Bitly did not send rate limit headers
+	RateLimit    RateLimit
+	HasRateLimit bool
+}
+
+// Metrics receives stats of every service call, see package bitlyprom for
+// Prometheus adapter. ObserveRequest is called concurrently.
+type Metrics interface {
+	ObserveRequest(stats RequestStats)
+}
+
+// Instrument returns Middleware which reports every service call to metrics.
+// Use it before Retry, so latency covers all attempts and retries are counted.
+func Instrument(metrics Metrics) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*Response, error) {
+			start := time.Now()
+			ctx, retries := withRetries(req.Context())
+
+			resp, err := next.Do(req.WithContext(ctx))
+
+			stats := RequestStats{
+				Operation: requestOperation(req),
+				Method:    req.Method,
+				Duration:  time.Since(start),
+				Retries:   *retries,
+				Err:       err,
+			}
+			if resp != nil {
+				stats.StatusCode = resp.StatusCode
+				stats.RateLimit = resp.RateLimit
+				stats.HasRateLimit = resp.Header.Get(headerRateLimitRemaining) != ""
+			}
+			if errResp, ok := err.(*ErrorResponse); ok {
+				stats.ErrorCode = errResp.ErrorCode()
+			}
+			metrics.ObserveRequest(stats)
+			return resp, err
+		})
+	}
+}
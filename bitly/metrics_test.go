@@ -0,0 +1,70 @@
+package bitly
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testMetrics struct {
+	mu    sync.Mutex
+	stats []RequestStats
+}
+
+func (m *testMetrics) ObserveRequest(stats RequestStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats = append(m.stats, stats)
+}
+
+func TestInstrument(t *testing.T) {
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch {
+		case r.URL.Path == "/v4/groups/Ba1/preferences" && requests == 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case r.URL.Path == "/v4/groups/Ba1/preferences":
+			w.Header().Set("X-RateLimit-Limit", "100")
+			w.Header().Set("X-RateLimit-Remaining", "42")
+			w.Write([]byte(`{"group_guid":"Ba1"}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"FORBIDDEN"}`))
+		}
+	}))
+	defer s.Close()
+
+	metrics := &testMetrics{}
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL
+	c.Use(Instrument(metrics), Retry(RetryOptions{MaxRetries: 1, MinBackoff: time.Millisecond}))
+
+	if _, _, err := c.Groups.GetGroupPreferences(context.Background(), "Ba1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := c.User.Update(context.Background(), &UserUpdateOptions{Name: "test"}); err == nil {
+		t.Fatalf("want error")
+	}
+
+	if len(metrics.stats) != 2 {
+		t.Fatalf("want 2 stats got %#v", metrics.stats)
+	}
+	prefs, update := metrics.stats[0], metrics.stats[1]
+	wantOperation := Operation{Name: "Groups.GetGroupPreferences", Path: "/v4/groups/{group_guid}/preferences"}
+	if prefs.Operation != wantOperation || prefs.Method != "GET" || prefs.StatusCode != 200 || prefs.Retries != 1 || prefs.Err != nil || prefs.ErrorCode != "" {
+		t.Fatalf("unexpected stats %#v", prefs)
+	}
+	if !prefs.HasRateLimit || prefs.RateLimit.Remaining != 42 || prefs.Duration <= 0 {
+		t.Fatalf("unexpected rate limit or duration %#v", prefs)
+	}
+	if update.Operation.Name != "User.Update" || update.Method != "PATCH" || update.StatusCode != 403 || update.Retries != 0 {
+		t.Fatalf("unexpected stats %#v", update)
+	}
+	if update.Err == nil || update.ErrorCode != "FORBIDDEN" || update.HasRateLimit {
+		t.Fatalf("unexpected error stats %#v", update)
+	}
+}
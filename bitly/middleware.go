@@ -0,0 +1,187 @@
+package bitly
+
+import (
+	"github.com/pkg/errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRetryMinBackoff = 500 * time.Millisecond
+	defaultRetryMaxBackoff = 30 * time.Second
+)
+
+// Doer sends a request to Bitly. Responses with non 2xx status are returned
+// together with *ErrorResponse, the body of successful responses is buffered
+// and can be read by middlewares as long as it is restored for the next one.
+type Doer interface {
+	Do(req *http.Request) (*Response, error)
+}
+
+// DoerFunc is an adapter to allow the use of ordinary functions as Doer
+type DoerFunc func(req *http.Request) (*Response, error)
+
+// Do calls f(req)
+func (f DoerFunc) Do(req *http.Request) (*Response, error) {
+	return f(req)
+}
+
+// Middleware wraps a Doer to add behaviour around every request,
+// like authentication, logging, metrics, retries or tracing
+type Middleware func(next Doer) Doer
+
+// Use appends middlewares to the chain of the client. The first middleware
+// is the outermost one: it sees the request first and the response last.
+// Use must not be called concurrently with requests.
+func (c *Client) Use(middlewares ...Middleware) {
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+func (c *Client) chain() Doer {
+	var d Doer = DoerFunc(c.send)
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		d = c.middlewares[i](d)
+	}
+	return d
+}
+
+// cloneRequest returns a copy of req with deep copied headers and a fresh body,
+// middlewares use it because the request they received must not be modified
+func cloneRequest(req *http.Request) (*http.Request, error) {
+	req2 := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req2.Body = body
+	}
+	return req2, nil
+}
+
+// WithHeader returns Middleware which sets header key to value on every request
+func WithHeader(key, value string) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*Response, error) {
+			req2, err := cloneRequest(req)
+			if err != nil {
+				return nil, err
+			}
+			req2.Header.Set(key, value)
+			return next.Do(req2)
+		})
+	}
+}
+
+// Authenticate returns Middleware which adds headers of credentials to every request
+func Authenticate(credentials Credentials) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*Response, error) {
+			req2, err := cloneRequest(req)
+			if err != nil {
+				return nil, err
+			}
+			for k, v := range credentials.Headers() {
+				req2.Header.Set(k, v)
+			}
+			return next.Do(req2)
+		})
+	}
+}
+
+// Logging returns Middleware which logs every request with its status,
+// duration, Bitly request ID and error
+func Logging(logger *log.Logger) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*Response, error) {
+			start := time.Now()
+			resp, err := next.Do(req)
+			status, requestID := 0, ""
+			if resp != nil {
+				status, requestID = resp.StatusCode, resp.RequestID
+			}
+			if err != nil {
+				logger.Printf("%s %s: %d in %v (request id %q): %v", req.Method, req.URL, status, time.Since(start), requestID, err)
+			} else {
+				logger.Printf("%s %s: %d in %v (request id %q)", req.Method, req.URL, status, time.Since(start), requestID)
+			}
+			return resp, err
+		})
+	}
+}
+
+// RetryOptions configures Retry middleware
+type RetryOptions struct {
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the exponential delay between attempts,
+	// they default to 500ms and 30s
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// RetryNonIdempotent retries POST, PATCH, PUT and DELETE requests too.
+	// Bitly may have applied a request which failed with a network error or
+	// 5xx response, so a retried shorten can create a second Bitlink.
+	RetryNonIdempotent bool
+}
+
+// Retry returns Middleware which retries GET and HEAD requests failed with
+// network errors, 429 or 5xx responses, other methods are retried only with
+// RetryNonIdempotent. Retry-After header is honored up to MaxBackoff.
+func Retry(options RetryOptions) Middleware {
+	if options.MinBackoff <= 0 {
+		options.MinBackoff = defaultRetryMinBackoff
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = defaultRetryMaxBackoff
+	}
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*Response, error) {
+			if !options.RetryNonIdempotent && req.Method != "GET" && req.Method != "HEAD" {
+				return next.Do(req)
+			}
+			backoff := options.MinBackoff
+			for attempt := 0; ; attempt++ {
+				req2, err := cloneRequest(req)
+				if err != nil {
+					return nil, err
+				}
+				resp, err := next.Do(req2)
+				if err == nil || attempt >= options.MaxRetries || !isTemporaryError(err) {
+					return resp, err
+				}
+				// An open circuit asks callers to fail fast instead of waiting
+				if _, ok := errors.Cause(err).(*ErrCircuitOpen); ok {
+					return resp, err
+				}
+
+				wait := backoff
+				if after := retryAfter(resp); after > 0 {
+					wait = after
+				}
+				if wait > options.MaxBackoff {
+					wait = options.MaxBackoff
+				}
+				select {
+				case <-req.Context().Done():
+					return resp, req.Context().Err()
+				case <-time.After(wait):
+				}
+				countRetry(req.Context())
+				backoff *= 2
+			}
+		})
+	}
+}
+
+func retryAfter(resp *Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
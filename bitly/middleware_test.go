@@ -0,0 +1,224 @@
+package bitly
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_Use(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Test"); got != "inner" {
+			t.Fatalf("want header X-Test inner got %q", got)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Fatalf("invalid authorization header: %q", got)
+		}
+		w.Write([]byte(`{"login":"test"}`))
+	}))
+	defer s.Close()
+
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Doer) Doer {
+			return DoerFunc(func(req *http.Request) (*Response, error) {
+				order = append(order, name+" request")
+				resp, err := next.Do(req)
+				order = append(order, name+" response")
+				return resp, err
+			})
+		}
+	}
+
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL
+	c.Use(trace("outer"), WithHeader("X-Test", "outer"), Authenticate(NewOauthTokenCredentials("token")))
+	c.Use(WithHeader("X-Test", "inner"), trace("inner"))
+
+	req, err := c.NewRequest(context.Background(), "GET", "/v4/user", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u := &User{}
+	if _, err := c.Do(req, u); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.Login != "test" {
+		t.Fatalf("want login test got %v", u.Login)
+	}
+	if req.Header.Get("X-Test") != "" || req.Header.Get("Authorization") != "" {
+		t.Fatalf("middlewares modified original request: %v", req.Header)
+	}
+	wantOrder := []string{"outer request", "inner request", "inner response", "outer response"}
+	if !reflect.DeepEqual(wantOrder, order) {
+		t.Fatalf("want order %v got %v", wantOrder, order)
+	}
+}
+
+func TestClient_UseDecodedError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message":"INVALID_ARG_LONG_URL","errors":[{"field":"long_url","error_code":"invalid"}]}`))
+	}))
+	defer s.Close()
+
+	var seen *ErrorResponse
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL
+	c.Use(func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*Response, error) {
+			resp, err := next.Do(req)
+			seen, _ = err.(*ErrorResponse)
+			return resp, err
+		})
+	})
+
+	_, _, err := c.Bitlinks.Shorten(context.Background(), &ShortenOptions{LongURL: "example"})
+	if err == nil {
+		t.Fatalf("want error")
+	}
+	if seen == nil || seen.Message != "INVALID_ARG_LONG_URL" || seen.Errors[0].ErrorCode != "invalid" {
+		t.Fatalf("want decoded error got %#v", seen)
+	}
+}
+
+func TestClient_UseReadBody(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"login":"test"}`))
+	}))
+	defer s.Close()
+
+	var body []byte
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL
+	c.Use(func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*Response, error) {
+			resp, err := next.Do(req)
+			if err == nil {
+				body, _ = ioutil.ReadAll(resp.Body)
+				resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+			}
+			return resp, err
+		})
+	})
+
+	u, _, err := c.User.Get(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.Login != "test" || string(body) != `{"login":"test"}` {
+		t.Fatalf("unexpected user %#v and body %s", u, body)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		responses     []int
+		maxRetries    int
+		nonIdempotent bool
+		wantRequests  int
+		wantErr       string
+	}{
+		{
+			desc:          "success after unavailable",
+			responses:     []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusCreated},
+			maxRetries:    3,
+			nonIdempotent: true,
+			wantRequests:  3,
+		},
+		{
+			desc:          "retries exhausted",
+			responses:     []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+			maxRetries:    1,
+			nonIdempotent: true,
+			wantRequests:  2,
+			wantErr:       "503",
+		},
+		{
+			desc:          "client error is not retried",
+			responses:     []int{http.StatusBadRequest, http.StatusCreated},
+			maxRetries:    3,
+			nonIdempotent: true,
+			wantRequests:  1,
+			wantErr:       "400",
+		},
+		{
+			desc:         "post is not retried by default",
+			responses:    []int{http.StatusServiceUnavailable, http.StatusCreated},
+			maxRetries:   3,
+			wantRequests: 1,
+			wantErr:      "503",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			requests := 0
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				if string(body) != `{"long_url":"http://example.com"}`+"\n" {
+					t.Fatalf("invalid request body on attempt %d: %q", requests+1, body)
+				}
+				if requests == 0 {
+					w.Header().Set("Retry-After", "1")
+				}
+				w.WriteHeader(tc.responses[requests])
+				w.Write([]byte(`{"id":"bit.ly/a","message":"error"}`))
+				requests++
+			}))
+			defer s.Close()
+
+			c := NewClient(http.DefaultClient)
+			c.BaseURL = s.URL
+			c.Use(Retry(RetryOptions{
+				MaxRetries:         tc.maxRetries,
+				MinBackoff:         time.Millisecond,
+				MaxBackoff:         5 * time.Millisecond,
+				RetryNonIdempotent: tc.nonIdempotent,
+			}))
+
+			link, _, err := c.Bitlinks.Shorten(context.Background(), &ShortenOptions{LongURL: "http://example.com"})
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("want error %v got %v", tc.wantErr, err)
+			}
+			if err == nil && link.ID != "bit.ly/a" {
+				t.Fatalf("unexpected link %#v", link)
+			}
+			if requests != tc.wantRequests {
+				t.Fatalf("want %d requests got %d", tc.wantRequests, requests)
+			}
+		})
+	}
+}
+
+func TestLogging(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Bitly-Request-Id", "req-1")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"NOT_FOUND"}`))
+	}))
+	defer s.Close()
+
+	buf := new(bytes.Buffer)
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL
+	c.Use(Logging(log.New(buf, "", 0)))
+
+	c.Groups.GetGroup(context.Background(), "Ba1")
+	got := buf.String()
+	for _, want := range []string{"GET " + s.URL + "/v4/groups/Ba1: 404", `request id "req-1"`, "NOT_FOUND"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("want log to contain %q got %q", want, got)
+		}
+	}
+}
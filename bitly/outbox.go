@@ -0,0 +1,338 @@
+package bitly
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	outboxFileExt    = ".json"
+	outboxCorruptExt = ".corrupt"
+
+	defaultOutboxMinBackoff = time.Second
+	defaultOutboxMaxBackoff = 5 * time.Minute
+)
+
+var (
+	errOutboxRunning = errors.New("outbox is already running")
+)
+
+// OutboxOperation is a kind of mutation queued in Outbox
+type OutboxOperation string
+
+const (
+	OutboxShorten OutboxOperation = "shorten"
+	OutboxUpdate  OutboxOperation = "update"
+)
+
+// OutboxHandle is a provisional identifier of a queued operation,
+// the same handle is reported back in OutboxResult
+type OutboxHandle string
+
+// OutboxResult is a final outcome of a queued operation
+type OutboxResult struct {
+	Handle    OutboxHandle
+	Operation OutboxOperation
+	Link      *Bitlink
+	Err       error
+}
+
+type outboxEntry struct {
+	Handle    OutboxHandle          `json:"handle"`
+	Operation OutboxOperation       `json:"operation"`
+	Bitlink   string                `json:"bitlink,omitempty"`
+	Shorten   *ShortenOptions       `json:"shorten,omitempty"`
+	Update    *BitlinkUpdateOptions `json:"update,omitempty"`
+	Queued    time.Time             `json:"queued"`
+
+	// corrupt is the decoding error of an entry moved aside by next
+	corrupt error
+}
+
+// Outbox persists Shorten and Update calls to a local directory and replays
+// them through the Client in background, so callers are not blocked while
+// Bitly is unreachable.
+//
+// Operations are replayed in the order they were queued and survive process
+// restarts. Delivery is at least once: an operation interrupted between the
+// API call and its removal from the directory is replayed again.
+type Outbox struct {
+	client *Client
+	dir    string
+
+	// MinBackoff and MaxBackoff bound the delay between replays while Bitly is unavailable
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// OnResult is called with the outcome of every operation, when nil
+	// results are sent to the channel returned by Results.
+	// It must be set before Run is called.
+	OnResult func(OutboxResult)
+
+	results chan OutboxResult
+	wakeup  chan struct{}
+
+	mu       sync.Mutex
+	running  bool
+	lastNano int64
+}
+
+// NewOutbox returns Outbox which stores pending operations in dir,
+// operations left by a previous process are picked up by Run
+func NewOutbox(client *Client, dir string) (*Outbox, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Outbox{
+		client:     client,
+		dir:        dir,
+		MinBackoff: defaultOutboxMinBackoff,
+		MaxBackoff: defaultOutboxMaxBackoff,
+		results:    make(chan OutboxResult),
+		wakeup:     make(chan struct{}, 1),
+	}, nil
+}
+
+// Shorten queues Bitlinks.Shorten call and returns its handle
+func (o *Outbox) Shorten(options *ShortenOptions) (OutboxHandle, error) {
+	if options == nil {
+		return "", errOptionsRequired
+	}
+	if options.LongURL == "" {
+		return "", &errorParameter{paramName: "long_url"}
+	}
+	return o.enqueue(&outboxEntry{Operation: OutboxShorten, Shorten: options})
+}
+
+// Update queues Bitlinks.Update call and returns its handle
+func (o *Outbox) Update(bitlink string, options *BitlinkUpdateOptions) (OutboxHandle, error) {
+	if bitlink == "" {
+		return "", &errorParameter{paramName: "bitlink"}
+	}
+	if options == nil {
+		return "", errOptionsRequired
+	}
+	// Invalid Bitlinks would never succeed, reject them before they are queued
+	id, err := ParseBitlink(bitlink)
+	if err != nil {
+		return "", err
+	}
+	return o.enqueue(&outboxEntry{Operation: OutboxUpdate, Bitlink: id.String(), Update: options})
+}
+
+// Results returns channel with outcomes of operations, it is used when OnResult is nil.
+// Run blocks until every result is received.
+func (o *Outbox) Results() <-chan OutboxResult {
+	return o.results
+}
+
+// Pending returns handles of operations which are not replayed yet, oldest first
+func (o *Outbox) Pending() ([]OutboxHandle, error) {
+	names, err := o.list()
+	if err != nil {
+		return nil, err
+	}
+	handles := make([]OutboxHandle, 0, len(names))
+	for _, name := range names {
+		handles = append(handles, OutboxHandle(strings.TrimSuffix(name, outboxFileExt)))
+	}
+	return handles, nil
+}
+
+// Run replays queued operations until ctx is done. While Bitly is unavailable
+// (network errors, 429 and 5xx responses, an open circuit of CircuitBreaker)
+// it waits with exponential backoff between MinBackoff and MaxBackoff, and at
+// least until the circuit lets requests through. Other errors are reported as results.
+// Entries which cannot be decoded are renamed with the .corrupt extension
+// and reported as results with Err, so they do not block the queue.
+func (o *Outbox) Run(ctx context.Context) error {
+	o.mu.Lock()
+	if o.running {
+		o.mu.Unlock()
+		return errOutboxRunning
+	}
+	o.running = true
+	o.mu.Unlock()
+	defer func() {
+		o.mu.Lock()
+		o.running = false
+		o.mu.Unlock()
+	}()
+
+	backoff := o.MinBackoff
+	for {
+		entry, err := o.next()
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-o.wakeup:
+			}
+			continue
+		}
+
+		link, err := o.replay(ctx, entry)
+		if err != nil && isTemporaryError(err) {
+			wait := backoff
+			if after := retryAfterError(err); after > wait {
+				wait = after
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			backoff *= 2
+			if backoff > o.MaxBackoff {
+				backoff = o.MaxBackoff
+			}
+			continue
+		}
+		backoff = o.MinBackoff
+
+		result := OutboxResult{
+			Handle:    entry.Handle,
+			Operation: entry.Operation,
+			Link:      link,
+			Err:       err,
+		}
+		if o.OnResult != nil {
+			o.OnResult(result)
+		} else {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case o.results <- result:
+			}
+		}
+		if err := os.Remove(o.entryPath(entry.Handle)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+}
+
+func (o *Outbox) replay(ctx context.Context, entry *outboxEntry) (*Bitlink, error) {
+	if entry.corrupt != nil {
+		return nil, entry.corrupt
+	}
+	switch entry.Operation {
+	case OutboxShorten:
+		link, _, err := o.client.Bitlinks.Shorten(ctx, entry.Shorten)
+		return link, err
+	case OutboxUpdate:
+		link, _, err := o.client.Bitlinks.Update(ctx, entry.Bitlink, entry.Update)
+		return link, err
+	}
+	return nil, fmt.Errorf("unknown outbox operation %q", entry.Operation)
+}
+
+func (o *Outbox) enqueue(entry *outboxEntry) (OutboxHandle, error) {
+	handle, err := o.newHandle()
+	if err != nil {
+		return "", err
+	}
+	entry.Handle = handle
+	entry.Queued = time.Now()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	// Write to a temporary file first, so partially written entries are never replayed
+	f, err := ioutil.TempFile(o.dir, ".tmp-")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), o.entryPath(handle))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	select {
+	case o.wakeup <- struct{}{}:
+	default:
+	}
+	return handle, nil
+}
+
+// newHandle returns handle which sorts after every handle issued before
+func (o *Outbox) newHandle() (OutboxHandle, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	o.mu.Lock()
+	nano := time.Now().UnixNano()
+	if nano <= o.lastNano {
+		nano = o.lastNano + 1
+	}
+	o.lastNano = nano
+	o.mu.Unlock()
+	return OutboxHandle(fmt.Sprintf("%016x-%s", nano, hex.EncodeToString(suffix))), nil
+}
+
+func (o *Outbox) next() (*outboxEntry, error) {
+	names, err := o.list()
+	if err != nil || len(names) == 0 {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(filepath.Join(o.dir, names[0]))
+	if err != nil {
+		return nil, err
+	}
+	entry := &outboxEntry{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		handle := OutboxHandle(strings.TrimSuffix(names[0], outboxFileExt))
+		path := o.entryPath(handle)
+		if err := os.Rename(path, path+outboxCorruptExt); err != nil {
+			return nil, err
+		}
+		return &outboxEntry{Handle: handle, corrupt: errors.Wrapf(err, "corrupted outbox entry %s", names[0])}, nil
+	}
+	return entry, nil
+}
+
+func (o *Outbox) list() ([]string, error) {
+	files, err := ioutil.ReadDir(o.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, outboxFileExt) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (o *Outbox) entryPath(handle OutboxHandle) string {
+	return filepath.Join(o.dir, string(handle)+outboxFileExt)
+}
@@ -0,0 +1,304 @@
+package bitly
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type outboxTestServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	available bool
+	requests  int
+}
+
+func newOutboxTestServer(t *testing.T) *outboxTestServer {
+	s := &outboxTestServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		available := s.available
+		s.mu.Unlock()
+		if !available {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`<html>unavailable</html>`))
+			return
+		}
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/v4/shorten":
+			options := &ShortenOptions{}
+			if err := json.NewDecoder(r.Body).Decode(options); err != nil {
+				t.Errorf("invalid request body: %v", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if options.LongURL == "http://invalid" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"message":"INVALID_ARG_LONG_URL"}`))
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]string{"id": "bit.ly/abc", "link": "http://bit.ly/abc", "long_url": options.LongURL})
+		case r.Method == "PATCH" && r.URL.Path == "/v4/bitlinks/bit.ly/abc":
+			options := &BitlinkUpdateOptions{}
+			if err := json.NewDecoder(r.Body).Decode(options); err != nil {
+				t.Errorf("invalid request body: %v", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"id": "bit.ly/abc", "title": *options.Title})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return s
+}
+
+func (s *outboxTestServer) setAvailable(available bool) {
+	s.mu.Lock()
+	s.available = available
+	s.mu.Unlock()
+}
+
+func (s *outboxTestServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func newTestOutbox(t *testing.T, baseURL, dir string) *Outbox {
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = baseURL
+	o, err := NewOutbox(c, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	o.MinBackoff = time.Millisecond
+	o.MaxBackoff = 10 * time.Millisecond
+	return o
+}
+
+func receiveResult(t *testing.T, o *Outbox) OutboxResult {
+	select {
+	case result := <-o.Results():
+		return result
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for outbox result")
+	}
+	return OutboxResult{}
+}
+
+func TestOutbox_ReplayWhenAvailable(t *testing.T) {
+	s := newOutboxTestServer(t)
+	defer s.Close()
+
+	o := newTestOutbox(t, s.URL, t.TempDir())
+	shortenHandle, err := o.Shorten(&ShortenOptions{LongURL: "http://example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	updateHandle, err := o.Update("bit.ly/abc", &BitlinkUpdateOptions{Title: String("new title")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- o.Run(ctx) }()
+
+	for s.requestCount() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	pending, err := o.Pending()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pending) != 2 || pending[0] != shortenHandle || pending[1] != updateHandle {
+		t.Fatalf("want pending %v got %v", []OutboxHandle{shortenHandle, updateHandle}, pending)
+	}
+	s.setAvailable(true)
+
+	result := receiveResult(t, o)
+	if result.Err != nil {
+		t.Fatalf("unexpected error: %v", result.Err)
+	}
+	if result.Handle != shortenHandle || result.Operation != OutboxShorten || result.Link.ID != "bit.ly/abc" {
+		t.Fatalf("unexpected shorten result %#v", result)
+	}
+	result = receiveResult(t, o)
+	if result.Err != nil {
+		t.Fatalf("unexpected error: %v", result.Err)
+	}
+	if result.Handle != updateHandle || result.Operation != OutboxUpdate || result.Link.Title != "new title" {
+		t.Fatalf("unexpected update result %#v", result)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("want error %v got %v", context.Canceled, err)
+	}
+	pending, err = o.Pending()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("want no pending operations got %v", pending)
+	}
+}
+
+func TestOutbox_SurviveRestart(t *testing.T) {
+	s := newOutboxTestServer(t)
+	defer s.Close()
+	dir := t.TempDir()
+
+	o := newTestOutbox(t, s.URL, dir)
+	handle, err := o.Shorten(&ShortenOptions{LongURL: "http://example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- o.Run(ctx) }()
+	for s.requestCount() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	s.setAvailable(true)
+	restarted := newTestOutbox(t, s.URL, dir)
+	var results []OutboxResult
+	restarted.OnResult = func(result OutboxResult) {
+		results = append(results, result)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go func() { done <- restarted.Run(ctx) }()
+
+	for {
+		pending, err := restarted.Pending()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(pending) == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	if len(results) != 1 || results[0].Handle != handle || results[0].Err != nil {
+		t.Fatalf("unexpected results %#v", results)
+	}
+}
+
+func TestOutbox_PermanentError(t *testing.T) {
+	s := newOutboxTestServer(t)
+	defer s.Close()
+	s.setAvailable(true)
+
+	o := newTestOutbox(t, s.URL, t.TempDir())
+	handle, err := o.Shorten(&ShortenOptions{LongURL: "http://invalid"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go o.Run(ctx)
+
+	result := receiveResult(t, o)
+	if result.Handle != handle || result.Err == nil {
+		t.Fatalf("want error result got %#v", result)
+	}
+	if s.requestCount() != 1 {
+		t.Fatalf("want 1 request got %v", s.requestCount())
+	}
+}
+
+func TestOutbox_OpenCircuit(t *testing.T) {
+	s := newOutboxTestServer(t)
+	defer s.Close()
+
+	o := newTestOutbox(t, s.URL, t.TempDir())
+	o.client.Use(CircuitBreaker(CircuitBreakerOptions{MinRequests: 1, CoolDown: 50 * time.Millisecond}))
+	handle, err := o.Shorten(&ShortenOptions{LongURL: "http://example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go o.Run(ctx)
+
+	// The failed request opens the circuit, the operation waits for its cool-down
+	for s.requestCount() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	s.setAvailable(true)
+
+	result := receiveResult(t, o)
+	if result.Handle != handle || result.Err != nil {
+		t.Fatalf("want operation replayed after the circuit closed got %#v", result)
+	}
+	if s.requestCount() != 2 {
+		t.Fatalf("want 2 requests got %v", s.requestCount())
+	}
+}
+
+func TestOutbox_CorruptEntry(t *testing.T) {
+	s := newOutboxTestServer(t)
+	defer s.Close()
+	s.setAvailable(true)
+	dir := t.TempDir()
+
+	o := newTestOutbox(t, s.URL, dir)
+	corrupt, err := o.newHandle()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ioutil.WriteFile(o.entryPath(corrupt), []byte(`{"handle":`), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handle, err := o.Shorten(&ShortenOptions{LongURL: "http://example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go o.Run(ctx)
+
+	result := receiveResult(t, o)
+	if result.Handle != corrupt || result.Err == nil || !strings.Contains(result.Err.Error(), "corrupted outbox entry") {
+		t.Fatalf("want corrupted entry result got %#v", result)
+	}
+	result = receiveResult(t, o)
+	if result.Handle != handle || result.Err != nil {
+		t.Fatalf("unexpected result %#v", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, string(corrupt)+".json.corrupt")); err != nil {
+		t.Fatalf("want corrupted entry kept aside: %v", err)
+	}
+}
+
+func TestOutbox_InvalidOptions(t *testing.T) {
+	o := newTestOutbox(t, "http://example.com", t.TempDir())
+	if _, err := o.Shorten(nil); err != errOptionsRequired {
+		t.Fatalf("want error %v got %v", errOptionsRequired, err)
+	}
+	if _, err := o.Shorten(&ShortenOptions{}); err == nil {
+		t.Fatalf("want error for empty long_url")
+	}
+	if _, err := o.Update("", &BitlinkUpdateOptions{}); err == nil {
+		t.Fatalf("want error for empty bitlink")
+	}
+}
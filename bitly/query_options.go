@@ -1,9 +1,11 @@
 package bitly
 
-type queryOption string
+// QueryOption is a value of filters which keep Bitlinks with a property,
+// without it or both
+type QueryOption string
 
 const (
-	BothOption queryOption = "both"
-	OnOption   queryOption = "on"
-	OffOption  queryOption = "off"
+	BothOption QueryOption = "both"
+	OnOption   QueryOption = "on"
+	OffOption  QueryOption = "off"
 )
@@ -0,0 +1,133 @@
+package reconcile
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/lcd1232/go-bitly/bitly"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// Manifest is the desired state of Bitlinks:
+//
+//	group: Ba1b2c3d4e5
+//	links:
+//	  - long_url: https://example.com/spring
+//	    back_half: spring
+//	    title: Spring sale
+//	    tags: [sale]
+//	  - long_url: https://example.com/winter
+//	    archived: true
+type Manifest struct {
+	// Group is the GUID of the group of links without their own group
+	Group string `json:"group" yaml:"group"`
+	// Prune archives Bitlinks of the groups of the manifest which it does not list
+	Prune bool   `json:"prune" yaml:"prune"`
+	Links []Link `json:"links" yaml:"links"`
+}
+
+// Link is a desired Bitlink. Title and Tags are managed when they are set,
+// so an empty title keeps the current one and an empty tags list clears tags.
+type Link struct {
+	LongURL string `json:"long_url" yaml:"long_url"`
+	// BackHalf is a custom back-half, like spring for bit.ly/spring. Links with
+	// a back-half are matched by it, the others by their long URL.
+	BackHalf string `json:"back_half,omitempty" yaml:"back_half,omitempty"`
+	// Domain is the short domain, the domain preference of the group by default
+	Domain   string   `json:"domain,omitempty" yaml:"domain,omitempty"`
+	Group    string   `json:"group,omitempty" yaml:"group,omitempty"`
+	Title    string   `json:"title,omitempty" yaml:"title,omitempty"`
+	Tags     []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Archived bool     `json:"archived,omitempty" yaml:"archived,omitempty"`
+}
+
+// ReadManifest reads a YAML or JSON manifest, JSON is expected when the
+// file name ends with .json
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m *Manifest
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		m, err = ParseJSON(data)
+	} else {
+		m, err = ParseYAML(data)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid manifest %s", path)
+	}
+	return m, nil
+}
+
+// ParseYAML decodes and validates a YAML manifest, unknown fields are errors
+func ParseYAML(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(m); err != nil {
+		return nil, err
+	}
+	return m, m.Validate()
+}
+
+// ParseJSON decodes and validates a JSON manifest, unknown fields are errors
+func ParseJSON(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(m); err != nil {
+		return nil, err
+	}
+	return m, m.Validate()
+}
+
+// Validate checks every link has a long URL and a group and back-halves are
+// valid and unique
+func (m *Manifest) Validate() error {
+	seen := map[string]int{}
+	for i, link := range m.Links {
+		if link.LongURL == "" {
+			return errors.Errorf("links[%d]: long_url is required", i)
+		}
+		if !strings.HasPrefix(link.LongURL, "http://") && !strings.HasPrefix(link.LongURL, "https://") {
+			return errors.Errorf("links[%d]: invalid long_url %q", i, link.LongURL)
+		}
+		if m.group(link) == "" {
+			return errors.Errorf("links[%d]: group is required", i)
+		}
+		key := "url " + m.group(link) + " " + link.Domain + " " + link.LongURL
+		if link.BackHalf != "" {
+			domain := link.Domain
+			if domain == "" {
+				// The domain is not known until the preference of the group is fetched
+				domain = "bit.ly"
+			}
+			if _, err := bitly.ParseBitlink(domain + "/" + link.BackHalf); err != nil {
+				return errors.Errorf("links[%d]: invalid back_half %q", i, link.BackHalf)
+			}
+			key = "back-half " + strings.ToLower(link.Domain) + "/" + link.BackHalf
+			if link.Domain == "" {
+				// Links without domain use the default domain of their group,
+				// so back-halves of different groups may not collide
+				key = "back-half group " + m.group(link) + "/" + link.BackHalf
+			}
+		}
+		if j, ok := seen[key]; ok {
+			return errors.Errorf("links[%d]: duplicates links[%d]", i, j)
+		}
+		seen[key] = i
+	}
+	return nil
+}
+
+// group returns the group of link
+func (m *Manifest) group(link Link) string {
+	if link.Group != "" {
+		return link.Group
+	}
+	return m.Group
+}
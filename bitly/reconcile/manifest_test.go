@@ -0,0 +1,122 @@
+package reconcile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reconcile")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	yamlData := `
+group: Ba1
+prune: true
+links:
+  - long_url: https://example.com/spring
+    back_half: spring
+    title: Spring sale
+    tags: [sale, spring]
+  - long_url: https://example.com/winter
+    group: Bother
+    archived: true
+    tags: []
+`
+	jsonData := `{"group":"Ba1","prune":true,"links":[
+		{"long_url":"https://example.com/spring","back_half":"spring","title":"Spring sale","tags":["sale","spring"]},
+		{"long_url":"https://example.com/winter","group":"Bother","archived":true,"tags":[]}]}`
+	want := &Manifest{
+		Group: "Ba1",
+		Prune: true,
+		Links: []Link{
+			{LongURL: "https://example.com/spring", BackHalf: "spring", Title: "Spring sale", Tags: []string{"sale", "spring"}},
+			{LongURL: "https://example.com/winter", Group: "Bother", Archived: true, Tags: []string{}},
+		},
+	}
+	for name, data := range map[string]string{"links.yaml": yamlData, "links.json": jsonData} {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, err := ReadManifest(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Fatalf("%s: want manifest %#v got %#v", name, want, got)
+		}
+	}
+}
+
+func TestParseYAML_Errors(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		data    string
+		wantErr string
+	}{
+		{
+			desc:    "unknown field",
+			data:    "links:\n  - long_url: https://example.com\n    titel: x\n",
+			wantErr: "field titel not found",
+		},
+		{
+			desc:    "missing long url",
+			data:    "group: Ba1\nlinks:\n  - title: x\n",
+			wantErr: "links[0]: long_url is required",
+		},
+		{
+			desc:    "invalid long url",
+			data:    "group: Ba1\nlinks:\n  - long_url: example.com\n",
+			wantErr: `links[0]: invalid long_url "example.com"`,
+		},
+		{
+			desc:    "missing group",
+			data:    "links:\n  - long_url: https://example.com\n",
+			wantErr: "links[0]: group is required",
+		},
+		{
+			desc:    "invalid back-half",
+			data:    "group: Ba1\nlinks:\n  - long_url: https://example.com\n    back_half: a/b\n",
+			wantErr: `links[0]: invalid back_half "a/b"`,
+		},
+		{
+			desc:    "duplicate back-half",
+			data:    "group: Ba1\nlinks:\n  - long_url: https://example.com/a\n    back_half: a\n  - long_url: https://example.com/b\n    back_half: a\n",
+			wantErr: "links[1]: duplicates links[0]",
+		},
+		{
+			desc:    "duplicate long url",
+			data:    "group: Ba1\nlinks:\n  - long_url: https://example.com/a\n  - long_url: https://example.com/a\n",
+			wantErr: "links[1]: duplicates links[0]",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := ParseYAML([]byte(tc.data))
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("want error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestManifest_ValidateBackHalfGroups(t *testing.T) {
+	// Without domain a back-half uses the default domain of its group,
+	// which may differ between groups
+	data := "group: Ba1\nlinks:\n  - long_url: https://example.com/a\n    back_half: a\n" +
+		"  - long_url: https://example.com/b\n    group: Bother\n    back_half: a\n"
+	if _, err := ParseYAML([]byte(data)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data += "  - long_url: https://example.com/c\n    group: Ba1\n    back_half: a\n"
+	if _, err := ParseYAML([]byte(data)); err == nil || !strings.Contains(err.Error(), "links[2]: duplicates links[0]") {
+		t.Fatalf("want duplicate back-half in the same group got %v", err)
+	}
+}
@@ -0,0 +1,349 @@
+// Package reconcile makes Bitlinks of groups match a declarative Manifest.
+//
+// NewPlan compares the manifest with the current Bitlinks of its groups and
+// returns the changes, Apply makes them:
+//
+//	m, err := reconcile.ReadManifest("links.yaml")
+//	plan, err := reconcile.NewPlan(ctx, client, m)
+//	results, err := reconcile.Apply(ctx, client, plan, reconcile.ApplyOptions{})
+//
+// Applying a plan of a manifest again results in an empty plan. Archiving is
+// destructive, so Apply refuses plans which archive Bitlinks unless
+// ApplyOptions.AllowArchive is set.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"github.com/lcd1232/go-bitly/bitly"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// Action is the kind of a Change
+type Action string
+
+const (
+	ActionCreate  Action = "create"
+	ActionUpdate  Action = "update"
+	ActionArchive Action = "archive"
+)
+
+// ErrArchiveNotAllowed is returned by Apply when the plan archives Bitlinks
+// and ApplyOptions.AllowArchive is not set
+var ErrArchiveNotAllowed = errors.New("reconcile: plan archives Bitlinks, archiving is not allowed")
+
+// FieldChange is a changed field of a Bitlink, From is nil for created Bitlinks
+type FieldChange struct {
+	Field string
+	From  interface{}
+	To    interface{}
+}
+
+func (f FieldChange) String() string {
+	if f.From == nil {
+		return fmt.Sprintf("%s: %s", f.Field, formatValue(f.To))
+	}
+	return fmt.Sprintf("%s: %s -> %s", f.Field, formatValue(f.From), formatValue(f.To))
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []string:
+		return "[" + strings.Join(v, ", ") + "]"
+	}
+	return fmt.Sprint(v)
+}
+
+// Change is a create, update or archive of a Bitlink
+type Change struct {
+	Action Action
+	// Bitlink is the ID of the changed Bitlink, it is empty for created ones
+	Bitlink string
+	Group   string
+	// Link is the desired state with the domain resolved, it is empty for
+	// Bitlinks archived by Manifest.Prune
+	Link   Link
+	Fields []FieldChange
+}
+
+// Plan is the list of changes which make Bitlinks match a Manifest
+type Plan struct {
+	Changes []Change
+}
+
+// Empty reports whether the Bitlinks already match the manifest
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Archives returns the number of archived Bitlinks
+func (p *Plan) Archives() int {
+	n := 0
+	for _, change := range p.Changes {
+		if change.Action == ActionArchive {
+			n++
+		}
+	}
+	return n
+}
+
+// NewPlan fetches Bitlinks of the groups of the manifest, archived ones
+// included, and returns changes which make them match it
+func NewPlan(ctx context.Context, client *bitly.Client, m *Manifest) (*Plan, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	var groups []string
+	existing := map[string][]bitly.Bitlink{}
+	addGroup := func(group string) error {
+		if _, ok := existing[group]; ok {
+			return nil
+		}
+		links, err := bitly.AllBitlinksByGroup(ctx, client.Groups, group)
+		if err != nil {
+			return errors.Wrapf(err, "cannot list Bitlinks of group %s", group)
+		}
+		groups = append(groups, group)
+		existing[group] = links
+		return nil
+	}
+	if m.Prune && m.Group != "" {
+		if err := addGroup(m.Group); err != nil {
+			return nil, err
+		}
+	}
+	for _, link := range m.Links {
+		if err := addGroup(m.group(link)); err != nil {
+			return nil, err
+		}
+	}
+
+	domains := map[string]string{}
+	domainOf := func(group string) (string, error) {
+		if domain, ok := domains[group]; ok {
+			return domain, nil
+		}
+		prefs, _, err := client.Groups.GetGroupPreferences(ctx, group)
+		if err != nil {
+			return "", errors.Wrapf(err, "cannot get preferences of group %s", group)
+		}
+		domains[group] = prefs.DomainPreference
+		return prefs.DomainPreference, nil
+	}
+
+	plan := &Plan{}
+	matched := map[string]bool{}
+	for _, link := range m.Links {
+		link.Group = m.group(link)
+		if link.Domain == "" {
+			domain, err := domainOf(link.Group)
+			if err != nil {
+				return nil, err
+			}
+			link.Domain = domain
+		}
+		link.Domain = strings.ToLower(link.Domain)
+
+		current := findBitlink(existing[link.Group], link, matched)
+		if current == nil {
+			plan.Changes = append(plan.Changes, Change{Action: ActionCreate, Group: link.Group, Link: link, Fields: createFields(link)})
+			continue
+		}
+		matched[current.ID] = true
+		fields := updateFields(current, link)
+		if len(fields) == 0 {
+			continue
+		}
+		action := ActionUpdate
+		if link.Archived && !current.Archived {
+			action = ActionArchive
+		}
+		plan.Changes = append(plan.Changes, Change{Action: action, Bitlink: current.ID, Group: link.Group, Link: link, Fields: fields})
+	}
+
+	if m.Prune {
+		for _, group := range groups {
+			for _, current := range existing[group] {
+				if matched[current.ID] || current.Archived {
+					continue
+				}
+				plan.Changes = append(plan.Changes, Change{
+					Action:  ActionArchive,
+					Bitlink: current.ID,
+					Group:   group,
+					Fields:  []FieldChange{{Field: "archived", From: false, To: true}},
+				})
+			}
+		}
+	}
+	return plan, nil
+}
+
+// findBitlink returns the Bitlink with the back-half of link, or without a
+// back-half the first not matched one with the long URL on the domain of link
+func findBitlink(links []bitly.Bitlink, link Link, matched map[string]bool) *bitly.Bitlink {
+	for i := range links {
+		current := &links[i]
+		if link.BackHalf != "" {
+			key := link.Domain + "/" + link.BackHalf
+			if sameBitlink(current.ID, key) {
+				return current
+			}
+			for _, custom := range current.CustomBitlinks {
+				if sameBitlink(custom, key) {
+					return current
+				}
+			}
+			continue
+		}
+		if !matched[current.ID] && current.LongURL == link.LongURL && sameBitlinkDomain(current.ID, link.Domain) {
+			return current
+		}
+	}
+	return nil
+}
+
+// sameBitlink compares Bitlinks in any form ParseBitlink accepts
+func sameBitlink(a, b string) bool {
+	idA, errA := bitly.ParseBitlink(a)
+	idB, errB := bitly.ParseBitlink(b)
+	return errA == nil && errB == nil && idA == idB
+}
+
+func sameBitlinkDomain(bitlink, domain string) bool {
+	id, err := bitly.ParseBitlink(bitlink)
+	return err == nil && id.Domain == domain
+}
+
+func createFields(link Link) []FieldChange {
+	fields := []FieldChange{{Field: "long_url", To: link.LongURL}}
+	if link.BackHalf != "" {
+		fields = append(fields, FieldChange{Field: "back_half", To: link.Domain + "/" + link.BackHalf})
+	}
+	if link.Title != "" {
+		fields = append(fields, FieldChange{Field: "title", To: link.Title})
+	}
+	if len(link.Tags) > 0 {
+		fields = append(fields, FieldChange{Field: "tags", To: bitly.NormalizeTags(link.Tags)})
+	}
+	if link.Archived {
+		fields = append(fields, FieldChange{Field: "archived", To: true})
+	}
+	return fields
+}
+
+func updateFields(current *bitly.Bitlink, link Link) []FieldChange {
+	var fields []FieldChange
+	if link.LongURL != current.LongURL {
+		fields = append(fields, FieldChange{Field: "long_url", From: current.LongURL, To: link.LongURL})
+	}
+	if link.Title != "" && link.Title != current.Title {
+		fields = append(fields, FieldChange{Field: "title", From: current.Title, To: link.Title})
+	}
+	if link.Tags != nil {
+		from, to := bitly.NormalizeTags(current.Tags), bitly.NormalizeTags(link.Tags)
+		if strings.Join(from, "\x00") != strings.Join(to, "\x00") {
+			fields = append(fields, FieldChange{Field: "tags", From: from, To: to})
+		}
+	}
+	if link.Archived != current.Archived {
+		fields = append(fields, FieldChange{Field: "archived", From: current.Archived, To: link.Archived})
+	}
+	return fields
+}
+
+// ApplyOptions configures Apply
+type ApplyOptions struct {
+	// AllowArchive allows plans which archive Bitlinks
+	AllowArchive bool
+}
+
+// Result is an applied Change with the ID of the Bitlink, for created
+// Bitlinks with a back-half it is the custom Bitlink
+type Result struct {
+	Change  Change
+	Bitlink string
+}
+
+// Apply makes the changes of plan in order and stops at the first failure,
+// the applied changes are returned with the error. A failed plan can be
+// computed and applied again, changes made before the failure are not repeated.
+func Apply(ctx context.Context, client *bitly.Client, plan *Plan, options ApplyOptions) ([]Result, error) {
+	if n := plan.Archives(); n > 0 && !options.AllowArchive {
+		return nil, errors.Wrapf(ErrArchiveNotAllowed, "%d Bitlinks would be archived", n)
+	}
+	var results []Result
+	for _, change := range plan.Changes {
+		var bitlink string
+		var err error
+		if change.Action == ActionCreate {
+			bitlink, err = create(ctx, client, change.Link)
+		} else {
+			bitlink, err = change.Bitlink, update(ctx, client, change.Bitlink, change.Fields)
+		}
+		if err != nil {
+			target := change.Bitlink
+			if target == "" {
+				target = change.Link.LongURL
+			}
+			return results, errors.Wrapf(err, "cannot %s %s", change.Action, target)
+		}
+		results = append(results, Result{Change: change, Bitlink: bitlink})
+	}
+	return results, nil
+}
+
+func create(ctx context.Context, client *bitly.Client, link Link) (string, error) {
+	created, _, err := client.Bitlinks.Shorten(ctx, &bitly.ShortenOptions{
+		LongURL:   link.LongURL,
+		Domain:    link.Domain,
+		GroupGUID: link.Group,
+	})
+	if err != nil {
+		return "", err
+	}
+	bitlink := created.ID
+	if link.BackHalf != "" {
+		custom := link.Domain + "/" + link.BackHalf
+		body := map[string]string{"custom_bitlink": custom, "bitlink_id": created.ID}
+		if _, err := client.Call(ctx, "POST", "/custom_bitlinks", nil, body, nil); err != nil {
+			return "", err
+		}
+		bitlink = custom
+	}
+
+	var fields []FieldChange
+	for _, field := range createFields(link) {
+		if field.Field != "long_url" && field.Field != "back_half" {
+			fields = append(fields, field)
+		}
+	}
+	return bitlink, update(ctx, client, created.ID, fields)
+}
+
+// update sends the changed fields of a Bitlink with Bitlinks.Update
+func update(ctx context.Context, client *bitly.Client, bitlink string, fields []FieldChange) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	options := &bitly.BitlinkUpdateOptions{}
+	for _, field := range fields {
+		switch field.Field {
+		case "long_url":
+			options.LongURL = field.To.(string)
+		case "title":
+			options.Title = bitly.String(field.To.(string))
+		case "tags":
+			options.Tags = bitly.Strings(field.To.([]string))
+		case "archived":
+			options.Archived = bitly.Bool(field.To.(bool))
+		default:
+			return errors.Errorf("cannot update field %s", field.Field)
+		}
+	}
+	_, _, err := client.Bitlinks.Update(ctx, bitlink, options)
+	return err
+}
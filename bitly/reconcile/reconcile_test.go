@@ -0,0 +1,147 @@
+package reconcile
+
+import (
+	"context"
+	"github.com/lcd1232/go-bitly/bitly"
+	"github.com/lcd1232/go-bitly/bitly/bitlytest"
+	"github.com/pkg/errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlanAndApply(t *testing.T) {
+	s := bitlytest.NewServer()
+	defer s.Close()
+	s.AddGroup(bitlytest.Group{GUID: "Bbrand", DomainPreference: "es.pn", BSDS: []string{"es.pn"}})
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/keep", LongURL: "https://example.com/keep", Title: "keep", Tags: []string{"b", "a"}})
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/retitle", LongURL: "https://example.com/retitle", Title: "old", Tags: []string{"x"}})
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/old", LongURL: "https://example.com/old"})
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/archived", LongURL: "https://example.com/archived", Archived: true})
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/abc", LongURL: "https://example.com/moved", CustomBitlinks: []string{"bit.ly/moved"}})
+	client := s.Client()
+	ctx := context.Background()
+
+	m := &Manifest{
+		Group: bitlytest.DefaultGroupGUID,
+		Prune: true,
+		Links: []Link{
+			{LongURL: "https://example.com/keep", Title: "keep", Tags: []string{"a", "b"}},
+			{LongURL: "https://example.com/retitle", Title: "new", Tags: []string{}},
+			{LongURL: "https://example.com/archived"},
+			{LongURL: "https://example.com/moved/here", BackHalf: "moved"},
+			{LongURL: "https://example.com/spring", BackHalf: "spring", Title: "Spring", Tags: []string{"sale"}},
+			{LongURL: "https://example.com/brand", Group: "Bbrand"},
+		},
+	}
+	plan, err := NewPlan(ctx, client, m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	type summary struct {
+		Action  Action
+		Bitlink string
+		Fields  []string
+	}
+	var got []summary
+	for _, change := range plan.Changes {
+		var fields []string
+		for _, field := range change.Fields {
+			fields = append(fields, field.String())
+		}
+		got = append(got, summary{change.Action, change.Bitlink, fields})
+	}
+	want := []summary{
+		{ActionUpdate, "bit.ly/retitle", []string{`title: "old" -> "new"`, "tags: [x] -> []"}},
+		{ActionUpdate, "bit.ly/archived", []string{"archived: true -> false"}},
+		{ActionUpdate, "bit.ly/abc", []string{`long_url: "https://example.com/moved" -> "https://example.com/moved/here"`}},
+		{ActionCreate, "", []string{`long_url: "https://example.com/spring"`, `back_half: "bit.ly/spring"`, `title: "Spring"`, "tags: [sale]"}},
+		{ActionCreate, "", []string{`long_url: "https://example.com/brand"`}},
+		{ActionArchive, "bit.ly/old", []string{"archived: false -> true"}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want plan\n%v\ngot\n%v", want, got)
+	}
+	if plan.Changes[4].Link.Domain != "es.pn" {
+		t.Fatalf("want domain preference of the group got %v", plan.Changes[4].Link.Domain)
+	}
+
+	if _, err := Apply(ctx, client, plan, ApplyOptions{}); errors.Cause(err) != ErrArchiveNotAllowed {
+		t.Fatalf("want ErrArchiveNotAllowed got %v", err)
+	}
+	if link, _ := s.Bitlink("bit.ly/retitle"); link.Title != "old" {
+		t.Fatalf("want nothing applied when archiving is not allowed")
+	}
+
+	results, err := Apply(ctx, client, plan, ApplyOptions{AllowArchive: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != len(plan.Changes) || results[3].Bitlink != "bit.ly/spring" {
+		t.Fatalf("unexpected results %#v", results)
+	}
+	spring, ok := s.Bitlink("bit.ly/spring")
+	if !ok || spring.Title != "Spring" || !reflect.DeepEqual(spring.Tags, []string{"sale"}) {
+		t.Fatalf("want created Bitlink with back-half got %#v", spring)
+	}
+	if link, _ := s.Bitlink("bit.ly/retitle"); link.Title != "new" || len(link.Tags) != 0 {
+		t.Fatalf("want updated Bitlink with cleared tags got %#v", link)
+	}
+	if link, _ := s.Bitlink("bit.ly/old"); !link.Archived {
+		t.Fatalf("want pruned Bitlink archived")
+	}
+	if link, _ := s.Bitlink("bit.ly/moved"); link.LongURL != "https://example.com/moved/here" {
+		t.Fatalf("want long url of back-half updated got %#v", link)
+	}
+
+	plan, err = NewPlan(ctx, client, m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !plan.Empty() {
+		t.Fatalf("want empty plan after apply got %#v", plan.Changes)
+	}
+}
+
+func TestApply_Failure(t *testing.T) {
+	s := bitlytest.NewServer()
+	defer s.Close()
+	s.AddGroup(bitlytest.Group{GUID: "Bother"})
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/taken", GroupGUID: "Bother", LongURL: "https://example.com/taken"})
+	client := s.Client()
+	ctx := context.Background()
+
+	m := &Manifest{Group: bitlytest.DefaultGroupGUID, Links: []Link{
+		{LongURL: "https://example.com/a", Title: "a"},
+		{LongURL: "https://example.com/b", BackHalf: "taken"},
+	}}
+	plan, err := NewPlan(ctx, client, m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	results, err := Apply(ctx, client, plan, ApplyOptions{})
+	if err == nil || !strings.HasSuffix(err.Error(), "409 ALREADY_A_BITLY_LINK") {
+		t.Fatalf("want custom bitlink error got %v", err)
+	}
+	if len(results) != 1 || results[0].Change.Link.LongURL != "https://example.com/a" {
+		t.Fatalf("want changes applied before the failure got %#v", results)
+	}
+
+	m.Links = m.Links[:1]
+	plan, err = NewPlan(ctx, client, m)
+	if err != nil || !plan.Empty() {
+		t.Fatalf("want applied change not repeated got %#v %v", plan, err)
+	}
+}
+
+func TestNewPlan_ListError(t *testing.T) {
+	s := bitlytest.NewServer()
+	defer s.Close()
+	s.AddFault(bitlytest.Fault{Path: "/v4/groups/" + bitlytest.DefaultGroupGUID + "/bitlinks", Status: 503, Message: "TEMPORARILY_UNAVAILABLE"})
+
+	m := &Manifest{Group: bitlytest.DefaultGroupGUID, Links: []Link{{LongURL: "https://example.com"}}}
+	_, err := NewPlan(context.Background(), s.Client(), m)
+	if _, ok := errors.Cause(err).(*bitly.ErrorResponse); !ok {
+		t.Fatalf("want *bitly.ErrorResponse got %v", err)
+	}
+}
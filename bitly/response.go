@@ -0,0 +1,84 @@
+package bitly
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	headerRateLimitLimit     = "X-RateLimit-Limit"
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+	headerBitlyRequestID     = "X-Bitly-Request-Id"
+	headerRequestID          = "X-Request-Id"
+)
+
+// RateLimit is the rate limit state reported by Bitly with every response.
+// Fields are zero when Bitly did not send the corresponding header.
+type RateLimit struct {
+	// Limit is the number of requests allowed in the current window
+	Limit int
+	// Remaining is the number of requests left in the current window
+	Remaining int
+	// Reset is the time when the current window resets
+	Reset time.Time
+}
+
+// Response wraps http.Response returned by Bitly and provides parsed metadata.
+// It is returned by every service method, also together with an error,
+// so the request ID can be logged when contacting Bitly support.
+type Response struct {
+	*http.Response
+
+	RateLimit RateLimit
+	// RequestID is the identifier Bitly assigned to the request
+	RequestID string
+	// Pagination is set for responses of paginated endpoints
+	Pagination *Paginate
+	// Cached is true when the response was served by Cache middleware
+	Cached bool
+}
+
+func newResponse(r *http.Response) *Response {
+	response := &Response{Response: r}
+	response.RateLimit = parseRateLimit(r.Header)
+	response.RequestID = r.Header.Get(headerBitlyRequestID)
+	if response.RequestID == "" {
+		response.RequestID = r.Header.Get(headerRequestID)
+	}
+	return response
+}
+
+func parseRateLimit(h http.Header) RateLimit {
+	var rate RateLimit
+	if limit := h.Get(headerRateLimitLimit); limit != "" {
+		rate.Limit, _ = strconv.Atoi(limit)
+	}
+	if remaining := h.Get(headerRateLimitRemaining); remaining != "" {
+		rate.Remaining, _ = strconv.Atoi(remaining)
+	}
+	if reset := h.Get(headerRateLimitReset); reset != "" {
+		if v, err := strconv.ParseInt(reset, 10, 64); err == nil {
+			rate.Reset = time.Unix(v, 0)
+		}
+	}
+	return rate
+}
+
+// parsePagination returns pagination object embedded in the body of paginated responses
+func parsePagination(body []byte) *Paginate {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '{' {
+		return nil
+	}
+	page := struct {
+		Pagination *Paginate `json:"pagination"`
+	}{}
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil
+	}
+	return page.Pagination
+}
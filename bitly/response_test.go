@@ -0,0 +1,149 @@
+package bitly
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		header http.Header
+		want   RateLimit
+	}{
+		{
+			desc: "all headers",
+			header: http.Header{
+				"X-Ratelimit-Limit":     []string{"1000"},
+				"X-Ratelimit-Remaining": []string{"998"},
+				"X-Ratelimit-Reset":     []string{"1531998000"},
+			},
+			want: RateLimit{Limit: 1000, Remaining: 998, Reset: time.Unix(1531998000, 0)},
+		},
+		{
+			desc:   "no headers",
+			header: http.Header{},
+			want:   RateLimit{},
+		},
+		{
+			desc: "invalid values",
+			header: http.Header{
+				"X-Ratelimit-Remaining": []string{"many"},
+				"X-Ratelimit-Reset":     []string{"tomorrow"},
+			},
+			want: RateLimit{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := parseRateLimit(tc.header)
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Fatalf("want rate limit %#v got %#v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParsePagination(t *testing.T) {
+	testCases := []struct {
+		desc string
+		body string
+		want *Paginate
+	}{
+		{
+			desc: "paginated body",
+			body: `{"links":[],"pagination":{"prev":"","next":"https://api-ssl.bitly.com/v4/groups/Ba1/bitlinks?page=2","size":50,"page":1,"total":120}}`,
+			want: &Paginate{Next: "https://api-ssl.bitly.com/v4/groups/Ba1/bitlinks?page=2", Size: 50, Page: 1, Total: 120},
+		},
+		{
+			desc: "body without pagination",
+			body: `{"guid":"Ba1"}`,
+			want: nil,
+		},
+		{
+			desc: "array body",
+			body: `[1,2]`,
+			want: nil,
+		},
+		{
+			desc: "empty body",
+			body: ``,
+			want: nil,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := parsePagination([]byte(tc.body))
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Fatalf("want pagination %#v got %#v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestClient_Response(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		responseCode   int
+		responseBody   string
+		wantErr        bool
+		wantRequestID  string
+		wantRemaining  int
+		wantPagination *Paginate
+	}{
+		{
+			desc:           "ok response",
+			responseCode:   http.StatusOK,
+			responseBody:   `{"links":[],"pagination":{"prev":"","next":"","size":50,"page":1,"total":0}}`,
+			wantRequestID:  "req-1",
+			wantRemaining:  42,
+			wantPagination: &Paginate{Size: 50, Page: 1},
+		},
+		{
+			desc:          "error response",
+			responseCode:  http.StatusTooManyRequests,
+			responseBody:  `{"message":"RATE_LIMIT_EXCEEDED"}`,
+			wantErr:       true,
+			wantRequestID: "req-1",
+			wantRemaining: 42,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Bitly-Request-Id", "req-1")
+				w.Header().Set("X-RateLimit-Remaining", "42")
+				w.WriteHeader(tc.responseCode)
+				w.Write([]byte(tc.responseBody))
+			}))
+			defer s.Close()
+
+			c := NewClient(http.DefaultClient)
+			c.BaseURL = s.URL
+
+			_, resp, err := c.Groups.GetBitlinksByGroup(context.Background(), "Ba1", nil)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp == nil {
+				t.Fatalf("want response got nil")
+			}
+			if resp.StatusCode != tc.responseCode {
+				t.Fatalf("want status %v got %v", tc.responseCode, resp.StatusCode)
+			}
+			if resp.RequestID != tc.wantRequestID {
+				t.Fatalf("want request id %v got %v", tc.wantRequestID, resp.RequestID)
+			}
+			if resp.RateLimit.Remaining != tc.wantRemaining {
+				t.Fatalf("want remaining %v got %v", tc.wantRemaining, resp.RateLimit.Remaining)
+			}
+			if !reflect.DeepEqual(tc.wantPagination, resp.Pagination) {
+				t.Fatalf("want pagination %#v got %#v", tc.wantPagination, resp.Pagination)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package bitly
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	defaultSearchConcurrency = 4
+	defaultSearchPageSize    = 100
+)
+
+// SearchOptions configures Search, Keyword, Query, Tags and Archived are
+// sent to GetBitlinksByGroup of every group
+type SearchOptions struct {
+	// OrganizationGUID limits the search to groups of the organization
+	OrganizationGUID string
+	Keyword          string
+	Query            string
+	Tags             []string
+	Archived         QueryOption
+	// PageSize is the size of pages requested from Bitly, 100 by default
+	PageSize int
+	// Concurrency is the number of groups searched at the same time, 4 by default
+	Concurrency int
+}
+
+// SearchResult is a found Bitlink or, when Err is set, the failure of
+// searching a group
+type SearchResult struct {
+	GroupGUID string
+	Link      Bitlink
+	Err       error
+}
+
+// Search looks for Bitlinks in every group of the user, or of the
+// organization of options, and sends them to the returned channel newest
+// first. The channel is closed when every group is searched or ctx is done,
+// callers which stop reading early must cancel ctx.
+//
+// Groups are searched concurrently and results are streamed while later
+// pages are fetched. A Bitlink is reported once even when pagination shifts
+// under concurrent changes. A failed group is reported as a result with
+// Err and does not stop the search of the others, the returned error means
+// the groups could not be listed.
+func (c *Client) Search(ctx context.Context, options SearchOptions) (<-chan SearchResult, error) {
+	groups, _, err := c.Groups.ListGroups(ctx, options.OrganizationGUID)
+	if err != nil {
+		return nil, err
+	}
+	if options.PageSize <= 0 {
+		options.PageSize = defaultSearchPageSize
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = defaultSearchConcurrency
+	}
+
+	m := &searchMerge{queues: make([]*searchQueue, len(groups.Groups))}
+	m.cond = sync.NewCond(&m.mu)
+	for i, group := range groups.Groups {
+		m.queues[i] = &searchQueue{groupGUID: group.GUID}
+	}
+
+	slots := make(chan struct{}, options.Concurrency)
+	for _, q := range m.queues {
+		go func(q *searchQueue) {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				m.finish(q, ctx.Err())
+				return
+			}
+			err := c.searchGroup(ctx, q.groupGUID, options, m, q)
+			<-slots
+			m.finish(q, err)
+		}(q)
+	}
+
+	results := make(chan SearchResult)
+	go func() {
+		defer close(results)
+		seen := map[string]bool{}
+		for {
+			result, ok := m.next()
+			if !ok {
+				return
+			}
+			if result.Err == nil {
+				if seen[result.Link.ID] {
+					continue
+				}
+				seen[result.Link.ID] = true
+			}
+			select {
+			case results <- result:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return results, nil
+}
+
+// searchGroup adds every page of Bitlinks of the group to q
+func (c *Client) searchGroup(ctx context.Context, groupGUID string, options SearchOptions, m *searchMerge, q *searchQueue) error {
+	params := &GetBitlinksByGroupQueryParams{
+		Size:     options.PageSize,
+		Keyword:  options.Keyword,
+		Query:    options.Query,
+		Tags:     options.Tags,
+		Archived: options.Archived,
+	}
+	for params.Page = 1; ; params.Page++ {
+		page, _, err := c.Groups.GetBitlinksByGroup(ctx, groupGUID, params)
+		if err != nil {
+			return err
+		}
+		m.add(q, page.Links)
+		if page.Pagination.Next == "" || len(page.Links) == 0 {
+			return nil
+		}
+	}
+}
+
+// searchQueue holds fetched and not yet sent Bitlinks of a group,
+// newest first as Bitly lists them
+type searchQueue struct {
+	groupGUID string
+	links     []Bitlink
+	done      bool
+	err       error
+}
+
+// searchMerge merges queues of groups into a single list ordered newest
+// first. Fetching never waits for the consumer, so groups release their
+// concurrency slot as soon as their last page is fetched.
+type searchMerge struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queues []*searchQueue
+}
+
+func (m *searchMerge) add(q *searchQueue, links []Bitlink) {
+	m.mu.Lock()
+	q.links = append(q.links, links...)
+	m.mu.Unlock()
+	m.cond.Broadcast()
+}
+
+func (m *searchMerge) finish(q *searchQueue, err error) {
+	m.mu.Lock()
+	q.done, q.err = true, err
+	m.mu.Unlock()
+	m.cond.Broadcast()
+}
+
+// next returns the newest Bitlink of all groups or an error of a failed
+// group, it waits until every group has a Bitlink fetched or is done.
+// ok is false when every group is done and sent.
+func (m *searchMerge) next() (result SearchResult, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for {
+		ready := true
+		var newest *searchQueue
+		for _, q := range m.queues {
+			if len(q.links) == 0 {
+				if !q.done {
+					ready = false
+					continue
+				}
+				if q.err != nil {
+					err := q.err
+					q.err = nil
+					return SearchResult{GroupGUID: q.groupGUID, Err: err}, true
+				}
+				continue
+			}
+			if newest == nil || newer(q.links[0], newest.links[0]) {
+				newest = q
+			}
+		}
+		if ready {
+			if newest == nil {
+				return SearchResult{}, false
+			}
+			link := newest.links[0]
+			newest.links = newest.links[1:]
+			return SearchResult{GroupGUID: newest.groupGUID, Link: link}, true
+		}
+		m.cond.Wait()
+	}
+}
+
+// newer orders Bitlinks by creation time, newest first, and then by ID
+func newer(a, b Bitlink) bool {
+	ta, tb := a.CreatedAt.Time(), b.CreatedAt.Time()
+	if !ta.Equal(tb) {
+		return ta.After(tb)
+	}
+	return a.ID < b.ID
+}
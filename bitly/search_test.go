@@ -0,0 +1,129 @@
+package bitly
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSearchTestServer(t *testing.T, links map[string][]Bitlink) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path == "/v4/groups" {
+			list := &GroupList{}
+			for _, guid := range []string{"Ba", "Bb", "Bc", "Bd"} {
+				if org := q.Get("organization_guid"); org != "" && !strings.HasPrefix(org, "O"+strings.ToLower(guid[1:])) {
+					continue
+				}
+				list.Groups = append(list.Groups, Group{GUID: guid})
+			}
+			json.NewEncoder(w).Encode(list)
+			return
+		}
+		guid := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v4/groups/"), "/bitlinks")
+		if guid == "Bd" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"FORBIDDEN"}`))
+			return
+		}
+		if q.Get("keyword") != "spring" || q.Get("tags") != "sale" {
+			t.Errorf("want search params sent got %s", r.URL.RawQuery)
+		}
+		page, _ := strconv.Atoi(q.Get("page"))
+		size, _ := strconv.Atoi(q.Get("size"))
+		all := links[guid]
+		resp := &BitlinksByGroup{}
+		for i := (page - 1) * size; i < page*size && i < len(all); i++ {
+			resp.Links = append(resp.Links, all[i])
+		}
+		if page*size < len(all) {
+			resp.Pagination.Next = "next"
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestClient_Search(t *testing.T) {
+	day := func(d int) JSONDate {
+		return JSONDate(time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC))
+	}
+	s := newSearchTestServer(t, map[string][]Bitlink{
+		"Ba": {{ID: "bit.ly/a5", CreatedAt: day(5)}, {ID: "bit.ly/a3", CreatedAt: day(3)}, {ID: "bit.ly/a1", CreatedAt: day(1)}},
+		// bit.ly/b4 is repeated as if a Bitlink was created between pages
+		"Bb": {{ID: "bit.ly/b4", CreatedAt: day(4)}, {ID: "bit.ly/b4", CreatedAt: day(4)}, {ID: "bit.ly/b2", CreatedAt: day(2)}},
+	})
+	defer s.Close()
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL
+
+	results, err := c.Search(context.Background(), SearchOptions{Keyword: "spring", Tags: []string{"sale"}, PageSize: 1, Concurrency: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got, failed []string
+	for result := range results {
+		if result.Err != nil {
+			failed = append(failed, result.GroupGUID)
+			if e, ok := result.Err.(*ErrorResponse); !ok || e.StatusCode() != http.StatusForbidden {
+				t.Fatalf("want *ErrorResponse got %v", result.Err)
+			}
+			continue
+		}
+		got = append(got, result.GroupGUID+" "+result.Link.ID)
+	}
+	want := []string{"Ba bit.ly/a5", "Bb bit.ly/b4", "Ba bit.ly/a3", "Bb bit.ly/b2", "Ba bit.ly/a1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want merged results newest first %v got %v", want, got)
+	}
+	if !reflect.DeepEqual(failed, []string{"Bd"}) {
+		t.Fatalf("want failed group reported got %v", failed)
+	}
+
+	results, err = c.Search(context.Background(), SearchOptions{OrganizationGUID: "Ob", Keyword: "spring", Tags: []string{"sale"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got = nil
+	for result := range results {
+		got = append(got, result.Link.ID)
+	}
+	if !reflect.DeepEqual(got, []string{"bit.ly/b4", "bit.ly/b2"}) {
+		t.Fatalf("want results of the organization got %v", got)
+	}
+}
+
+func TestClient_SearchCancel(t *testing.T) {
+	s := newSearchTestServer(t, map[string][]Bitlink{"Ba": {{ID: "bit.ly/a"}, {ID: "bit.ly/b"}}})
+	defer s.Close()
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results, err := c.Search(ctx, SearchOptions{Keyword: "spring", Tags: []string{"sale"}, PageSize: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-results
+	cancel()
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("want results closed after cancel")
+	case <-drain(results):
+	}
+}
+
+func drain(results <-chan SearchResult) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		for range results {
+		}
+		close(done)
+	}()
+	return done
+}
@@ -1,15 +1,44 @@
 package bitly
 
 import (
+	"bytes"
+	"fmt"
+	"strconv"
 	"time"
 )
 
-// JSONDate is simple time.Time object with custom json formatter
+// JSONDate is simple time.Time object with custom json formatter.
+// null and empty string are decoded as the zero time and the zero time
+// is encoded as null.
 type JSONDate time.Time
 
 const timeFormat = "2006-01-02T15:04:05-0700"
 
+// timeFormats are layouts accepted by UnmarshalJSON, Bitly mostly uses the
+// first one but RFC 3339 forms with Z or +00:00 offset are also seen.
+// Fractional seconds are accepted by both.
+var timeFormats = []string{
+	timeFormat,
+	time.RFC3339,
+}
+
+// Time returns JSONDate as time.Time
+func (jd JSONDate) Time() time.Time {
+	return time.Time(jd)
+}
+
+// String returns JSONDate in the Bitly API format, zero time is an empty string
+func (jd JSONDate) String() string {
+	if time.Time(jd).IsZero() {
+		return ""
+	}
+	return time.Time(jd).Format(timeFormat)
+}
+
 func (jd JSONDate) MarshalJSON() ([]byte, error) {
+	if time.Time(jd).IsZero() {
+		return []byte("null"), nil
+	}
 	b := make([]byte, 0, len(timeFormat)+2)
 	b = append(b, '"')
 	b = time.Time(jd).AppendFormat(b, timeFormat)
@@ -18,10 +47,34 @@ func (jd JSONDate) MarshalJSON() ([]byte, error) {
 }
 
 func (jd *JSONDate) UnmarshalJSON(p []byte) error {
-	t, err := time.Parse(`"`+timeFormat+`"`, string(p))
+	if bytes.Equal(p, []byte("null")) {
+		*jd = JSONDate{}
+		return nil
+	}
+	s, err := strconv.Unquote(string(p))
+	if err != nil {
+		return fmt.Errorf("invalid date %s", p)
+	}
+	if s == "" {
+		*jd = JSONDate{}
+		return nil
+	}
+	t, err := parseTime(s)
 	if err != nil {
 		return err
 	}
 	*jd = JSONDate(t)
 	return nil
 }
+
+func parseTime(s string) (time.Time, error) {
+	var err error
+	for _, layout := range timeFormats {
+		var t time.Time
+		t, err = time.Parse(layout, s)
+		if err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, err
+}
@@ -20,6 +20,66 @@ func TestJSONDate_UnmarshalJSON(t *testing.T) {
 			wantTime: JSONDate(time.Date(2012, 12, 18, 18, 14, 53, 0, time.UTC)),
 			wantErr:  "",
 		},
+		{
+			desc:     "numeric offset",
+			rawJSON:  []byte(`"2012-12-18T21:14:53+0300"`),
+			wantTime: JSONDate(time.Date(2012, 12, 18, 18, 14, 53, 0, time.UTC)),
+			wantErr:  "",
+		},
+		{
+			desc:     "Z suffix",
+			rawJSON:  []byte(`"2012-12-18T18:14:53Z"`),
+			wantTime: JSONDate(time.Date(2012, 12, 18, 18, 14, 53, 0, time.UTC)),
+			wantErr:  "",
+		},
+		{
+			desc:     "colon offset",
+			rawJSON:  []byte(`"2012-12-18T18:14:53+00:00"`),
+			wantTime: JSONDate(time.Date(2012, 12, 18, 18, 14, 53, 0, time.UTC)),
+			wantErr:  "",
+		},
+		{
+			desc:     "negative colon offset",
+			rawJSON:  []byte(`"2012-12-18T13:14:53-05:00"`),
+			wantTime: JSONDate(time.Date(2012, 12, 18, 18, 14, 53, 0, time.UTC)),
+			wantErr:  "",
+		},
+		{
+			desc:     "fractional seconds",
+			rawJSON:  []byte(`"2012-12-18T18:14:53.250+0000"`),
+			wantTime: JSONDate(time.Date(2012, 12, 18, 18, 14, 53, 250000000, time.UTC)),
+			wantErr:  "",
+		},
+		{
+			desc:     "fractional seconds with Z suffix",
+			rawJSON:  []byte(`"2012-12-18T18:14:53.250Z"`),
+			wantTime: JSONDate(time.Date(2012, 12, 18, 18, 14, 53, 250000000, time.UTC)),
+			wantErr:  "",
+		},
+		{
+			desc:     "null",
+			rawJSON:  []byte(`null`),
+			wantTime: JSONDate{},
+			wantErr:  "",
+		},
+		{
+			desc:     "empty string",
+			rawJSON:  []byte(`""`),
+			wantTime: JSONDate{},
+			wantErr:  "",
+		},
+		{
+			desc:     "invalid time",
+			rawJSON:  []byte(`"yesterday"`),
+			wantTime: JSONDate{},
+			wantErr:  "cannot parse",
+		},
+		{
+			desc:     "not a string",
+			rawJSON:  []byte(`1531998000`),
+			wantTime: JSONDate{},
+			wantErr:  "invalid date 1531998000",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -38,3 +98,68 @@ func TestJSONDate_UnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestJSONDate_MarshalJSON(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		date     JSONDate
+		wantJSON string
+	}{
+		{
+			desc:     "valid time",
+			date:     JSONDate(time.Date(2012, 12, 18, 18, 14, 53, 0, time.UTC)),
+			wantJSON: `"2012-12-18T18:14:53+0000"`,
+		},
+		{
+			desc:     "zero time",
+			date:     JSONDate{},
+			wantJSON: `null`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := json.Marshal(tc.date)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tc.wantJSON {
+				t.Fatalf("want json %v got %s", tc.wantJSON, got)
+			}
+			var decoded JSONDate
+			if err := json.Unmarshal(got, &decoded); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !decoded.Time().Equal(tc.date.Time()) {
+				t.Fatalf("want time %v got %v", tc.date, decoded)
+			}
+		})
+	}
+}
+
+func TestJSONDate_String(t *testing.T) {
+	testCases := []struct {
+		desc string
+		date JSONDate
+		want string
+	}{
+		{
+			desc: "valid time",
+			date: JSONDate(time.Date(2012, 12, 18, 18, 14, 53, 0, time.UTC)),
+			want: "2012-12-18T18:14:53+0000",
+		},
+		{
+			desc: "zero time",
+			date: JSONDate{},
+			want: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := tc.date.String(); got != tc.want {
+				t.Fatalf("want %v got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package bitly
+
+import (
+	"context"
+	"net/http"
+)
+
+// Attribute keys set on spans by Tracing middleware
+const (
+	AttributeMethod     = "http.request.method"
+	AttributeRoute      = "http.route"
+	AttributeStatusCode = "http.response.status_code"
+	AttributeErrorCode  = "bitly.error_code"
+	AttributeRequestID  = "bitly.request_id"
+	AttributeRetries    = "bitly.retries"
+)
+
+// Operation identifies the service call a request belongs to
+type Operation struct {
+	// Name is the service and method, like Groups.GetBitlinksByGroup
+	Name string
+	// Path is the templated API path, like /v4/groups/{group_guid}/bitlinks
+	Path string
+}
+
+type operationKey struct{}
+
+type retriesKey struct{}
+
+func withOperation(ctx context.Context, name, path string) context.Context {
+	return context.WithValue(ctx, operationKey{}, Operation{Name: name, Path: path})
+}
+
+// OperationFromContext returns Operation set by the service method which created
+// the request context, middlewares get it with OperationFromContext(req.Context())
+func OperationFromContext(ctx context.Context) (Operation, bool) {
+	op, ok := ctx.Value(operationKey{}).(Operation)
+	return op, ok
+}
+
+// requestOperation returns Operation of req, requests created with NewRequest
+// directly are named Do and keep their URL path
+func requestOperation(req *http.Request) Operation {
+	if op, ok := OperationFromContext(req.Context()); ok {
+		return op
+	}
+	return Operation{Name: "Do", Path: req.URL.Path}
+}
+
+// withRetries returns ctx with a counter of retries made by Retry middleware,
+// the counter of ctx is reused if it has one already
+func withRetries(ctx context.Context) (context.Context, *int) {
+	if retries, ok := ctx.Value(retriesKey{}).(*int); ok {
+		return ctx, retries
+	}
+	retries := new(int)
+	return context.WithValue(ctx, retriesKey{}, retries), retries
+}
+
+func countRetry(ctx context.Context) {
+	if retries, ok := ctx.Value(retriesKey{}).(*int); ok {
+		*retries++
+	}
+}
+
+// Attribute is a key-value pair attached to a span.
+// Value is a string, int or bool.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span is a traced unit of work
+type Span interface {
+	SetAttributes(attributes ...Attribute)
+	// RecordError marks the span as failed
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts spans, see package bitlyotel for OpenTelemetry adapter
+type Tracer interface {
+	// Start starts a span named name as a child of the span in ctx,
+	// the returned context carries the new span
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Tracing returns Middleware which starts a span for every service call.
+// Spans are named after the Operation, like bitly.Groups.GetBitlinksByGroup,
+// requests created with NewRequest directly are named bitly.Do.
+// Use it before Retry, so the span covers all attempts and counts retries.
+func Tracing(tracer Tracer) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*Response, error) {
+			op := requestOperation(req)
+			ctx, span := tracer.Start(req.Context(), "bitly."+op.Name)
+			defer span.End()
+			ctx, retries := withRetries(ctx)
+
+			resp, err := next.Do(req.WithContext(ctx))
+
+			attributes := []Attribute{
+				{Key: AttributeMethod, Value: req.Method},
+				{Key: AttributeRoute, Value: op.Path},
+				{Key: AttributeRetries, Value: *retries},
+			}
+			if resp != nil {
+				attributes = append(attributes, Attribute{Key: AttributeStatusCode, Value: resp.StatusCode})
+				if resp.RequestID != "" {
+					attributes = append(attributes, Attribute{Key: AttributeRequestID, Value: resp.RequestID})
+				}
+			}
+			if errResp, ok := err.(*ErrorResponse); ok {
+				attributes = append(attributes, Attribute{Key: AttributeErrorCode, Value: errResp.ErrorCode()})
+			}
+			span.SetAttributes(attributes...)
+			if err != nil {
+				span.RecordError(err)
+			}
+			return resp, err
+		})
+	}
+}
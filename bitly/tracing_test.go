@@ -0,0 +1,153 @@
+package bitly
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testSpan struct {
+	name       string
+	parent     *testSpan
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (s *testSpan) SetAttributes(attributes ...Attribute) {
+	for _, a := range attributes {
+		s.attributes[a.Key] = a.Value
+	}
+}
+
+func (s *testSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *testSpan) End() {
+	s.ended = true
+}
+
+type testSpanKey struct{}
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(testSpanKey{}).(*testSpan)
+	span := &testSpan{name: name, parent: parent, attributes: map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+func TestTracing(t *testing.T) {
+	requests := 0
+	var requestSpan *testSpan
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-Bitly-Request-Id", "req-1")
+		switch {
+		case r.URL.Path == "/v4/groups/Ba1/bitlinks" && requests == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/v4/groups/Ba1/bitlinks":
+			w.Write([]byte(`{"links":[]}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"INVALID_ARG_LONG_URL","errors":[{"field":"long_url","error_code":"invalid"}]}`))
+		}
+	}))
+	defer s.Close()
+
+	tracer := &testTracer{}
+	c := NewClient(http.DefaultClient)
+	c.BaseURL = s.URL
+	c.Use(
+		Tracing(tracer),
+		Retry(RetryOptions{MaxRetries: 2, MinBackoff: time.Millisecond}),
+		func(next Doer) Doer {
+			return DoerFunc(func(req *http.Request) (*Response, error) {
+				requestSpan, _ = req.Context().Value(testSpanKey{}).(*testSpan)
+				return next.Do(req)
+			})
+		},
+	)
+
+	parent := &testSpan{name: "parent"}
+	ctx := context.WithValue(context.Background(), testSpanKey{}, parent)
+	if _, _, err := c.Groups.GetBitlinksByGroup(ctx, "Ba1", &GetBitlinksByGroupQueryParams{Size: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := c.Bitlinks.Shorten(context.Background(), &ShortenOptions{LongURL: "example"}); err == nil {
+		t.Fatalf("want error")
+	}
+	req, err := c.NewRequest(context.Background(), "GET", "/v4/user", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Do(req, nil)
+
+	if len(tracer.spans) != 3 {
+		t.Fatalf("want 3 spans got %d", len(tracer.spans))
+	}
+	testCases := []struct {
+		name           string
+		parent         *testSpan
+		wantAttributes map[string]interface{}
+		wantErr        bool
+	}{
+		{
+			name:   "bitly.Groups.GetBitlinksByGroup",
+			parent: parent,
+			wantAttributes: map[string]interface{}{
+				AttributeMethod:     "GET",
+				AttributeRoute:      "/v4/groups/{group_guid}/bitlinks",
+				AttributeStatusCode: 200,
+				AttributeRequestID:  "req-1",
+				AttributeRetries:    1,
+			},
+		},
+		{
+			name: "bitly.Bitlinks.Shorten",
+			wantAttributes: map[string]interface{}{
+				AttributeMethod:     "POST",
+				AttributeRoute:      "/v4/shorten",
+				AttributeStatusCode: 400,
+				AttributeRequestID:  "req-1",
+				AttributeErrorCode:  "invalid",
+				AttributeRetries:    0,
+			},
+			wantErr: true,
+		},
+		{
+			name: "bitly.Do",
+			wantAttributes: map[string]interface{}{
+				AttributeMethod:     "GET",
+				AttributeRoute:      "/v4/user",
+				AttributeStatusCode: 400,
+				AttributeRequestID:  "req-1",
+				AttributeErrorCode:  "invalid",
+				AttributeRetries:    0,
+			},
+			wantErr: true,
+		},
+	}
+	for i, tc := range testCases {
+		span := tracer.spans[i]
+		if span.name != tc.name || span.parent != tc.parent || !span.ended {
+			t.Fatalf("unexpected span %d: %#v", i, span)
+		}
+		if !reflect.DeepEqual(tc.wantAttributes, span.attributes) {
+			t.Fatalf("want attributes of %s %v got %v", tc.name, tc.wantAttributes, span.attributes)
+		}
+		if tc.wantErr != (span.err != nil) {
+			t.Fatalf("want error of %s %v got %v", tc.name, tc.wantErr, span.err)
+		}
+	}
+	if requestSpan != tracer.spans[2] {
+		t.Fatalf("want request context to carry span")
+	}
+}
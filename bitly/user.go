@@ -2,12 +2,30 @@ package bitly
 
 import (
 	"context"
+	"encoding/json"
 )
 
 type Email struct {
 	Email      string `json:"email"`
 	IsPrimary  bool   `json:"is_primary"`
 	IsVerified bool   `json:"is_verified"`
+
+	// Extra holds fields returned by Bitly which this package does not know
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, unknown fields are kept in Extra
+func (e *Email) UnmarshalJSON(data []byte) error {
+	type email Email
+	extra, err := unmarshalExtra(data, (*email)(e))
+	e.Extra = extra
+	return err
+}
+
+// MarshalJSON implements json.Marshaler
+func (e Email) MarshalJSON() ([]byte, error) {
+	type email Email
+	return marshalExtra(email(e), e.Extra)
 }
 
 type User struct {
@@ -19,48 +37,70 @@ type User struct {
 	Is2FAEnabled bool     `json:"is_2fa_enabled"`
 	Emails       []Email  `json:"emails"`
 	IsSSOUser    bool     `json:"is_sso_user"`
+
+	// Extra holds fields returned by Bitly which this package does not know
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, unknown fields are kept in Extra
+func (u *User) UnmarshalJSON(data []byte) error {
+	type user User
+	extra, err := unmarshalExtra(data, (*user)(u))
+	u.Extra = extra
+	return err
+}
+
+// MarshalJSON implements json.Marshaler
+func (u User) MarshalJSON() ([]byte, error) {
+	type user User
+	return marshalExtra(user(u), u.Extra)
 }
 
 type UserUpdateOptions struct {
 	Name string `json:"name"`
 }
 
+// UserClient methods return the model along with any error, it is empty
+// when the request failed
 type UserClient struct {
 	client *Client
 }
 
-func (s *UserClient) Get(ctx context.Context) (*User, error) {
+func (s *UserClient) Get(ctx context.Context) (*User, *Response, error) {
+	ctx = withCacheable(withOperation(ctx, "User.Get", "/v4/user"))
 	path := versioned("user")
 	u := &User{}
 
-	_, err := s.client.get(path, u)
-	return u, err
+	resp, err := s.client.get(ctx, path, u)
+	return u, resp, err
 }
 
-func (s *UserClient) Update(ctx context.Context, options *UserUpdateOptions) (*User, error) {
+func (s *UserClient) Update(ctx context.Context, options *UserUpdateOptions) (*User, *Response, error) {
 	if options == nil {
-		return nil, errOptionsRequired
+		return nil, nil, errOptionsRequired
 	}
+	ctx = withOperation(ctx, "User.Update", "/v4/user")
 	path := versioned("user")
 	u := &User{}
 
-	_, err := s.client.patch(path, options, u)
-	return u, err
+	resp, err := s.client.patch(ctx, path, options, u)
+	return u, resp, err
 }
 
-func (s *UserClient) GetGroups(ctx context.Context, login string) (*groupsResponse, error) {
+func (s *UserClient) GetGroups(ctx context.Context, login string) (*GroupList, *Response, error) {
 	if login == "" {
-		return nil, &errorParameter{paramName: "login"}
+		return nil, nil, &errorParameter{paramName: "login"}
 	}
+	ctx = withOperation(ctx, "User.GetGroups", "/v4/user")
 	path := versioned("user")
-	groupsResp := &groupsResponse{}
+	groupsResp := &GroupList{}
 
-	_, err := s.client.get(path, groupsResp)
-	return groupsResp, err
+	resp, err := s.client.get(ctx, path, groupsResp)
+	return groupsResp, resp, err
 }
 
 type UserService interface {
-	Get(ctx context.Context) (*User, error)
-	Update(ctx context.Context, options *UserUpdateOptions) (*User, error)
-	GetGroups(ctx context.Context, login string) (*groupsResponse, error)
+	Get(ctx context.Context) (*User, *Response, error)
+	Update(ctx context.Context, options *UserUpdateOptions) (*User, *Response, error)
+	GetGroups(ctx context.Context, login string) (*GroupList, *Response, error)
 }
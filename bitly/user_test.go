@@ -139,7 +139,7 @@ func TestUserClient_Get(t *testing.T) {
 			c := NewClient(http.DefaultClient)
 			c.BaseURL = s.URL
 
-			u, err := c.User.Get(context.Background())
+			u, _, err := c.User.Get(context.Background())
 
 			if tc.wantErr == "" && err != nil {
 				t.Fatalf("unexpected error: %v", err)
@@ -224,7 +224,7 @@ func TestUserClient_Update(t *testing.T) {
 			c := NewClient(http.DefaultClient)
 			c.BaseURL = s.URL
 
-			u, err := c.User.Update(context.Background(), tc.options)
+			u, _, err := c.User.Update(context.Background(), tc.options)
 
 			if tc.wantErr == "" && err != nil {
 				t.Fatalf("unexpected error: %v", err)
@@ -0,0 +1,291 @@
+package bitly
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWatcherInterval = time.Minute
+	defaultWatcherOverlap  = time.Minute
+)
+
+var (
+	errWatcherRunning = errors.New("watcher is already running")
+)
+
+// WatchEventType is a kind of change reported by Watcher
+type WatchEventType string
+
+const (
+	// WatchCreated is reported for Bitlinks the watcher has not seen before
+	WatchCreated WatchEventType = "created"
+	// WatchUpdated is reported when the long URL, title, tags, custom
+	// Bitlinks or deep links of a Bitlink change, or it is unarchived
+	WatchUpdated WatchEventType = "updated"
+	// WatchArchived is reported when a Bitlink is archived
+	WatchArchived WatchEventType = "archived"
+)
+
+// WatchEvent is a change of a Bitlink of the watched group
+type WatchEvent struct {
+	Type WatchEventType
+	Link Bitlink
+}
+
+// watcherState is persisted between polls: the high-water mark and
+// fingerprints of every Bitlink seen
+type watcherState struct {
+	GroupGUID     string            `json:"group_guid"`
+	HighWaterMark time.Time         `json:"high_water_mark"`
+	Links         map[string]string `json:"links"`
+}
+
+// Watcher polls Bitlinks of a group and reports created, updated and
+// archived ones, it is a replacement of webhooks for syncing Bitlinks
+// into other systems.
+//
+// Every poll asks for Bitlinks modified after the high-water mark, the start
+// of the previous poll minus Overlap, and compares them with fingerprints of
+// Bitlinks seen before, so the same change is reported once. The state is
+// saved to a file after events of a poll are delivered, delivery is at least
+// once: events of a poll interrupted before the state is saved are reported again.
+type Watcher struct {
+	client    *Client
+	groupGUID string
+	statePath string
+
+	// Interval is the time between polls
+	Interval time.Duration
+	// Overlap is subtracted from the high-water mark to tolerate clock skew
+	// between the host and Bitly
+	Overlap time.Duration
+	// SkipExisting makes the first poll without a state record Bitlinks
+	// of the group without reporting them as created.
+	// It must be set before Run is called.
+	SkipExisting bool
+
+	// OnEvent is called with every event, when nil events are sent to the
+	// channel returned by Events. It must be set before Run is called.
+	OnEvent func(WatchEvent)
+	// Now returns the current time, time.Now by default
+	Now func() time.Time
+
+	events chan WatchEvent
+
+	mu      sync.Mutex
+	running bool
+}
+
+// NewWatcher returns Watcher of the group which keeps its state in the file
+// statePath, a state left by a previous process is picked up by Run
+func NewWatcher(client *Client, groupGUID, statePath string) (*Watcher, error) {
+	if groupGUID == "" {
+		return nil, &errorParameter{paramName: "group_guid"}
+	}
+	if statePath == "" {
+		return nil, &errorParameter{paramName: "state_path"}
+	}
+	return &Watcher{
+		client:    client,
+		groupGUID: groupGUID,
+		statePath: statePath,
+		Interval:  defaultWatcherInterval,
+		Overlap:   defaultWatcherOverlap,
+		Now:       time.Now,
+		events:    make(chan WatchEvent),
+	}, nil
+}
+
+// Events returns channel with events, it is used when OnEvent is nil.
+// Run blocks until every event is received.
+func (w *Watcher) Events() <-chan WatchEvent {
+	return w.events
+}
+
+// Run polls the group right away and then every Interval until ctx is done.
+// While Bitly is unavailable (network errors, 429 and 5xx responses, an open
+// circuit of CircuitBreaker) polls are retried on the next tick, but not before
+// the circuit lets requests through. Other errors stop Run.
+func (w *Watcher) Run(ctx context.Context) error {
+	w.mu.Lock()
+	if w.running {
+		w.mu.Unlock()
+		return errWatcherRunning
+	}
+	w.running = true
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		w.running = false
+		w.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		err := w.Poll(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil && !isTemporaryError(err) {
+			return err
+		}
+		retryAt := time.Now().Add(retryAfterError(err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if wait := time.Until(retryAt); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+	}
+}
+
+// Poll checks the group once and reports changes since the previous poll,
+// it is useful for watchers driven by cron instead of Run
+func (w *Watcher) Poll(ctx context.Context) error {
+	state, err := w.loadState()
+	if err != nil {
+		return err
+	}
+	first := state.HighWaterMark.IsZero()
+	started := w.Now()
+
+	params := &GetBitlinksByGroupQueryParams{Size: 100, Archived: BothOption}
+	if !first {
+		params.ModifiedAfter = JSONDate(state.HighWaterMark.Add(-w.Overlap)).String()
+	}
+	paginator, err := w.client.Groups.GetBitlinksByGroupPaginator(ctx, w.groupGUID, params)
+	if err != nil {
+		return err
+	}
+	var events []WatchEvent
+	for {
+		if err := paginator.Get(); err != nil {
+			return err
+		}
+		for _, link := range paginator.Resp.Links {
+			fingerprint, err := bitlinkFingerprint(link)
+			if err != nil {
+				return err
+			}
+			previous, seen := state.Links[link.ID]
+			state.Links[link.ID] = fingerprint
+			switch {
+			case !seen:
+				events = append(events, WatchEvent{Type: WatchCreated, Link: link})
+			case previous == fingerprint:
+			case link.Archived && strings.HasPrefix(previous, "0"):
+				events = append(events, WatchEvent{Type: WatchArchived, Link: link})
+			default:
+				events = append(events, WatchEvent{Type: WatchUpdated, Link: link})
+			}
+		}
+		if !paginator.Next() {
+			break
+		}
+	}
+
+	if !(first && w.SkipExisting) {
+		// Bitly lists the newest Bitlinks first, events are reported oldest first
+		sort.SliceStable(events, func(i, j int) bool {
+			return events[i].Link.CreatedAt.Time().Before(events[j].Link.CreatedAt.Time())
+		})
+		for _, event := range events {
+			if w.OnEvent != nil {
+				w.OnEvent(event)
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case w.events <- event:
+			}
+		}
+	}
+	state.HighWaterMark = started
+	return w.saveState(state)
+}
+
+// bitlinkFingerprint digests fields of link which are reported as changes,
+// it starts with 1 for archived Bitlinks and 0 for the others
+func bitlinkFingerprint(link Bitlink) (string, error) {
+	tags := append([]string{}, link.Tags...)
+	sort.Strings(tags)
+	custom := append([]string{}, link.CustomBitlinks...)
+	sort.Strings(custom)
+	data, err := json.Marshal([]interface{}{link.LongURL, link.Title, tags, custom, link.DeepLinks})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	archived := "0"
+	if link.Archived {
+		archived = "1"
+	}
+	return archived + hex.EncodeToString(sum[:16]), nil
+}
+
+func (w *Watcher) loadState() (*watcherState, error) {
+	state := &watcherState{GroupGUID: w.groupGUID, Links: map[string]string{}}
+	data, err := ioutil.ReadFile(w.statePath)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, errors.Wrapf(err, "corrupted watcher state %s", w.statePath)
+	}
+	if state.GroupGUID != w.groupGUID {
+		return nil, errors.Errorf("watcher state %s belongs to group %s", w.statePath, state.GroupGUID)
+	}
+	if state.Links == nil {
+		state.Links = map[string]string{}
+	}
+	return state, nil
+}
+
+// saveState replaces the state file atomically, so an interrupted write
+// keeps the previous state
+func (w *Watcher) saveState(state *watcherState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(w.statePath), ".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), w.statePath)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
@@ -0,0 +1,266 @@
+package bitly
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type watcherTestServer struct {
+	*httptest.Server
+	mu            sync.Mutex
+	links         []Bitlink
+	modifiedAfter []string
+	status        int
+}
+
+func newWatcherTestServer() *watcherTestServer {
+	s := &watcherTestServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.status != 0 {
+			w.WriteHeader(s.status)
+			w.Write([]byte(`{"message":"TEMPORARILY_UNAVAILABLE"}`))
+			return
+		}
+		if r.URL.Path != "/v4/groups/Ba1/bitlinks" || r.URL.Query().Get("archived") != "both" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		s.modifiedAfter = append(s.modifiedAfter, r.URL.Query().Get("modified_after"))
+		json.NewEncoder(w).Encode(&BitlinksByGroup{Links: s.links})
+	}))
+	return s
+}
+
+func (s *watcherTestServer) setLinks(links ...Bitlink) {
+	s.mu.Lock()
+	s.links = links
+	s.mu.Unlock()
+}
+
+func newTestWatcher(t *testing.T, s *watcherTestServer, statePath string) *Watcher {
+	client := NewClient(http.DefaultClient)
+	client.BaseURL = s.URL
+	w, err := NewWatcher(client, "Ba1", statePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w.Now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	return w
+}
+
+func TestWatcher_Poll(t *testing.T) {
+	s := newWatcherTestServer()
+	defer s.Close()
+	dir, err := ioutil.TempDir("", "watcher")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	statePath := filepath.Join(dir, "state.json")
+
+	older := JSONDate(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	newer := JSONDate(time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC))
+	a := Bitlink{ID: "bit.ly/a", LongURL: "https://example.com/a", Tags: []string{"x", "y"}, CreatedAt: older}
+	b := Bitlink{ID: "bit.ly/b", LongURL: "https://example.com/b", CreatedAt: newer}
+
+	var events []WatchEvent
+	poll := func() []WatchEvent {
+		w := newTestWatcher(t, s, statePath)
+		events = nil
+		w.OnEvent = func(event WatchEvent) { events = append(events, event) }
+		if err := w.Poll(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return events
+	}
+	summary := func(events []WatchEvent) []string {
+		var got []string
+		for _, event := range events {
+			got = append(got, string(event.Type)+" "+event.Link.ID)
+		}
+		return got
+	}
+	check := func(desc string, got []WatchEvent, want ...string) {
+		t.Helper()
+		if g := summary(got); len(g) != len(want) || (len(want) > 0 && !reflect.DeepEqual(g, want)) {
+			t.Fatalf("%s: want events %v got %v", desc, want, g)
+		}
+	}
+
+	s.setLinks(b, a)
+	check("first poll", poll(), "created bit.ly/a", "created bit.ly/b")
+	check("no changes", poll())
+
+	a.Tags = []string{"y", "x"}
+	b.Title = "renamed"
+	s.setLinks(b, a)
+	check("title change", poll(), "updated bit.ly/b")
+
+	b.Archived = true
+	s.setLinks(b)
+	check("archive", poll(), "archived bit.ly/b")
+	b.Archived = false
+	s.setLinks(b)
+	check("unarchive", poll(), "updated bit.ly/b")
+
+	s.mu.Lock()
+	modifiedAfter := s.modifiedAfter
+	s.mu.Unlock()
+	if modifiedAfter[0] != "" || modifiedAfter[1] != "2020-01-02T03:03:05+0000" {
+		t.Fatalf("want high-water mark minus overlap got %q", modifiedAfter)
+	}
+
+	other, err := NewWatcher(NewClient(http.DefaultClient), "Bother", statePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := other.Poll(context.Background()); err == nil {
+		t.Fatalf("want error for state of another group")
+	}
+}
+
+func TestWatcher_SkipExisting(t *testing.T) {
+	s := newWatcherTestServer()
+	defer s.Close()
+	dir, err := ioutil.TempDir("", "watcher")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s.setLinks(Bitlink{ID: "bit.ly/a", LongURL: "https://example.com/a"})
+	w := newTestWatcher(t, s, filepath.Join(dir, "state.json"))
+	w.SkipExisting = true
+	w.OnEvent = func(event WatchEvent) { t.Fatalf("unexpected event %v", event) }
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s.setLinks(Bitlink{ID: "bit.ly/b", LongURL: "https://example.com/b"}, Bitlink{ID: "bit.ly/a", LongURL: "https://example.com/a"})
+	var got []WatchEvent
+	w.OnEvent = func(event WatchEvent) { got = append(got, event) }
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].Type != WatchCreated || got[0].Link.ID != "bit.ly/b" {
+		t.Fatalf("want only new Bitlink reported got %v", got)
+	}
+}
+
+func TestWatcher_Run(t *testing.T) {
+	s := newWatcherTestServer()
+	defer s.Close()
+	dir, err := ioutil.TempDir("", "watcher")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s.mu.Lock()
+	s.status = http.StatusServiceUnavailable
+	s.mu.Unlock()
+	w := newTestWatcher(t, s, filepath.Join(dir, "state.json"))
+	w.Interval = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	s.mu.Lock()
+	s.status = 0
+	s.mu.Unlock()
+	s.setLinks(Bitlink{ID: "bit.ly/a", LongURL: "https://example.com/a"})
+	select {
+	case event := <-w.Events():
+		if event.Type != WatchCreated || event.Link.ID != "bit.ly/a" {
+			t.Fatalf("unexpected event %v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("want event after Bitly is available again")
+	}
+	if err := w.Run(ctx); err != errWatcherRunning {
+		t.Fatalf("want errWatcherRunning got %v", err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("want context.Canceled got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("want Run to stop on cancel")
+	}
+}
+
+func TestWatcher_RunOpenCircuit(t *testing.T) {
+	s := newWatcherTestServer()
+	defer s.Close()
+	s.mu.Lock()
+	s.status = http.StatusServiceUnavailable
+	s.mu.Unlock()
+
+	w := newTestWatcher(t, s, filepath.Join(t.TempDir(), "state.json"))
+	w.Interval = 10 * time.Millisecond
+	// polls counts every poll request, including ones rejected by the circuit
+	var mu sync.Mutex
+	polls := 0
+	w.client.Use(func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*Response, error) {
+			mu.Lock()
+			polls++
+			mu.Unlock()
+			return next.Do(req)
+		})
+	}, CircuitBreaker(CircuitBreakerOptions{MinRequests: 1, CoolDown: 100 * time.Millisecond}))
+	type pollEvent struct {
+		event WatchEvent
+		polls int
+	}
+	events := make(chan pollEvent, 1)
+	w.OnEvent = func(event WatchEvent) {
+		mu.Lock()
+		events <- pollEvent{event: event, polls: polls}
+		mu.Unlock()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+
+	// The failed poll opens the circuit, Run keeps going and waits for its cool-down
+	time.Sleep(30 * time.Millisecond)
+	s.mu.Lock()
+	s.status = 0
+	s.mu.Unlock()
+	s.setLinks(Bitlink{ID: "bit.ly/a", LongURL: "https://example.com/a"})
+	select {
+	case got := <-events:
+		if got.event.Type != WatchCreated || got.event.Link.ID != "bit.ly/a" {
+			t.Fatalf("unexpected event %v", got.event)
+		}
+		// The failed poll, the one rejected by the circuit and the trial
+		if got.polls != 3 {
+			t.Fatalf("want 3 polls got %d", got.polls)
+		}
+	case err := <-done:
+		t.Fatalf("want Run to keep going behind an open circuit got %v", err)
+	case <-time.After(time.Second):
+		t.Fatalf("want event after the circuit closed")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// loginResult is printed by auth login
+type loginResult struct {
+	Profile string `json:"profile"`
+	Login   string `json:"login"`
+	Config  string `json:"config"`
+}
+
+func runAuthLogin(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet()
+	baseURL := fs.String("base-url", "", "API server of the profile")
+	group := fs.String("group", "", "default group GUID of the profile")
+	domain := fs.String("domain", "", "default short domain of the profile")
+	makeDefault := fs.Bool("default", false, "use the profile when none is selected")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	path, err := c.configPath()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	name, _ := c.profileName(cfg)
+
+	// The token is read from stdin only, flags end up in shell history and ps
+	fmt.Fprintf(c.stderr, "Paste the token of profile %s: ", name)
+	line, err := bufio.NewReader(c.stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return errors.Wrap(err, "cannot read token")
+	}
+	token := strings.TrimSpace(line)
+	if token == "" {
+		return usagef("token is empty")
+	}
+
+	// login updates the given settings and keeps the rest of a stored profile
+	p := &profile{}
+	if stored, ok := cfg.Profiles[name]; ok {
+		*p = *stored
+	}
+	p.Token = token
+	if *baseURL != "" {
+		p.BaseURL = *baseURL
+	}
+	if *group != "" {
+		p.GroupGUID = *group
+	}
+	if *domain != "" {
+		p.Domain = *domain
+	}
+
+	// The token is checked before it is stored
+	check := *p
+	if check.BaseURL == "" {
+		check.BaseURL = c.getenv("BITLY_BASE_URL")
+	}
+	user, _, err := c.clientFor(&check).User.Get(ctx)
+	if err != nil {
+		return errors.Wrap(err, "cannot authenticate with the token")
+	}
+
+	cfg.Profiles[name] = p
+	if *makeDefault || cfg.DefaultProfile == "" {
+		cfg.DefaultProfile = name
+	}
+	if err := cfg.save(path); err != nil {
+		return err
+	}
+	return c.render(&loginResult{Profile: name, Login: user.Login, Config: path})
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/lcd1232/go-bitly/bitly/backup"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func runGroupsBackup(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet()
+	out := fs.String("file", "-", "bundle file, a tar archive when it ends with .tar and JSON otherwise, - is stdout")
+	clicks := fs.Bool("clicks", false, "add click summaries, it costs a request per Bitlink")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	client, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	b, err := backup.Backup(ctx, client, args[0], backup.Options{Clicks: *clicks})
+	if err != nil {
+		return err
+	}
+
+	if *out == "-" {
+		return b.WriteJSON(c.stdout)
+	}
+	// The bundle is written to a temporary file first, so a failed backup
+	// does not replace the previous one
+	f, err := ioutil.TempFile(filepath.Dir(*out), ".bundle-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	write := b.WriteJSON
+	if strings.EqualFold(filepath.Ext(*out), ".tar") {
+		write = b.WriteTar
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), *out); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "%d Bitlinks of group %s written to %s\n", len(b.Links), args[0], *out)
+	return nil
+}
+
+// restoreRow is printed by groups restore
+type restoreRow struct {
+	Source    string   `json:"source"`
+	Bitlink   string   `json:"bitlink"`
+	Status    string   `json:"status"`
+	Conflicts []string `json:"conflicts"`
+	Error     string   `json:"error"`
+}
+
+func runGroupsRestore(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet()
+	group := fs.String("group", "", "GUID of the target group, the group of the profile by default")
+	domain := fs.String("domain", "", "short domain of created Bitlinks, the domain of the backed up Bitlink by default")
+	overwrite := fs.Bool("overwrite", false, "update title, tags and archived of Bitlinks the group already has")
+	skipArchived := fs.Bool("skip-archived", false, "do not restore archived Bitlinks")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	var r io.Reader = c.stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	b, err := backup.ReadBundle(r)
+	if err != nil {
+		return err
+	}
+	client, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	target, err := c.groupGUID(*group)
+	if err != nil {
+		return err
+	}
+
+	report, restoreErr := backup.Restore(ctx, client, b, backup.RestoreOptions{
+		GroupGUID:    target,
+		Domain:       *domain,
+		Overwrite:    *overwrite,
+		SkipArchived: *skipArchived,
+	})
+	if report == nil {
+		return restoreErr
+	}
+	rows := []restoreRow{}
+	for _, result := range report.Results {
+		row := restoreRow{Source: result.Source, Bitlink: result.Bitlink, Status: string(result.Status), Conflicts: result.Conflicts}
+		if result.Err != nil {
+			row.Error = result.Err.Error()
+		}
+		rows = append(rows, row)
+	}
+	if err := c.render(rows); err != nil {
+		return err
+	}
+	if restoreErr != nil {
+		return restoreErr
+	}
+	failed := report.Count(backup.StatusFailed)
+	fmt.Fprintf(c.stderr, "%d Bitlinks: %d created, %d existing, %d updated, %d failed, %d with conflicts\n",
+		len(report.Results), report.Count(backup.StatusCreated), report.Count(backup.StatusExisting),
+		report.Count(backup.StatusUpdated), failed, len(report.Conflicts()))
+	if failed > 0 {
+		return errors.Errorf("%d Bitlinks failed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/lcd1232/go-bitly/bitly/bitlytest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_GroupsBackupRestore(t *testing.T) {
+	s := bitlytest.NewServer()
+	defer s.Close()
+	s.AddGroup(bitlytest.Group{GUID: "Btarget"})
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/a", LongURL: "https://example.com/a", Title: "a"})
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/b", LongURL: "https://example.com/b", Archived: true})
+
+	dir, err := ioutil.TempDir("", "bitly-backup")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "group.tar")
+
+	code, stdout, stderr := runCLI(s, "groups", "backup", bitlytest.DefaultGroupGUID, "--file", path)
+	if code != exitOK || stdout != "" || !strings.Contains(stderr, "2 Bitlinks of group") {
+		t.Fatalf("want bundle written got %d\n%s%s", code, stdout, stderr)
+	}
+
+	args := []string{"groups", "restore", path, "--group", "Btarget", "--skip-archived", "-o", "csv", "--columns", "source,status"}
+	code, stdout, stderr = runCLI(s, args...)
+	if want := "source,status\nbit.ly/a,created\n"; code != exitOK || stdout != want {
+		t.Fatalf("want restore report\n%s\ngot %d\n%s%s", want, code, stdout, stderr)
+	}
+	code, stdout, _ = runCLI(s, args...)
+	if want := "source,status\nbit.ly/a,existing\n"; code != exitOK || stdout != want {
+		t.Fatalf("want repeated restore to keep Bitlinks\n%s\ngot %d\n%s", want, code, stdout)
+	}
+
+	code, stdout, _ = runCLI(s, "groups", "backup", "Btarget")
+	if code != exitOK || !strings.Contains(stdout, `"version": 1`) || !strings.Contains(stdout, "https://example.com/a") {
+		t.Fatalf("want JSON bundle on stdout got %d\n%s", code, stdout)
+	}
+	code, _, stderr = runCLI(s, "groups", "restore", filepath.Join(dir, "missing.tar"), "--group", "Btarget")
+	if code != exitError || !strings.Contains(stderr, "no such file") {
+		t.Fatalf("want missing bundle error got %d: %s", code, stderr)
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/lcd1232/go-bitly/bitly"
+	"github.com/lcd1232/go-bitly/bitly/analytics"
+	"github.com/pkg/errors"
+	"net/url"
+	"strconv"
+)
+
+func runShorten(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet()
+	domain := fs.String("domain", "", "short domain, the domain of the profile or the group preference by default")
+	group := fs.String("group", "", "GUID of the group, the group of the profile or the default group of the user by default")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	client, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	if *domain == "" {
+		*domain = c.settings.Domain
+	}
+	if *group == "" {
+		*group = c.settings.GroupGUID
+	}
+	link, _, err := client.Bitlinks.Shorten(ctx, &bitly.ShortenOptions{
+		LongURL:   args[0],
+		Domain:    *domain,
+		GroupGUID: *group,
+	})
+	if err != nil {
+		return err
+	}
+	return c.render(link)
+}
+
+// expandResult is the response of POST /v4/expand
+type expandResult struct {
+	ID        string         `json:"id"`
+	Link      string         `json:"link"`
+	LongURL   string         `json:"long_url"`
+	CreatedAt bitly.JSONDate `json:"created_at"`
+}
+
+func runExpand(ctx context.Context, c *cli, args []string) error {
+	args, err := c.parse(c.flagSet(), args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := bitly.ParseBitlink(args[0])
+	if err != nil {
+		return err
+	}
+	client, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	result := &expandResult{}
+	if _, err := client.Call(ctx, "POST", "/expand", nil, map[string]string{"bitlink_id": id.String()}, result); err != nil {
+		return err
+	}
+	return c.render(result)
+}
+
+func runUser(ctx context.Context, c *cli, args []string) error {
+	if _, err := c.parse(c.flagSet(), args, 0); err != nil {
+		return err
+	}
+	client, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	user, _, err := client.User.Get(ctx)
+	if err != nil {
+		return err
+	}
+	return c.render(user)
+}
+
+func runGroupsList(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet()
+	organization := fs.String("organization", "", "list only groups of the organization GUID")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	client, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	groups, _, err := client.Groups.ListGroups(ctx, *organization)
+	if err != nil {
+		return err
+	}
+	return c.render(groups.Groups)
+}
+
+func runGroupsGet(ctx context.Context, c *cli, args []string) error {
+	args, err := c.parse(c.flagSet(), args, 1)
+	if err != nil {
+		return err
+	}
+	client, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	group, _, err := client.Groups.GetGroup(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return c.render(group)
+}
+
+func runGroupsPrefs(ctx context.Context, c *cli, args []string) error {
+	args, err := c.parse(c.flagSet(), args, 1)
+	if err != nil {
+		return err
+	}
+	client, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	prefs, _, err := client.Groups.GetGroupPreferences(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return c.render(prefs)
+}
+
+// linksFlags are filters of GetBitlinksByGroupQueryParams shared by links commands
+type linksFlags struct {
+	group  string
+	params bitly.GetBitlinksByGroupQueryParams
+
+	createdBefore, createdAfter, modifiedAfter          timeFlag
+	archived, deepLinks, domainDeepLinks, customBitlink optionFlag
+	tags, encodingLogins                                stringsFlag
+}
+
+func addLinksFlags(fs *flag.FlagSet) *linksFlags {
+	f := &linksFlags{}
+	fs.StringVar(&f.group, "group", "", "GUID of the group, the group of the profile by default")
+	fs.IntVar(&f.params.Size, "size", 0, "number of Bitlinks per page")
+	fs.IntVar(&f.params.Page, "page", 0, "page to list")
+	fs.StringVar(&f.params.Keyword, "keyword", "", "custom keyword to filter by")
+	fs.StringVar(&f.params.Query, "query", "", "value to search for")
+	fs.StringVar(&f.params.CampaignGUID, "campaign", "", "campaign GUID to filter by")
+	fs.StringVar(&f.params.ChannelGUID, "channel", "", "channel GUID to filter by")
+	fs.Var(&f.createdBefore, "created-before", "list Bitlinks created before the time")
+	fs.Var(&f.createdAfter, "created-after", "list Bitlinks created after the time")
+	fs.Var(&f.modifiedAfter, "modified-after", "list Bitlinks modified after the time")
+	fs.Var(&f.archived, "archived", "archived Bitlinks: on, off or both")
+	fs.Var(&f.deepLinks, "deeplinks", "Bitlinks with deeplinks: on, off or both")
+	fs.Var(&f.domainDeepLinks, "domain-deeplinks", "Bitlinks with domain deeplinks: on, off or both")
+	fs.Var(&f.customBitlink, "custom-bitlink", "Bitlinks with custom back-halves: on, off or both")
+	fs.Var(&f.tags, "tag", "tag to filter by, may be repeated")
+	fs.Var(&f.encodingLogins, "encoding-login", "login of the creator to filter by, may be repeated")
+	return f
+}
+
+// queryParams returns the query of the flags
+func (f *linksFlags) queryParams() *bitly.GetBitlinksByGroupQueryParams {
+	params := f.params
+	if !f.createdBefore.IsZero() {
+		params.CreatedBefore = int(f.createdBefore.Unix())
+	}
+	if !f.createdAfter.IsZero() {
+		params.CreatedAfter = int(f.createdAfter.Unix())
+	}
+	if !f.modifiedAfter.IsZero() {
+		params.ModifiedAfter = bitly.JSONDate(f.modifiedAfter.UTC()).String()
+	}
+	params.Archived = f.archived.option()
+	params.DeepLinks = f.deepLinks.option()
+	params.DomainDeepLinks = f.domainDeepLinks.option()
+	params.CustomBitlink = f.customBitlink.option()
+	params.Tags = f.tags
+	params.EncodingLogin = f.encodingLogins
+	return &params
+}
+
+// groupGUID returns group or the group of the profile, the client must be created
+func (c *cli) groupGUID(group string) (string, error) {
+	if group == "" {
+		group = c.settings.GroupGUID
+	}
+	if group == "" {
+		return "", usagef("--group is required when the profile has no group")
+	}
+	return group, nil
+}
+
+func runLinksList(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet()
+	filters := addLinksFlags(fs)
+	all := fs.Bool("all", false, "follow pagination and list every page")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	client, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	group, err := c.groupGUID(filters.group)
+	if err != nil {
+		return err
+	}
+	params := filters.queryParams()
+	if !*all {
+		links, _, err := client.Groups.GetBitlinksByGroup(ctx, group, params)
+		if err != nil {
+			return err
+		}
+		return c.render(links.Links)
+	}
+
+	paginator, err := client.Groups.GetBitlinksByGroupPaginator(ctx, group, params)
+	if err != nil {
+		return err
+	}
+	links := []bitly.Bitlink{}
+	for {
+		if err := paginator.Get(); err != nil {
+			return err
+		}
+		links = append(links, paginator.Resp.Links...)
+		if !paginator.Next() {
+			break
+		}
+	}
+	return c.render(links)
+}
+
+func runClicks(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet()
+	unit := fs.String("unit", "day", "unit of time: minute, hour, day, week or month")
+	units := fs.Int("units", -1, "number of units to return, -1 returns every unit")
+	var reference timeFlag
+	fs.Var(&reference, "unit-reference", "time the units count back from, now by default")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if !analytics.Unit(*unit).Valid() {
+		return usagef("unknown --unit %q", *unit)
+	}
+	id, err := bitly.ParseBitlink(args[0])
+	if err != nil {
+		return err
+	}
+	params := url.Values{"unit": {*unit}, "units": {strconv.Itoa(*units)}}
+	if !reference.IsZero() {
+		params.Set("unit_reference", bitly.JSONDate(reference.UTC()).String())
+	}
+	client, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	// Call keeps --unit-reference and the newest first order of Bitly,
+	// analytics.GetClicks supports neither
+	result := &analytics.Clicks{}
+	if _, err := client.Call(ctx, "GET", "/v4/bitlinks/"+id.Path()+"/clicks", params, nil, result); err != nil {
+		return err
+	}
+	return c.render(result.LinkClicks)
+}
+
+// searchRow is a Bitlink found by search
+type searchRow struct {
+	GroupGUID string         `json:"group_guid"`
+	ID        string         `json:"id"`
+	LongURL   string         `json:"long_url"`
+	Title     string         `json:"title"`
+	Tags      []string       `json:"tags"`
+	Archived  bool           `json:"archived"`
+	CreatedAt bitly.JSONDate `json:"created_at"`
+}
+
+func runSearch(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet()
+	var options bitly.SearchOptions
+	var archived optionFlag
+	var tags stringsFlag
+	fs.StringVar(&options.OrganizationGUID, "organization", "", "search only groups of the organization GUID")
+	fs.StringVar(&options.Keyword, "keyword", "", "custom keyword to filter by")
+	fs.StringVar(&options.Query, "query", "", "value to search for, like a long URL")
+	fs.Var(&tags, "tag", "tag to filter by, may be repeated")
+	fs.Var(&archived, "archived", "archived Bitlinks: on, off or both")
+	fs.IntVar(&options.Concurrency, "concurrency", 4, "number of groups searched at the same time")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	options.Tags = tags
+	options.Archived = archived.option()
+	client, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	results, err := client.Search(ctx, options)
+	if err != nil {
+		return err
+	}
+
+	// Failed groups are reported and the Bitlinks of the others are still printed
+	rows := []searchRow{}
+	failed := 0
+	for result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(c.stderr, "bitly search: group %s: %v\n", result.GroupGUID, result.Err)
+			continue
+		}
+		link := result.Link
+		rows = append(rows, searchRow{
+			GroupGUID: result.GroupGUID,
+			ID:        link.ID,
+			LongURL:   link.LongURL,
+			Title:     link.Title,
+			Tags:      link.Tags,
+			Archived:  link.Archived,
+			CreatedAt: link.CreatedAt,
+		})
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := c.render(rows); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return errors.Errorf("%d groups failed", failed)
+	}
+	return nil
+}
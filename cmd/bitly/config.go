@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/lcd1232/go-bitly/bitly"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultProfileName = "default"
+
+	// configFileMode keeps tokens readable only by the owner
+	configFileMode = 0600
+	configDirMode  = 0700
+)
+
+// profile holds settings of one Bitly account
+type profile struct {
+	Token     string `json:"token,omitempty"`
+	BaseURL   string `json:"base_url,omitempty"`
+	GroupGUID string `json:"group_guid,omitempty"`
+	Domain    string `json:"domain,omitempty"`
+}
+
+// credentials returns Credentials of the profile for bitly.Authenticate
+func (p *profile) credentials() bitly.Credentials {
+	return bitly.NewOauthTokenCredentials(p.Token)
+}
+
+// config is the configuration file with named profiles, either TOML:
+//
+//	default_profile = "marketing"
+//
+//	[profiles.marketing]
+//	token = "..."
+//	group_guid = "Ba1b2c3d4e5"
+//
+// or JSON with the same keys when the file name ends with .json
+type config struct {
+	DefaultProfile string              `json:"default_profile,omitempty"`
+	Profiles       map[string]*profile `json:"profiles"`
+}
+
+// configPath returns BITLY_CONFIG or config.toml in the go-bitly configuration
+// directory, config.json is used instead when only it exists
+func (c *cli) configPath() (string, error) {
+	if path := c.getenv("BITLY_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir := c.getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home := c.getenv("HOME")
+		if home == "" {
+			return "", errors.New("cannot find the configuration directory, set BITLY_CONFIG or HOME")
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	dir = filepath.Join(dir, "go-bitly")
+	path := filepath.Join(dir, "config.toml")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := os.Stat(filepath.Join(dir, "config.json")); err == nil {
+			return filepath.Join(dir, "config.json"), nil
+		}
+	}
+	return path, nil
+}
+
+// loadConfig reads the configuration file, a missing file is an empty configuration
+func loadConfig(path string) (*config, error) {
+	cfg := &config{Profiles: map[string]*profile{}}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if isJSONConfig(path) {
+		err = json.Unmarshal(data, cfg)
+	} else {
+		err = parseTOML(data, cfg)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid configuration %s", path)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]*profile{}
+	}
+	return cfg, nil
+}
+
+// save writes the configuration readable only by the owner. The file is
+// replaced atomically, so a failed write keeps the previous profiles.
+func (cfg *config) save(path string) error {
+	var data []byte
+	if isJSONConfig(path) {
+		var err error
+		if data, err = json.MarshalIndent(cfg, "", "  "); err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	} else {
+		data = cfg.toml()
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, configDirMode); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, ".config-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(configFileMode); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func isJSONConfig(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// toml encodes the configuration, profiles are sorted by name
+func (cfg *config) toml() []byte {
+	buf := &bytes.Buffer{}
+	if cfg.DefaultProfile != "" {
+		fmt.Fprintf(buf, "default_profile = %s\n", tomlString(cfg.DefaultProfile))
+	}
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := cfg.Profiles[name]
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		fmt.Fprintf(buf, "[profiles.%s]\n", tomlKey(name))
+		for _, kv := range [][2]string{{"token", p.Token}, {"base_url", p.BaseURL}, {"group_guid", p.GroupGUID}, {"domain", p.Domain}} {
+			if kv[1] != "" {
+				fmt.Fprintf(buf, "%s = %s\n", kv[0], tomlString(kv[1]))
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+// parseTOML decodes the subset of TOML written by toml: comments,
+// string keys and [profiles.NAME] tables
+func parseTOML(data []byte, cfg *config) error {
+	cfg.Profiles = map[string]*profile{}
+	var current *profile
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			name, err := parseTable(line)
+			if err != nil {
+				return errors.Errorf("line %d: %v", n, err)
+			}
+			current = cfg.Profiles[name]
+			if current == nil {
+				current = &profile{}
+				cfg.Profiles[name] = current
+			}
+			continue
+		}
+
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return errors.Errorf("line %d: want key = value", n)
+		}
+		key := strings.TrimSpace(line[:i])
+		value, err := parseTOMLString(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return errors.Errorf("line %d: %v", n, err)
+		}
+		if current == nil {
+			if key != "default_profile" {
+				return errors.Errorf("line %d: unknown key %s", n, key)
+			}
+			cfg.DefaultProfile = value
+			continue
+		}
+		switch key {
+		case "token":
+			current.Token = value
+		case "base_url":
+			current.BaseURL = value
+		case "group_guid":
+			current.GroupGUID = value
+		case "domain":
+			current.Domain = value
+		default:
+			return errors.Errorf("line %d: unknown key %s", n, key)
+		}
+	}
+	return scanner.Err()
+}
+
+// parseTable returns NAME of a [profiles.NAME] header
+func parseTable(line string) (string, error) {
+	if !strings.HasSuffix(line, "]") {
+		return "", errors.New("unterminated table header")
+	}
+	header := strings.TrimSpace(line[1 : len(line)-1])
+	if !strings.HasPrefix(header, "profiles.") {
+		return "", errors.Errorf("unknown table %s", header)
+	}
+	name := strings.TrimSpace(strings.TrimPrefix(header, "profiles."))
+	if strings.HasPrefix(name, `"`) || strings.HasPrefix(name, "'") {
+		return parseTOMLString(name)
+	}
+	if name == "" || !isBareKey(name) {
+		return "", errors.Errorf("invalid profile name %q", name)
+	}
+	return name, nil
+}
+
+// parseTOMLString decodes a basic or literal string followed by an optional comment
+func parseTOMLString(s string) (string, error) {
+	if strings.HasPrefix(s, "'") {
+		end := strings.Index(s[1:], "'")
+		if end < 0 {
+			return "", errors.New("unterminated string")
+		}
+		return s[1 : end+1], checkComment(s[end+2:])
+	}
+	if !strings.HasPrefix(s, `"`) {
+		return "", errors.Errorf("want string value got %s", s)
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			value, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", errors.Errorf("invalid string %s", s[:i+1])
+			}
+			return value, checkComment(s[i+1:])
+		}
+	}
+	return "", errors.New("unterminated string")
+}
+
+func checkComment(rest string) error {
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return errors.Errorf("unexpected %s after value", rest)
+	}
+	return nil
+}
+
+func isBareKey(s string) bool {
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func tomlKey(s string) string {
+	if s != "" && isBareKey(s) {
+		return s
+	}
+	return tomlString(s)
+}
+
+// tomlString quotes s as a TOML basic string
+func tomlString(s string) string {
+	buf := &strings.Builder{}
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(buf, `\u%04X`, r)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// profileName returns the profile selected by --profile, BITLY_PROFILE or
+// the configuration, explicit reports whether it was requested by the user
+func (c *cli) profileName(cfg *config) (name string, explicit bool) {
+	if c.profile != "" {
+		return c.profile, true
+	}
+	if name := c.getenv("BITLY_PROFILE"); name != "" {
+		return name, true
+	}
+	if cfg.DefaultProfile != "" {
+		return cfg.DefaultProfile, false
+	}
+	return defaultProfileName, false
+}
+
+// currentProfile returns the selected profile, settings from BITLY_TOKEN and
+// BITLY_BASE_URL take precedence over the ones of the profile
+func (c *cli) currentProfile() (*profile, error) {
+	cfg := &config{}
+	path, err := c.configPath()
+	if err == nil {
+		if cfg, err = loadConfig(path); err != nil {
+			return nil, err
+		}
+	}
+	name, explicit := c.profileName(cfg)
+	p := &profile{}
+	if stored, ok := cfg.Profiles[name]; ok {
+		*p = *stored
+		c.warnPermissions(path)
+	} else if explicit {
+		if err != nil {
+			return nil, err
+		}
+		return nil, errors.Errorf("profile %s is not found in %s, run bitly auth login --profile %s", name, path, name)
+	}
+	if token := c.getenv("BITLY_TOKEN"); token != "" {
+		p.Token = token
+	}
+	if baseURL := c.getenv("BITLY_BASE_URL"); baseURL != "" {
+		p.BaseURL = baseURL
+	}
+	return p, nil
+}
+
+// warnPermissions warns when the configuration with tokens is readable by others
+func (c *cli) warnPermissions(path string) {
+	info, err := os.Stat(path)
+	if err == nil && info.Mode().Perm()&0077 != 0 {
+		fmt.Fprintf(c.stderr, "bitly: warning: %s is accessible by other users, run chmod 600 %s\n", path, path)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/lcd1232/go-bitly/bitly"
+	"github.com/lcd1232/go-bitly/bitly/bitlytest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfig_TOML(t *testing.T) {
+	cfg := &config{
+		DefaultProfile: "work",
+		Profiles: map[string]*profile{
+			"work":    {Token: `to"ken\`, BaseURL: "http://localhost:8080", GroupGUID: "Ba1", Domain: "es.pn"},
+			"my home": {Token: "t2"},
+		},
+	}
+	data := cfg.toml()
+	want := "default_profile = \"work\"\n\n" +
+		"[profiles.\"my home\"]\ntoken = \"t2\"\n\n" +
+		"[profiles.work]\ntoken = \"to\\\"ken\\\\\"\nbase_url = \"http://localhost:8080\"\ngroup_guid = \"Ba1\"\ndomain = \"es.pn\"\n"
+	if string(data) != want {
+		t.Fatalf("want toml\n%s\ngot\n%s", want, data)
+	}
+	got := &config{}
+	if err := parseTOML(data, got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(cfg, got) {
+		t.Fatalf("want config %#v got %#v", cfg, got)
+	}
+
+	handWritten := `
+# profiles of the team
+default_profile = 'staging'   # literal string
+
+[profiles.staging]
+token = "abc" # comment
+`
+	got = &config{}
+	if err := parseTOML([]byte(handWritten), got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.DefaultProfile != "staging" || got.Profiles["staging"].Token != "abc" {
+		t.Fatalf("unexpected config %#v", got)
+	}
+}
+
+func TestConfig_TOMLErrors(t *testing.T) {
+	testCases := []struct {
+		data    string
+		wantErr string
+	}{
+		{data: "token = \"a\"", wantErr: "line 1: unknown key token"},
+		{data: "[profiles.a]\nuser = \"x\"", wantErr: "line 2: unknown key user"},
+		{data: "[groups.a]", wantErr: "line 1: unknown table groups.a"},
+		{data: "[profiles.a b]", wantErr: `line 1: invalid profile name "a b"`},
+		{data: "[profiles.a", wantErr: "line 1: unterminated table header"},
+		{data: "[profiles.a]\ntoken = 1", wantErr: "line 2: want string value got 1"},
+		{data: "[profiles.a]\ntoken = \"a", wantErr: "line 2: unterminated string"},
+		{data: "[profiles.a]\ntoken = \"a\" b", wantErr: "line 2: unexpected b after value"},
+		{data: "[profiles.a]\ntoken", wantErr: "line 2: want key = value"},
+	}
+	for _, tc := range testCases {
+		err := parseTOML([]byte(tc.data), &config{})
+		if err == nil || err.Error() != tc.wantErr {
+			t.Fatalf("want error %v got %v", tc.wantErr, err)
+		}
+	}
+}
+
+func TestConfig_Save(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bitly-config")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"config.toml", "config.json"} {
+		path := filepath.Join(dir, "go-bitly", name)
+		cfg := &config{DefaultProfile: "a", Profiles: map[string]*profile{"a": {Token: "secret"}}}
+		if err := cfg.save(path); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if info.Mode().Perm() != configFileMode {
+			t.Fatalf("want mode %v got %v", os.FileMode(configFileMode), info.Mode().Perm())
+		}
+		got, err := loadConfig(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(cfg, got) {
+			t.Fatalf("want config %#v got %#v", cfg, got)
+		}
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "go-bitly")); info.Mode().Perm() != configDirMode {
+		t.Fatalf("want directory mode %v got %v", os.FileMode(configDirMode), info.Mode().Perm())
+	}
+	files, _ := ioutil.ReadDir(filepath.Join(dir, "go-bitly"))
+	if len(files) != 2 {
+		t.Fatalf("want temporary files removed got %d files", len(files))
+	}
+}
+
+func TestRun_Profiles(t *testing.T) {
+	s := bitlytest.NewServer()
+	defer s.Close()
+	s.AddGroup(bitlytest.Group{GUID: "Bwork", Name: "work"})
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/work", GroupGUID: "Bwork", LongURL: "https://example.com/work"})
+
+	home, err := ioutil.TempDir("", "bitly-home")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(home)
+	env := map[string]string{"HOME": home}
+	runWith := func(stdin string, args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr, func(key string) string { return env[key] })
+		return code, stdout.String(), stderr.String()
+	}
+
+	code, stdout, stderr := runWith(bitlytest.Token+"\n", "auth", "login", "--profile", "work", "--base-url", s.URL, "--group", "Bwork", "--domain", "bit.ly", "-o", "json")
+	if code != exitOK {
+		t.Fatalf("want exit code 0 got %d: %s", code, stderr)
+	}
+	path := filepath.Join(home, ".config", "go-bitly", "config.toml")
+	var result loginResult
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil || result != (loginResult{Profile: "work", Login: bitlytest.DefaultLogin, Config: path}) {
+		t.Fatalf("unexpected output %s", stdout)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != configFileMode {
+		t.Fatalf("want configuration with mode 0600 got %v %v", info, err)
+	}
+
+	// The first profile becomes the default one
+	code, stdout, stderr = runWith("", "links", "list", "--output", "template={{.id}}")
+	if code != exitOK || stdout != "bit.ly/work\n" {
+		t.Fatalf("want links of the group of the profile got %d %q: %s", code, stdout, stderr)
+	}
+	code, stdout, stderr = runWith("", "shorten", "https://example.com/new", "-o", "json")
+	var link bitly.Bitlink
+	if code != exitOK || json.Unmarshal([]byte(stdout), &link) != nil || link.References["group"] == "" {
+		t.Fatalf("unexpected shorten result %d %s: %s", code, stdout, stderr)
+	}
+	if stored, ok := s.Bitlink(link.ID); !ok || stored.GroupGUID != "Bwork" {
+		t.Fatalf("want link shortened in the group of the profile got %#v", stored)
+	}
+
+	if code, _, _ = runWith("", "auth", "login", "--profile", "broken", "--token", "wrong"); code != exitUsage {
+		t.Fatalf("want --token rejected, tokens are read from stdin, got %d", code)
+	}
+	code, _, stderr = runWith("wrong\n", "auth", "login", "--profile", "broken", "--base-url", s.URL)
+	if code != exitError || !strings.Contains(stderr, "cannot authenticate with the token") {
+		t.Fatalf("want invalid token rejected got %d: %s", code, stderr)
+	}
+	cfg, err := loadConfig(path)
+	if err != nil || len(cfg.Profiles) != 1 {
+		t.Fatalf("want invalid token not stored got %#v %v", cfg, err)
+	}
+
+	env["BITLY_PROFILE"] = "missing"
+	code, _, stderr = runWith("", "user")
+	if code != exitError || !strings.Contains(stderr, "profile missing is not found") {
+		t.Fatalf("want missing profile error got %d: %s", code, stderr)
+	}
+	code, _, stderr = runWith("", "user", "--profile", "work")
+	if code != exitOK {
+		t.Fatalf("want --profile to take precedence got %d: %s", code, stderr)
+	}
+
+	delete(env, "BITLY_PROFILE")
+	env["BITLY_TOKEN"] = "wrong"
+	code, _, _ = runWith("", "user")
+	if code != exitError {
+		t.Fatalf("want BITLY_TOKEN to override the token of the profile")
+	}
+
+	delete(env, "BITLY_TOKEN")
+	os.Chmod(path, 0644)
+	code, _, stderr = runWith("", "user")
+	if code != exitOK || !strings.Contains(stderr, "is accessible by other users") {
+		t.Fatalf("want permissions warning got %d: %s", code, stderr)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"github.com/lcd1232/go-bitly/bitly"
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stringsFlag collects values of a repeatable flag, like --tag a --tag b
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// optionFlag is a filter which is on, off or both
+type optionFlag string
+
+func (f *optionFlag) String() string {
+	return string(*f)
+}
+
+func (f *optionFlag) Set(value string) error {
+	switch strings.ToLower(value) {
+	case "on", "off", "both":
+		*f = optionFlag(strings.ToLower(value))
+		return nil
+	}
+	return errors.Errorf("want on, off or both got %q", value)
+}
+
+// option returns the filter as bitly.QueryOption, it is empty when the flag is not set
+func (f optionFlag) option() bitly.QueryOption {
+	return bitly.QueryOption(f)
+}
+
+// timeFlag is a point in time given as RFC 3339 date, date or Unix timestamp
+type timeFlag struct {
+	time.Time
+}
+
+func (f *timeFlag) String() string {
+	if f.IsZero() {
+		return ""
+	}
+	return f.Format(time.RFC3339)
+}
+
+func (f *timeFlag) Set(value string) error {
+	t, err := parseTime(value)
+	if err != nil {
+		return err
+	}
+	f.Time = t
+	return nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(sec, 0).UTC(), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("want RFC 3339 time, date or Unix timestamp got %q", value)
+}
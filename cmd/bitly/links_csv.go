@@ -0,0 +1,473 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/lcd1232/go-bitly/bitly"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// exportColumns are columns written by links export, links import reads
+// id, long_url, title, tags and archived of them and ignores the rest
+var exportColumns = []string{"id", "link", "long_url", "title", "tags", "archived", "created_at", "created_by", "custom_bitlinks", "deeplinks"}
+
+func runLinksExport(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet()
+	filters := addLinksFlags(fs)
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	client, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	group, err := c.groupGUID(filters.group)
+	if err != nil {
+		return err
+	}
+	// Archived Bitlinks are exported too unless --archived is set
+	if filters.archived == "" {
+		filters.archived = "both"
+	}
+	if filters.params.Size == 0 {
+		filters.params.Size = 100
+	}
+	paginator, err := client.Groups.GetBitlinksByGroupPaginator(ctx, group, filters.queryParams())
+	if err != nil {
+		return err
+	}
+
+	// Pages are written as they arrive, so exports of large groups do not
+	// wait for the last page and a failure keeps the rows written before it
+	w := csv.NewWriter(c.stdout)
+	if err := w.Write(exportColumns); err != nil {
+		return err
+	}
+	for {
+		if err := paginator.Get(); err != nil {
+			return err
+		}
+		rows, _, err := toRows(paginator.Resp.Links)
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			record := make([]string, len(exportColumns))
+			for i, column := range exportColumns {
+				record[i] = cell(r.values[column])
+			}
+			if err := w.Write(record); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+		if !paginator.Next() {
+			return nil
+		}
+	}
+}
+
+// Actions and statuses of links import rows
+const (
+	actionCreate = "create"
+	actionUpdate = "update"
+
+	statusOK        = "ok"
+	statusUnchanged = "unchanged"
+	statusFailed    = "failed"
+	statusSkipped   = "skipped"
+	statusDryRun    = "dry-run"
+)
+
+// importRecord is a row of the imported CSV
+type importRecord struct {
+	row      int
+	id       string
+	longURL  string
+	title    string
+	tags     []string
+	archived *bool
+	group    string
+	domain   string
+	err      error
+
+	// hasTitle and hasTags are set when the file has the column,
+	// so an empty cell clears the value and a missing column keeps it
+	hasTitle bool
+	hasTags  bool
+}
+
+// key identifies the record in the progress file
+func (r *importRecord) key() string {
+	if r.id != "" {
+		return r.id
+	}
+	return r.longURL
+}
+
+func (r *importRecord) action() string {
+	if r.id != "" {
+		return actionUpdate
+	}
+	return actionCreate
+}
+
+// changes returns the fields of the record which differ from link,
+// nil when the Bitlink is up to date
+func (r *importRecord) changes(link *bitly.Bitlink) *bitly.BitlinkUpdateOptions {
+	options := &bitly.BitlinkUpdateOptions{}
+	changed := false
+	if r.hasTitle && r.title != link.Title {
+		options.Title, changed = bitly.String(r.title), true
+	}
+	if r.hasTags && !sameTags(r.tags, link.Tags) {
+		options.Tags, changed = bitly.Strings(r.tags), true
+	}
+	if r.archived != nil && *r.archived != link.Archived {
+		options.Archived, changed = r.archived, true
+	}
+	if r.longURL != "" && r.longURL != link.LongURL {
+		options.LongURL, changed = r.longURL, true
+	}
+	if !changed {
+		return nil
+	}
+	return options
+}
+
+// sameTags reports whether a and b have the same tags in any order
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// importResult is the report line of a row
+type importResult struct {
+	Row     int    `json:"row"`
+	Action  string `json:"action"`
+	Status  string `json:"status"`
+	ID      string `json:"id"`
+	LongURL string `json:"long_url"`
+	Error   string `json:"error"`
+}
+
+// progressEntry is a line of the progress file, written for every imported row
+type progressEntry struct {
+	Row int    `json:"row"`
+	Key string `json:"key"`
+	ID  string `json:"id"`
+}
+
+func runLinksImport(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet()
+	group := fs.String("group", "", "GUID of the group of created Bitlinks without group_guid column, the group of the profile by default")
+	domain := fs.String("domain", "", "short domain of created Bitlinks without domain column, the domain of the profile by default")
+	dryRun := fs.Bool("dry-run", false, "validate rows and report actions without changing Bitlinks")
+	concurrency := fs.Int("concurrency", 4, "number of rows imported at the same time")
+	progressPath := fs.String("progress", "", "progress file which lets an interrupted import resume, FILE.progress by default")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if *concurrency < 1 {
+		return usagef("--concurrency must be positive")
+	}
+	if *progressPath == "" {
+		*progressPath = args[0] + ".progress"
+	}
+
+	records, err := readImportFile(args[0])
+	if err != nil {
+		return err
+	}
+	results := make([]importResult, len(records))
+	for i, record := range records {
+		results[i] = importResult{Row: record.row, Action: record.action(), ID: record.id, LongURL: record.longURL}
+		if record.err != nil {
+			results[i].Status, results[i].Error = statusFailed, record.err.Error()
+		}
+	}
+
+	if *dryRun {
+		for i := range results {
+			if results[i].Status == "" {
+				results[i].Status = statusDryRun
+			}
+		}
+		return c.reportImport(results)
+	}
+
+	client, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	if *group == "" {
+		*group = c.settings.GroupGUID
+	}
+	if *domain == "" {
+		*domain = c.settings.Domain
+	}
+	done, err := readProgress(*progressPath)
+	if err != nil {
+		return err
+	}
+	// The progress file lists Bitlinks of the import, it is private like the config
+	progress, err := os.OpenFile(*progressPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer progress.Close()
+
+	var mu sync.Mutex
+	var progressErr error
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				record, result := records[i], &results[i]
+				if record.group == "" {
+					record.group = *group
+				}
+				if record.domain == "" {
+					record.domain = *domain
+				}
+				id, changed, err := importLink(ctx, client, record)
+				if err != nil {
+					result.Status, result.Error = statusFailed, err.Error()
+					continue
+				}
+				result.Status, result.ID = statusOK, id
+				if !changed {
+					result.Status = statusUnchanged
+				}
+
+				mu.Lock()
+				if err := writeProgress(progress, progressEntry{Row: record.row, Key: record.key(), ID: id}); err != nil && progressErr == nil {
+					progressErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i, record := range records {
+		if results[i].Status != "" {
+			continue
+		}
+		if entry, ok := done[record.row]; ok && entry.Key == record.key() {
+			results[i].Status, results[i].ID = statusSkipped, entry.ID
+			continue
+		}
+		if ctx.Err() != nil {
+			results[i].Status, results[i].Error = statusFailed, ctx.Err().Error()
+			continue
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if progressErr != nil {
+		return errors.Wrap(progressErr, "cannot write progress")
+	}
+	return c.reportImport(results)
+}
+
+// importLink creates or updates the Bitlink of record and returns its ID,
+// changed is false when an existing Bitlink already matches the record
+func importLink(ctx context.Context, client *bitly.Client, record *importRecord) (id string, changed bool, err error) {
+	if record.id != "" {
+		id, err := bitly.ParseBitlink(record.id)
+		if err != nil {
+			return "", false, err
+		}
+		link := &bitly.Bitlink{}
+		if _, err := client.Call(ctx, "GET", "/v4/bitlinks/"+id.Path(), nil, nil, link); err != nil {
+			return "", false, err
+		}
+		options := record.changes(link)
+		if options == nil {
+			return record.id, false, nil
+		}
+		_, _, err = client.Bitlinks.Update(ctx, record.id, options)
+		return record.id, true, err
+	}
+	link, _, err := client.Bitlinks.Shorten(ctx, &bitly.ShortenOptions{
+		LongURL:   record.longURL,
+		Domain:    record.domain,
+		GroupGUID: record.group,
+	})
+	if err != nil {
+		return "", false, err
+	}
+	if options := record.changes(link); options != nil {
+		_, _, err = client.Bitlinks.Update(ctx, link.ID, options)
+	}
+	return link.ID, true, err
+}
+
+// reportImport renders results and fails when a row failed
+func (c *cli) reportImport(results []importResult) error {
+	counts := map[string]int{}
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	if err := c.render(results); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "%d rows: %d ok, %d unchanged, %d skipped, %d dry-run, %d failed\n",
+		len(results), counts[statusOK], counts[statusUnchanged], counts[statusSkipped], counts[statusDryRun], counts[statusFailed])
+	if counts[statusFailed] > 0 {
+		return errors.Errorf("%d rows failed", counts[statusFailed])
+	}
+	return nil
+}
+
+// readImportFile reads records of a CSV file with a header. Columns are matched
+// by name, id or long_url is required and unknown columns are ignored, so
+// files written by links export can be imported back. Invalid rows are
+// returned with err set.
+func readImportFile(path string) ([]*importRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(bufio.NewReader(f))
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, errors.Errorf("%s is empty", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	_, hasID := columns["id"]
+	_, hasLongURL := columns["long_url"]
+	_, hasTitle := columns["title"]
+	_, hasTags := columns["tags"]
+	if !hasID && !hasLongURL {
+		return nil, errors.Errorf("%s has neither id nor long_url column", path)
+	}
+
+	var records []*importRecord
+	for row := 1; ; row++ {
+		values, err := r.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		get := func(column string) string {
+			if i, ok := columns[column]; ok {
+				return strings.TrimSpace(values[i])
+			}
+			return ""
+		}
+		record := &importRecord{
+			row:     row,
+			id:      get("id"),
+			longURL: get("long_url"),
+			title:   get("title"),
+			group:   get("group_guid"),
+			domain:  get("domain"),
+
+			hasTitle: hasTitle,
+			hasTags:  hasTags,
+		}
+		for _, tag := range strings.Split(get("tags"), ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				record.tags = append(record.tags, tag)
+			}
+		}
+		record.err = record.parse(get("archived"))
+		records = append(records, record)
+	}
+}
+
+// parse validates the record and sets archived
+func (r *importRecord) parse(archived string) error {
+	if archived != "" {
+		v, err := strconv.ParseBool(archived)
+		if err != nil {
+			return errors.Errorf("invalid archived %q", archived)
+		}
+		r.archived = &v
+	}
+	if r.id != "" {
+		id, err := bitly.ParseBitlink(r.id)
+		if err != nil {
+			return err
+		}
+		r.id = id.String()
+	} else if r.longURL == "" {
+		return errors.New("id or long_url is required")
+	}
+	if r.longURL != "" && !strings.HasPrefix(r.longURL, "http://") && !strings.HasPrefix(r.longURL, "https://") {
+		return errors.Errorf("invalid long_url %q", r.longURL)
+	}
+	return nil
+}
+
+// readProgress returns rows imported by previous runs by row number,
+// a missing file means nothing was imported
+func readProgress(path string) (map[int]progressEntry, error) {
+	done := map[int]progressEntry{}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry progressEntry
+		// A line cut by an interrupted write is ignored, its row is imported again
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		done[entry.Row] = entry
+	}
+	return done, scanner.Err()
+}
+
+func writeProgress(w *os.File, entry progressEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return w.Sync()
+}
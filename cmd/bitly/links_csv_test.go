@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/lcd1232/go-bitly/bitly/bitlytest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun_LinksExport(t *testing.T) {
+	s := bitlytest.NewServer()
+	defer s.Close()
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/a", LongURL: "https://example.com/a", Title: "first, with comma", Tags: []string{"x", "y"}})
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/b", LongURL: "https://example.com/b", Archived: true})
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/c", LongURL: "https://example.com/c", GroupGUID: "Bother"})
+
+	code, stdout, stderr := runCLI(s, "links", "export", "--group", bitlytest.DefaultGroupGUID, "--size", "1")
+	if code != exitOK {
+		t.Fatalf("want exit code 0 got %d: %s", code, stderr)
+	}
+	records, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv %s: %v", stdout, err)
+	}
+	if len(records) != 3 || strings.Join(records[0], ",") != strings.Join(exportColumns, ",") {
+		t.Fatalf("want header and every page of the group got %q", records)
+	}
+	got := map[string][]string{}
+	for _, record := range records[1:] {
+		got[record[0]] = record
+	}
+	if a := got["bit.ly/a"]; a == nil || a[3] != "first, with comma" || a[4] != "x,y" || a[5] != "false" {
+		t.Fatalf("unexpected row %q", a)
+	}
+	if b := got["bit.ly/b"]; b == nil || b[5] != "true" {
+		t.Fatalf("want archived link exported got %q", b)
+	}
+
+	code, _, stderr = runCLI(s, "links", "export", "--output", "json")
+	if code != exitUsage || !strings.Contains(stderr, "flag provided but not defined: -output") {
+		t.Fatalf("want export without output flags got %d: %s", code, stderr)
+	}
+}
+
+func TestRun_LinksImport(t *testing.T) {
+	s := bitlytest.NewServer()
+	defer s.Close()
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/old", LongURL: "https://example.com/old", Title: "old"})
+
+	dir, err := ioutil.TempDir("", "bitly-import")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "links.csv")
+	data := "long_url,id,title,tags,archived,created_at\n" +
+		"https://example.com/new,,New,\"a, b\",,2019-01-01\n" +
+		",bit.ly/old,Renamed,,true,\n" +
+		"ftp://example.com,,,,,\n" +
+		"https://example.com/other,,,,maybe,\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	args := []string{"links", "import", path, "--output", "csv", "--columns", "row,action,status,error", "--concurrency", "2"}
+
+	code, stdout, _ := runCLI(s, append(args, "--dry-run")...)
+	want := "row,action,status,error\n" +
+		"1,create,dry-run,\n" +
+		"2,update,dry-run,\n" +
+		"3,create,failed,\"invalid long_url \"\"ftp://example.com\"\"\"\n" +
+		"4,create,failed,\"invalid archived \"\"maybe\"\"\"\n"
+	if code != exitError || stdout != want {
+		t.Fatalf("want dry run report\n%s\ngot %d\n%s", want, code, stdout)
+	}
+	if _, err := os.Stat(path + ".progress"); !os.IsNotExist(err) {
+		t.Fatalf("want no progress file after dry run")
+	}
+	if link, _ := s.Bitlink("bit.ly/old"); link.Title != "old" {
+		t.Fatalf("want no changes after dry run got %#v", link)
+	}
+
+	code, stdout, stderr := runCLI(s, append(args, "--group", bitlytest.DefaultGroupGUID)...)
+	want = "row,action,status,error\n" +
+		"1,create,ok,\n" +
+		"2,update,ok,\n" +
+		"3,create,failed,\"invalid long_url \"\"ftp://example.com\"\"\"\n" +
+		"4,create,failed,\"invalid archived \"\"maybe\"\"\"\n"
+	if code != exitError || stdout != want || !strings.Contains(stderr, "4 rows: 2 ok, 0 unchanged, 0 skipped, 0 dry-run, 2 failed") {
+		t.Fatalf("want import report\n%s\ngot %d\n%s%s", want, code, stdout, stderr)
+	}
+	if link, _ := s.Bitlink("bit.ly/old"); link.Title != "Renamed" || !link.Archived {
+		t.Fatalf("want link updated got %#v", link)
+	}
+	if info, err := os.Stat(path + ".progress"); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("want private progress file got %v, %v", info, err)
+	}
+
+	// Fixed rows are imported on the next run and imported rows are skipped
+	data = strings.Replace(data, "ftp://", "https://", 1)
+	data = strings.Replace(data, "maybe", "false", 1)
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	code, stdout, stderr = runCLI(s, append(args[:3], "--output", "template={{.row}} {{.status}} {{.id}}")...)
+	if code != exitOK {
+		t.Fatalf("want exit code 0 got %d: %s", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "1 skipped bit.ly/") || lines[1] != "2 skipped bit.ly/old" ||
+		!strings.HasPrefix(lines[2], "3 ok bit.ly/") || !strings.HasPrefix(lines[3], "4 ok bit.ly/") {
+		t.Fatalf("unexpected report %s", stdout)
+	}
+	created, ok := s.Bitlink(strings.Fields(lines[0])[2])
+	if !ok || created.Title != "New" || strings.Join(created.Tags, "|") != "a|b" {
+		t.Fatalf("want created link with title and tags got %#v", created)
+	}
+	progress, _ := ioutil.ReadFile(path + ".progress")
+	if n := bytes.Count(progress, []byte("\n")); n != 4 {
+		t.Fatalf("want 4 progress entries got %s", progress)
+	}
+}
+
+func TestRun_LinksImportExported(t *testing.T) {
+	s := bitlytest.NewServer()
+	defer s.Close()
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/a", LongURL: "https://example.com/a", Title: "first", Tags: []string{"x", "y"}})
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/b", LongURL: "https://example.com/b", Title: "second", Tags: []string{"z"}, Archived: true})
+
+	code, exported, stderr := runCLI(s, "links", "export", "--group", bitlytest.DefaultGroupGUID)
+	if code != exitOK {
+		t.Fatalf("want exit code 0 got %d: %s", code, stderr)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "links.csv")
+	if err := ioutil.WriteFile(path, []byte(exported), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Modified changes on every PATCH, so unchanged rows must keep it
+	modified := map[string]time.Time{}
+	for _, id := range []string{"bit.ly/a", "bit.ly/b"} {
+		link, _ := s.Bitlink(id)
+		modified[id] = link.Modified
+	}
+	s.Now = func() time.Time { return time.Now().Add(time.Hour) }
+	args := []string{"links", "import", "--output", "template={{.row}} {{.status}} {{.id}}"}
+	code, stdout, stderr := runCLI(s, append(args, "--progress", filepath.Join(dir, "1.progress"), path)...)
+	if code != exitOK || !strings.Contains(stderr, "2 rows: 0 ok, 2 unchanged") {
+		t.Fatalf("want unchanged rows got %d: %s%s", code, stdout, stderr)
+	}
+	for id, want := range modified {
+		if link, _ := s.Bitlink(id); !link.Modified.Equal(want) {
+			t.Fatalf("want %s not patched got %#v", id, link)
+		}
+	}
+
+	// Empty title and tags cells clear the values
+	data := "id,title,tags,archived\nbit.ly/a,,,\nbit.ly/b,second,z,false\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	code, stdout, stderr = runCLI(s, append(args, "--progress", filepath.Join(dir, "2.progress"), path)...)
+	if code != exitOK || !strings.Contains(stderr, "2 rows: 2 ok, 0 unchanged") {
+		t.Fatalf("want updated rows got %d: %s%s", code, stdout, stderr)
+	}
+	if a, _ := s.Bitlink("bit.ly/a"); a.Title != "" || len(a.Tags) != 0 || a.LongURL != "https://example.com/a" {
+		t.Fatalf("want title and tags cleared got %#v", a)
+	}
+	if b, _ := s.Bitlink("bit.ly/b"); b.Title != "second" || strings.Join(b.Tags, "|") != "z" || b.Archived {
+		t.Fatalf("want link unarchived got %#v", b)
+	}
+}
+
+func TestReadImportFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bitly-import")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	testCases := []struct {
+		data    string
+		wantErr string
+	}{
+		{data: "", wantErr: "is empty"},
+		{data: "title,tags\nx,y\n", wantErr: "has neither id nor long_url column"},
+		{data: "long_url\n\"unterminated\n", wantErr: "extraneous or missing"},
+	}
+	for i, tc := range testCases {
+		path := filepath.Join(dir, strings.Repeat("x", i+1)+".csv")
+		ioutil.WriteFile(path, []byte(tc.data), 0644)
+		if _, err := readImportFile(path); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Fatalf("want error %v got %v", tc.wantErr, err)
+		}
+	}
+
+	path := filepath.Join(dir, "bom.csv")
+	ioutil.WriteFile(path, []byte("\ufeffID,Long_URL\nhttps://bit.ly/abc/,\n,https://example.com\n"), 0644)
+	records, err := readImportFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 2 || records[0].id != "bit.ly/abc" || records[0].err != nil || records[1].key() != "https://example.com" {
+		t.Fatalf("unexpected records %#v %#v", records[0], records[1])
+	}
+}
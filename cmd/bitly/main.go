@@ -0,0 +1,282 @@
+// Command bitly is a command line client of the Bitly v4 API.
+//
+// It authenticates with the OAuth token from BITLY_TOKEN or a profile stored
+// by bitly auth login, BITLY_BASE_URL points it at another API server, like
+// a local bitlytest fake:
+//
+//	BITLY_TOKEN=... bitly shorten https://example.com
+//	bitly auth login --profile marketing --group Ba1b2c3d4e5 < token.txt
+//	bitly groups list --profile marketing
+//	bitly links list --group Ba1b2c3d4e5 --tag spring --archived off
+//	bitly clicks bit.ly/2Ld3Bx9 --unit day --units 7
+//	bitly plan links.yaml && bitly apply links.yaml
+//
+// auth login reads the token from stdin, so it does not end up in shell
+// history or the process list. Run bitly help to list every command.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/lcd1232/go-bitly/bitly"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+
+	defaultTimeout = 30 * time.Second
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr, os.Getenv)
+	stop()
+	os.Exit(code)
+}
+
+// command is a leaf of the command tree, like "groups list"
+type command struct {
+	name    string
+	args    string
+	summary string
+	// columns are shown by table and CSV outputs when --columns is not set
+	columns string
+	// noOutput commands write their own format and have no output flags
+	noOutput bool
+	run      func(ctx context.Context, c *cli, args []string) error
+}
+
+var commands = []*command{
+	{name: "auth login", args: "[flags]", summary: "Store a token read from stdin in a profile", columns: "profile,login,config", run: runAuthLogin},
+	{name: "shorten", args: "[flags] URL", summary: "Shorten a long URL", columns: "id,link,long_url", run: runShorten},
+	{name: "expand", args: "[flags] BITLINK", summary: "Print the long URL of a Bitlink", columns: "id,long_url", run: runExpand},
+	{name: "user", args: "[flags]", summary: "Print the authenticated user", columns: "login,name,is_active,created", run: runUser},
+	{name: "groups list", args: "[flags]", summary: "List groups of the user", columns: groupColumns, run: runGroupsList},
+	{name: "groups get", args: "[flags] GROUP_GUID", summary: "Print a group", columns: groupColumns, run: runGroupsGet},
+	{name: "groups prefs", args: "[flags] GROUP_GUID", summary: "Print preferences of a group", run: runGroupsPrefs},
+	{name: "groups backup", args: "[flags] GROUP_GUID", summary: "Write a group with its Bitlinks to a bundle", noOutput: true, run: runGroupsBackup},
+	{name: "groups restore", args: "[flags] FILE", summary: "Recreate Bitlinks of a bundle in a group", columns: "source,bitlink,status,conflicts,error", run: runGroupsRestore},
+	{name: "links list", args: "[flags]", summary: "List Bitlinks of a group", columns: "id,title,long_url,tags,archived,created_at", run: runLinksList},
+	{name: "links export", args: "[flags]", summary: "Write every Bitlink of a group as CSV", noOutput: true, run: runLinksExport},
+	{name: "links import", args: "[flags] FILE", summary: "Create or update Bitlinks from CSV", columns: "row,action,status,id,long_url,error", run: runLinksImport},
+	{name: "search", args: "[flags]", summary: "Search Bitlinks of every group", columns: "group_guid,id,title,long_url,created_at", run: runSearch},
+	{name: "plan", args: "[flags] MANIFEST", summary: "Print changes which make Bitlinks match a manifest", columns: planColumns, run: runPlan},
+	{name: "apply", args: "[flags] MANIFEST", summary: "Make Bitlinks match a manifest", columns: planColumns, run: runApply},
+	{name: "clicks", args: "[flags] BITLINK", summary: "Print clicks of a Bitlink", run: runClicks},
+}
+
+const (
+	groupColumns = "guid,name,organization_guid,role,is_active"
+	planColumns  = "action,bitlink,group,long_url,changes"
+)
+
+// usageError is reported with the usage of the command and exit code 2
+type usageError struct {
+	message string
+	// reported is set when FlagSet has already printed the error and usage
+	reported bool
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// cli is the environment commands run in
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(string) string
+
+	command *command
+	output  outputOptions
+	// profile is the value of --profile
+	profile string
+	// settings are the profile the client was created with
+	settings *profile
+	client   *bitly.Client
+}
+
+// run executes the command named by args and returns the exit code
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer, getenv func(string) string) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr, getenv: getenv}
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		c.usage(commands)
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	cmd, rest := findCommand(args)
+	if cmd == nil {
+		matching := commandsWithPrefix(args[0])
+		if len(matching) == 0 {
+			fmt.Fprintf(stderr, "bitly: unknown command %q\n", strings.Join(args, " "))
+			matching = commands
+		}
+		c.usage(matching)
+		return exitUsage
+	}
+	c.command = cmd
+
+	err := cmd.run(ctx, c, rest)
+	switch err := err.(type) {
+	case nil:
+		return exitOK
+	case *usageError:
+		if err.reported {
+			return exitUsage
+		}
+		fmt.Fprintf(stderr, "bitly %s: %s\n", cmd.name, err.message)
+		fmt.Fprintf(stderr, "usage: bitly %s %s\n", cmd.name, cmd.args)
+		return exitUsage
+	}
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	fmt.Fprintf(stderr, "bitly %s: %v\n", cmd.name, err)
+	return exitError
+}
+
+// findCommand returns the command with the longest name matching leading words of args
+func findCommand(args []string) (*command, []string) {
+	var found *command
+	var rest []string
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(words) > len(args) || (found != nil && len(words) <= len(strings.Fields(found.name))) {
+			continue
+		}
+		if strings.Join(args[:len(words)], " ") == cmd.name {
+			found, rest = cmd, args[len(words):]
+		}
+	}
+	return found, rest
+}
+
+func commandsWithPrefix(word string) []*command {
+	var matching []*command
+	for _, cmd := range commands {
+		if strings.Fields(cmd.name)[0] == word {
+			matching = append(matching, cmd)
+		}
+	}
+	return matching
+}
+
+func (c *cli) usage(cmds []*command) {
+	fmt.Fprintln(c.stderr, "usage: bitly <command> [flags] [args]")
+	fmt.Fprintln(c.stderr)
+	fmt.Fprintln(c.stderr, "Commands:")
+	sorted := append([]*command(nil), cmds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+	for _, cmd := range sorted {
+		fmt.Fprintf(c.stderr, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(c.stderr)
+	fmt.Fprintln(c.stderr, "Every command accepts --output table|json|jsonl|csv|template=TEMPLATE,")
+	fmt.Fprintln(c.stderr, "--columns with JSON names of fields, like guid,name, and --sort [-]COLUMN.")
+	fmt.Fprintln(c.stderr)
+	fmt.Fprintln(c.stderr, "Environment:")
+	fmt.Fprintln(c.stderr, "  BITLY_TOKEN     OAuth token, overrides the token of the profile")
+	fmt.Fprintln(c.stderr, "  BITLY_BASE_URL  API server, https://api-ssl.bitly.com by default")
+	fmt.Fprintln(c.stderr, "  BITLY_PROFILE   profile to use when --profile is not set")
+	fmt.Fprintln(c.stderr, "  BITLY_CONFIG    configuration file, ~/.config/go-bitly/config.toml by default")
+}
+
+// flagSet returns FlagSet of the current command with --profile and the
+// output flags, it reports errors to stderr
+func (c *cli) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("bitly "+c.command.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.StringVar(&c.profile, "profile", "", "profile of the configuration file, BITLY_PROFILE by default")
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: bitly %s %s\n", c.command.name, c.command.args)
+		fs.PrintDefaults()
+	}
+	if c.command.noOutput {
+		return fs
+	}
+	fs.StringVar(&c.output.output, "output", formatTable, "output format: table, json, jsonl, csv or template=TEMPLATE")
+	fs.StringVar(&c.output.output, "o", formatTable, "shorthand for --output")
+	fs.StringVar(&c.output.columns, "columns", "", "comma separated JSON names of fields to output, like guid,name")
+	fs.StringVar(&c.output.sort, "sort", "", "column to sort by, descending when prefixed with -")
+	return fs
+}
+
+// parse parses flags of fs which may be mixed with positional arguments
+// and checks the number of positional arguments is n
+func (c *cli) parse(fs *flag.FlagSet, args []string, n int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return nil, err
+			}
+			return nil, &usageError{message: err.Error(), reported: true}
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if len(positional) != n {
+		return nil, usagef("want %d arguments got %d", n, len(positional))
+	}
+	if !c.command.noOutput {
+		if err := c.output.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return positional, nil
+}
+
+// newClient returns the client authenticated with the token of the selected profile
+func (c *cli) newClient() (*bitly.Client, error) {
+	if c.client != nil {
+		return c.client, nil
+	}
+	p, err := c.currentProfile()
+	if err != nil {
+		return nil, err
+	}
+	if p.Token == "" {
+		return nil, errors.New("no token, set BITLY_TOKEN or run bitly auth login")
+	}
+	c.settings = p
+	c.client = c.clientFor(p)
+	return c.client, nil
+}
+
+// clientFor returns the client authenticated with the credentials of p
+func (c *cli) clientFor(p *profile) *bitly.Client {
+	client := bitly.NewClient(&http.Client{Timeout: defaultTimeout})
+	if p.BaseURL != "" {
+		client.BaseURL = strings.TrimRight(p.BaseURL, "/")
+	}
+	// Only reads are retried, a retried shorten of links import could
+	// create a second Bitlink
+	client.Use(
+		bitly.Authenticate(p.credentials()),
+		bitly.Retry(bitly.RetryOptions{MaxRetries: 2}),
+	)
+	return client
+}
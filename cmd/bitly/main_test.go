@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/lcd1232/go-bitly/bitly"
+	"github.com/lcd1232/go-bitly/bitly/bitlytest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runCLI runs the command against s and returns the exit code, stdout and stderr
+func runCLI(s *bitlytest.Server, args ...string) (int, string, string) {
+	env := map[string]string{"BITLY_TOKEN": bitlytest.Token, "BITLY_BASE_URL": s.URL}
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, strings.NewReader(""), &stdout, &stderr, func(key string) string { return env[key] })
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_Commands(t *testing.T) {
+	s := bitlytest.NewServer()
+	defer s.Close()
+	s.AddGroup(bitlytest.Group{GUID: "Bother", OrganizationGUID: "Oother", Name: "other"})
+	id := s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/abc", LongURL: "https://example.com/abc", Title: "abc", Tags: []string{"spring"}})
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/def", LongURL: "https://example.com/def", Archived: true})
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/abc2", GroupGUID: "Bother", LongURL: "https://example.com/abc/2", Created: time.Now().Add(time.Hour)})
+	if err := s.AddClicks(id, time.Now(), 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		desc  string
+		args  []string
+		want  string
+		check func(data []byte) bool
+	}{
+		{
+			desc: "shorten",
+			args: []string{"shorten", "https://example.com/new", "--group", bitlytest.DefaultGroupGUID, "-o", "json"},
+			check: func(data []byte) bool {
+				var link bitly.Bitlink
+				return json.Unmarshal(data, &link) == nil && strings.HasPrefix(link.Link, "https://bit.ly/")
+			},
+		},
+		{
+			desc: "expand",
+			args: []string{"expand", "https://bit.ly/abc", "--output", "template={{.long_url}}"},
+			want: "https://example.com/abc\n",
+		},
+		{
+			desc: "user",
+			args: []string{"user", "-o", "json"},
+			check: func(data []byte) bool {
+				var user bitly.User
+				return json.Unmarshal(data, &user) == nil && user.Login == bitlytest.DefaultLogin
+			},
+		},
+		{
+			desc: "groups list of organization",
+			args: []string{"groups", "list", "--organization", "Oother", "-o", "json"},
+			check: func(data []byte) bool {
+				var groups []bitly.Group
+				return json.Unmarshal(data, &groups) == nil && len(groups) == 1 && groups[0].GUID == "Bother"
+			},
+		},
+		{
+			desc: "groups list as csv",
+			args: []string{"groups", "list", "--output", "csv", "--columns", "guid,name", "--sort", "-name"},
+			want: "guid,name\nBother,other\n" + bitlytest.DefaultGroupGUID + "," + bitlytest.DefaultLogin + "\n",
+		},
+		{
+			desc: "groups get",
+			args: []string{"groups", "get", "Bother", "-o", "json"},
+			check: func(data []byte) bool {
+				var group bitly.Group
+				return json.Unmarshal(data, &group) == nil && group.Name == "other"
+			},
+		},
+		{
+			desc: "groups prefs",
+			args: []string{"groups", "prefs", bitlytest.DefaultGroupGUID, "-o", "json"},
+			check: func(data []byte) bool {
+				var prefs bitly.GroupPreferences
+				return json.Unmarshal(data, &prefs) == nil && prefs.DomainPreference == bitlytest.DefaultDomain
+			},
+		},
+		{
+			desc: "links list with filters",
+			args: []string{"links", "list", "--group", bitlytest.DefaultGroupGUID, "--archived", "off", "--tag", "spring", "-o", "json"},
+			check: func(data []byte) bool {
+				var links []bitly.Bitlink
+				return json.Unmarshal(data, &links) == nil && len(links) == 1 && links[0].ID == "bit.ly/abc"
+			},
+		},
+		{
+			desc: "links list every page",
+			args: []string{"links", "list", "--group", bitlytest.DefaultGroupGUID, "--archived", "both", "--size", "1", "--all", "-o", "json"},
+			check: func(data []byte) bool {
+				var links []bitly.Bitlink
+				return json.Unmarshal(data, &links) == nil && len(links) == 3
+			},
+		},
+		{
+			desc: "search every group",
+			args: []string{"search", "--query", "example.com/abc", "-o", "csv", "--columns", "group_guid,id"},
+			want: "group_guid,id\nBother,bit.ly/abc2\n" + bitlytest.DefaultGroupGUID + ",bit.ly/abc\n",
+		},
+		{
+			desc: "clicks",
+			args: []string{"clicks", "bit.ly/abc", "--units", "2", "-o", "json"},
+			check: func(data []byte) bool {
+				var result []map[string]interface{}
+				if json.Unmarshal(data, &result) != nil || len(result) != 2 {
+					return false
+				}
+				_, hasDate := result[0]["date"]
+				return hasDate && result[0]["clicks"] == 3.0 && len(result[0]) == 2
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			code, stdout, stderr := runCLI(s, tc.args...)
+			if code != exitOK {
+				t.Fatalf("want exit code 0 got %d: %s", code, stderr)
+			}
+			if tc.want != "" && stdout != tc.want {
+				t.Fatalf("want output %q got %q", tc.want, stdout)
+			}
+			if tc.check != nil && !tc.check([]byte(stdout)) {
+				t.Fatalf("unexpected output %s", stdout)
+			}
+		})
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	s := bitlytest.NewServer()
+	defer s.Close()
+
+	testCases := []struct {
+		desc       string
+		args       []string
+		env        map[string]string
+		wantCode   int
+		wantStderr string
+	}{
+		{
+			desc:       "no command",
+			wantCode:   exitUsage,
+			wantStderr: "Commands:",
+		},
+		{
+			desc:       "unknown command",
+			args:       []string{"delete"},
+			wantCode:   exitUsage,
+			wantStderr: `unknown command "delete"`,
+		},
+		{
+			desc:       "incomplete command",
+			args:       []string{"groups"},
+			wantCode:   exitUsage,
+			wantStderr: "groups prefs",
+		},
+		{
+			desc:       "missing argument",
+			args:       []string{"expand"},
+			wantCode:   exitUsage,
+			wantStderr: "usage: bitly expand [flags] BITLINK",
+		},
+		{
+			desc:       "invalid flag value",
+			args:       []string{"links", "list", "--group", "Ba1", "--archived", "maybe"},
+			wantCode:   exitUsage,
+			wantStderr: "want on, off or both",
+		},
+		{
+			desc:       "required flag",
+			args:       []string{"links", "list"},
+			wantCode:   exitUsage,
+			wantStderr: "--group is required when the profile has no group",
+		},
+		{
+			desc:       "missing token",
+			args:       []string{"user"},
+			env:        map[string]string{"BITLY_BASE_URL": s.URL},
+			wantCode:   exitError,
+			wantStderr: "no token, set BITLY_TOKEN or run bitly auth login",
+		},
+		{
+			desc:       "api error",
+			args:       []string{"groups", "get", "Bmissing"},
+			wantCode:   exitError,
+			wantStderr: "bitly groups get:",
+		},
+		{
+			desc:       "invalid unit",
+			args:       []string{"clicks", "bit.ly/abc", "--unit", "year"},
+			wantCode:   exitUsage,
+			wantStderr: `unknown --unit "year"`,
+		},
+		{
+			desc:       "invalid bitlink",
+			args:       []string{"clicks", "bit.ly/a b"},
+			wantCode:   exitError,
+			wantStderr: "invalid bitlink",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			env := tc.env
+			if env == nil {
+				env = map[string]string{"BITLY_TOKEN": bitlytest.Token, "BITLY_BASE_URL": s.URL}
+			}
+			var stdout, stderr bytes.Buffer
+			code := run(context.Background(), tc.args, strings.NewReader(""), &stdout, &stderr, func(key string) string { return env[key] })
+			if code != tc.wantCode {
+				t.Fatalf("want exit code %d got %d: %s", tc.wantCode, code, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tc.wantStderr) {
+				t.Fatalf("want stderr with %q got %q", tc.wantStderr, stderr.String())
+			}
+		})
+	}
+}
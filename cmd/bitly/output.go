@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+// Output formats of --output
+const (
+	formatTable    = "table"
+	formatJSON     = "json"
+	formatJSONL    = "jsonl"
+	formatCSV      = "csv"
+	formatTemplate = "template"
+)
+
+// outputOptions are the output flags shared by every command
+type outputOptions struct {
+	output  string
+	columns string
+	sort    string
+
+	format   string
+	template *template.Template
+}
+
+// validate parses the --output value
+func (o *outputOptions) validate() error {
+	o.format = o.output
+	if strings.HasPrefix(o.output, formatTemplate+"=") {
+		o.format = formatTemplate
+		tmpl, err := template.New("output").Funcs(template.FuncMap{"json": templateJSON}).Parse(strings.TrimPrefix(o.output, formatTemplate+"="))
+		if err != nil {
+			return usagef("invalid --output template: %v", err)
+		}
+		o.template = tmpl
+	}
+	switch o.format {
+	case formatTable, formatJSON, formatJSONL, formatCSV, formatTemplate:
+	default:
+		return usagef("--output must be table, json, jsonl, csv or template=TEMPLATE got %q", o.output)
+	}
+	return nil
+}
+
+// selectedColumns returns --columns or the given defaults
+func (o *outputOptions) selectedColumns(defaults string) []string {
+	value := o.columns
+	if value == "" {
+		value = defaults
+	}
+	var columns []string
+	for _, column := range strings.Split(value, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// row is a result object with keys in the order of the fields of its Go type
+type row struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// project returns the row with only the given keys
+func (r row) project(keys []string) row {
+	return row{keys: keys, values: r.values}
+}
+
+func (r row) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, k := range r.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(r.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// render writes v, a struct or a slice of structs, to stdout in the format of --output.
+// Columns are JSON names of the fields, defaults are used by table and CSV
+// outputs when --columns is not set, JSON outputs have every field then.
+func (c *cli) render(v interface{}) error {
+	rows, list, err := toRows(v)
+	if err != nil {
+		return err
+	}
+	if err := c.output.sortRows(rows); err != nil {
+		return err
+	}
+
+	defaults := c.command.columns
+	if c.output.format == formatJSON || c.output.format == formatJSONL {
+		defaults = ""
+	}
+	columns := c.output.selectedColumns(defaults)
+	if c.output.columns != "" {
+		for _, column := range columns {
+			if !hasColumn(rows, column) {
+				return usagef("unknown --columns column %q", column)
+			}
+		}
+	}
+	if len(columns) == 0 && (c.output.format == formatTable || c.output.format == formatCSV) {
+		columns = fieldNames(reflect.TypeOf(v))
+	}
+	if len(columns) > 0 {
+		for i := range rows {
+			rows[i] = rows[i].project(columns)
+		}
+	}
+
+	switch c.output.format {
+	case formatJSON:
+		var out interface{} = rows
+		if rows == nil {
+			out = []row{}
+		}
+		if !list {
+			out = rows[0]
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(c.stdout, "%s\n", data)
+		return err
+	case formatJSONL:
+		enc := json.NewEncoder(c.stdout)
+		for _, r := range rows {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case formatCSV:
+		return writeCSV(c.stdout, columns, rows)
+	case formatTemplate:
+		for _, r := range rows {
+			if err := c.output.template.Execute(c.stdout, r.values); err != nil {
+				return err
+			}
+			if _, err := io.WriteString(c.stdout, "\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return writeTable(c.stdout, columns, rows)
+}
+
+// sortRows sorts rows by --sort column, descending when it starts with -.
+// The sort is stable, so rows with equal values keep the order of the API.
+func (o *outputOptions) sortRows(rows []row) error {
+	if o.sort == "" {
+		return nil
+	}
+	key, desc := strings.TrimPrefix(o.sort, "-"), strings.HasPrefix(o.sort, "-")
+	if !hasColumn(rows, key) {
+		return usagef("unknown --sort column %q", key)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if desc {
+			return less(rows[j].values[key], rows[i].values[key])
+		}
+		return less(rows[i].values[key], rows[j].values[key])
+	})
+	return nil
+}
+
+// hasColumn reports whether a row has the key, every key is accepted when
+// there are no rows
+func hasColumn(rows []row, key string) bool {
+	if len(rows) == 0 {
+		return true
+	}
+	for _, r := range rows {
+		if _, ok := r.values[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// less compares numbers numerically and other values by their cells
+func less(a, b interface{}) bool {
+	na, okA := a.(json.Number)
+	nb, okB := b.(json.Number)
+	if okA && okB {
+		fa, errA := na.Float64()
+		fb, errB := nb.Float64()
+		if errA == nil && errB == nil {
+			return fa < fb
+		}
+	}
+	return cell(a) < cell(b)
+}
+
+// toRows converts v to rows through its JSON form, so MarshalJSON methods
+// like the one of JSONDate and Extra fields of models are honored.
+// list reports whether v is a slice.
+func toRows(v interface{}) (rows []row, list bool, err error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, false, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return nil, false, err
+	}
+
+	order := fieldNames(reflect.TypeOf(v))
+	newRow := func(value interface{}) (row, error) {
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return row{}, errors.Errorf("cannot render %T", v)
+		}
+		return row{keys: rowKeys(order, values), values: values}, nil
+	}
+	switch raw := raw.(type) {
+	case nil:
+		if t := reflect.TypeOf(v); t != nil && t.Kind() == reflect.Slice {
+			return nil, true, nil
+		}
+		return nil, false, errors.New("nothing to render")
+	case []interface{}:
+		rows = make([]row, 0, len(raw))
+		for _, value := range raw {
+			r, err := newRow(value)
+			if err != nil {
+				return nil, false, err
+			}
+			rows = append(rows, r)
+		}
+		return rows, true, nil
+	default:
+		r, err := newRow(raw)
+		if err != nil {
+			return nil, false, err
+		}
+		return []row{r}, false, nil
+	}
+}
+
+// rowKeys returns keys of values, known fields in their order and the rest sorted
+func rowKeys(order []string, values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	known := map[string]bool{}
+	for _, k := range order {
+		if _, ok := values[k]; ok {
+			keys = append(keys, k)
+			known[k] = true
+		}
+	}
+	var extra []string
+	for k := range values {
+		if !known[k] {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+	return append(keys, extra...)
+}
+
+// fieldNames returns JSON names of fields of t, a struct or a slice of structs
+func fieldNames(t reflect.Type) []string {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" {
+			names = append(names, fieldNames(f.Type)...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// cell formats a JSON value for table, CSV and sorting: null is empty,
+// lists of scalars are joined with commas and objects are compact JSON
+func cell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				return templateJSON(v)
+			}
+			items = append(items, cell(item))
+		}
+		return strings.Join(items, ",")
+	}
+	return templateJSON(v)
+}
+
+// templateJSON encodes v as compact JSON, it is the json function of templates
+func templateJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func writeTable(w io.Writer, columns []string, rows []row) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = strings.ToUpper(column)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	replacer := strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+	for _, r := range rows {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = replacer.Replace(cell(r.values[column]))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+func writeCSV(w io.Writer, columns []string, rows []row) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = cell(r.values[column])
+		}
+		if err := cw.Write(cells); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/lcd1232/go-bitly/bitly"
+	"testing"
+	"time"
+)
+
+func TestCLI_Render(t *testing.T) {
+	created := bitly.JSONDate(time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC))
+	links := []bitly.Bitlink{
+		{ID: "bit.ly/b", Title: "second, with comma", Tags: []string{"x", "y"}, CreatedAt: created},
+		{ID: "bit.ly/a", Title: "first", Archived: true, Extra: map[string]json.RawMessage{"is_deleted": json.RawMessage(`false`)}},
+		{ID: "bit.ly/c", Title: "first"},
+	}
+	group := &bitly.Group{GUID: "Ba1", Name: "test", IsActive: true}
+
+	testCases := []struct {
+		desc    string
+		v       interface{}
+		columns string
+		flags   outputOptions
+		want    string
+		wantErr string
+	}{
+		{
+			desc:    "table with default columns",
+			v:       links,
+			columns: "id,title,tags",
+			flags:   outputOptions{output: "table"},
+			want: "ID        TITLE               TAGS\n" +
+				"bit.ly/b  second, with comma  x,y\n" +
+				"bit.ly/a  first               \n" +
+				"bit.ly/c  first               \n",
+		},
+		{
+			desc:  "table of a struct with every field",
+			v:     &bitly.GroupPreferences{GroupGUID: "Ba1", DomainPreference: "bit.ly"},
+			flags: outputOptions{output: "table"},
+			want:  "GROUP_GUID  DOMAIN_PREFERENCE\nBa1         bit.ly\n",
+		},
+		{
+			desc:  "stable sort by title",
+			v:     links,
+			flags: outputOptions{output: "table", columns: "id,title", sort: "title"},
+			want:  "ID        TITLE\nbit.ly/a  first\nbit.ly/c  first\nbit.ly/b  second, with comma\n",
+		},
+		{
+			desc:  "descending sort",
+			v:     links,
+			flags: outputOptions{output: "csv", columns: "id", sort: "-id"},
+			want:  "id\nbit.ly/c\nbit.ly/b\nbit.ly/a\n",
+		},
+		{
+			desc:    "csv",
+			v:       links[:1],
+			columns: "id,title,tags,created_at,deeplinks",
+			flags:   outputOptions{output: "csv"},
+			want:    "id,title,tags,created_at,deeplinks\nbit.ly/b,\"second, with comma\",\"x,y\",2019-01-02T03:04:05+0000,\n",
+		},
+		{
+			desc:  "json of a struct",
+			v:     group,
+			flags: outputOptions{output: "json", columns: "guid,is_active"},
+			want:  "{\n  \"guid\": \"Ba1\",\n  \"is_active\": true\n}\n",
+		},
+		{
+			desc:  "json of an empty list",
+			v:     []bitly.Group(nil),
+			flags: outputOptions{output: "json"},
+			want:  "[]\n",
+		},
+		{
+			desc:    "jsonl keeps every field and extra fields",
+			v:       links[1:2],
+			columns: "id",
+			flags:   outputOptions{output: "jsonl"},
+			want:    `{"references":null,"id":"bit.ly/a","link":"","custom_bitlinks":null,"archived":true,"tags":null,"created_at":null,"created_by":"","title":"first","deeplinks":null,"long_url":"","client_id":"","is_deleted":false}` + "\n",
+		},
+		{
+			desc:  "jsonl with columns",
+			v:     links,
+			flags: outputOptions{output: "jsonl", columns: "id,archived"},
+			want:  `{"id":"bit.ly/b","archived":false}` + "\n" + `{"id":"bit.ly/a","archived":true}` + "\n" + `{"id":"bit.ly/c","archived":false}` + "\n",
+		},
+		{
+			desc:  "template",
+			v:     links[:2],
+			flags: outputOptions{output: `template={{.id}} {{json .tags}} {{.is_deleted}}`},
+			want:  "bit.ly/b [\"x\",\"y\"] <no value>\nbit.ly/a null false\n",
+		},
+		{
+			desc:    "unknown sort column",
+			v:       links,
+			flags:   outputOptions{output: "table", sort: "size"},
+			wantErr: `unknown --sort column "size"`,
+		},
+		{
+			desc:    "unknown column",
+			v:       links,
+			flags:   outputOptions{output: "csv", columns: "id,size"},
+			wantErr: `unknown --columns column "size"`,
+		},
+		{
+			desc:    "not a struct",
+			v:       []string{"a"},
+			flags:   outputOptions{output: "json"},
+			wantErr: "cannot render []string",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var stdout bytes.Buffer
+			c := &cli{stdout: &stdout, command: &command{columns: tc.columns}, output: tc.flags}
+			if err := c.output.validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			err := c.render(tc.v)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("want error %v got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if stdout.String() != tc.want {
+				t.Fatalf("want output\n%s\ngot\n%s", tc.want, stdout.String())
+			}
+		})
+	}
+}
+
+func TestOutputOptions_Validate(t *testing.T) {
+	for _, output := range []string{"yaml", "template={{.id", ""} {
+		o := &outputOptions{output: output}
+		if err := o.validate(); err == nil {
+			t.Fatalf("want error for output %q", output)
+		}
+	}
+}
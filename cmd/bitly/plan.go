@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/lcd1232/go-bitly/bitly/reconcile"
+	"github.com/pkg/errors"
+)
+
+// changeRow is a change of plan and a result of apply
+type changeRow struct {
+	Action  string   `json:"action"`
+	Bitlink string   `json:"bitlink"`
+	Group   string   `json:"group"`
+	LongURL string   `json:"long_url"`
+	Changes []string `json:"changes"`
+}
+
+func newChangeRow(change reconcile.Change, bitlink string) changeRow {
+	r := changeRow{
+		Action:  string(change.Action),
+		Bitlink: bitlink,
+		Group:   change.Group,
+		LongURL: change.Link.LongURL,
+		Changes: []string{},
+	}
+	for _, field := range change.Fields {
+		r.Changes = append(r.Changes, field.String())
+	}
+	return r
+}
+
+func runPlan(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet()
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	plan, err := c.newPlan(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	rows := []changeRow{}
+	for _, change := range plan.Changes {
+		rows = append(rows, newChangeRow(change, change.Bitlink))
+	}
+	if err := c.render(rows); err != nil {
+		return err
+	}
+	c.summarizePlan(plan)
+	return nil
+}
+
+func runApply(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet()
+	allowArchive := fs.Bool("allow-archive", false, "allow archiving Bitlinks, apply refuses plans which archive them otherwise")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	plan, err := c.newPlan(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if n := plan.Archives(); n > 0 && !*allowArchive {
+		return errors.Errorf("plan archives %d Bitlinks, run bitly plan to review them and apply with --allow-archive", n)
+	}
+	client, err := c.newClient()
+	if err != nil {
+		return err
+	}
+
+	// Changes applied before a failure are reported with the error
+	results, applyErr := reconcile.Apply(ctx, client, plan, reconcile.ApplyOptions{AllowArchive: *allowArchive})
+	rows := []changeRow{}
+	for _, result := range results {
+		rows = append(rows, newChangeRow(result.Change, result.Bitlink))
+	}
+	if err := c.render(rows); err != nil {
+		return err
+	}
+	if applyErr != nil {
+		return errors.Wrapf(applyErr, "%d of %d changes applied", len(results), len(plan.Changes))
+	}
+	c.summarizePlan(plan)
+	return nil
+}
+
+// newPlan reads the manifest and compares it with Bitlinks of its groups
+func (c *cli) newPlan(ctx context.Context, path string) (*reconcile.Plan, error) {
+	m, err := reconcile.ReadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	client, err := c.newClient()
+	if err != nil {
+		return nil, err
+	}
+	return reconcile.NewPlan(ctx, client, m)
+}
+
+// summarizePlan counts changes of plan by action on stderr
+func (c *cli) summarizePlan(plan *reconcile.Plan) {
+	counts := map[reconcile.Action]int{}
+	for _, change := range plan.Changes {
+		counts[change.Action]++
+	}
+	fmt.Fprintf(c.stderr, "%d changes: %d create, %d update, %d archive\n",
+		len(plan.Changes), counts[reconcile.ActionCreate], counts[reconcile.ActionUpdate], counts[reconcile.ActionArchive])
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"github.com/lcd1232/go-bitly/bitly/bitlytest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_PlanApply(t *testing.T) {
+	s := bitlytest.NewServer()
+	defer s.Close()
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/keep", LongURL: "https://example.com/keep", Title: "old"})
+	s.AddBitlink(bitlytest.Bitlink{ID: "bit.ly/stale", LongURL: "https://example.com/stale"})
+
+	dir, err := ioutil.TempDir("", "bitly-plan")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "links.yaml")
+	manifest := "group: " + bitlytest.DefaultGroupGUID + "\n" +
+		"prune: true\n" +
+		"links:\n" +
+		"  - long_url: https://example.com/keep\n" +
+		"    title: new\n" +
+		"  - long_url: https://example.com/spring\n" +
+		"    back_half: spring\n"
+	if err := ioutil.WriteFile(path, []byte(manifest), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	args := []string{path, "-o", "csv", "--columns", "action,bitlink,changes"}
+
+	code, stdout, stderr := runCLI(s, append([]string{"plan"}, args...)...)
+	want := "action,bitlink,changes\n" +
+		"update,bit.ly/keep,\"title: \"\"old\"\" -> \"\"new\"\"\"\n" +
+		"create,,\"long_url: \"\"https://example.com/spring\"\",back_half: \"\"bit.ly/spring\"\"\"\n" +
+		"archive,bit.ly/stale,archived: false -> true\n"
+	if code != exitOK || stdout != want || stderr != "3 changes: 1 create, 1 update, 1 archive\n" {
+		t.Fatalf("want plan\n%s\ngot %d\n%s%s", want, code, stdout, stderr)
+	}
+
+	code, stdout, stderr = runCLI(s, append([]string{"apply"}, args...)...)
+	if code != exitError || stdout != "" || !strings.Contains(stderr, "plan archives 1 Bitlinks") {
+		t.Fatalf("want apply refused without --allow-archive got %d\n%s%s", code, stdout, stderr)
+	}
+	if link, _ := s.Bitlink("bit.ly/keep"); link.Title != "old" {
+		t.Fatalf("want nothing applied got %#v", link)
+	}
+
+	code, stdout, stderr = runCLI(s, append([]string{"apply", "--allow-archive"}, args...)...)
+	want = strings.Replace(want, "create,,", "create,bit.ly/spring,", 1)
+	if code != exitOK || stdout != want {
+		t.Fatalf("want applied changes\n%s\ngot %d\n%s%s", want, code, stdout, stderr)
+	}
+	if link, ok := s.Bitlink("bit.ly/spring"); !ok || link.LongURL != "https://example.com/spring" {
+		t.Fatalf("want created Bitlink got %#v", link)
+	}
+
+	code, stdout, stderr = runCLI(s, append([]string{"apply"}, args...)...)
+	if code != exitOK || stdout != "action,bitlink,changes\n" || stderr != "0 changes: 0 create, 0 update, 0 archive\n" {
+		t.Fatalf("want nothing to apply got %d\n%s%s", code, stdout, stderr)
+	}
+}
+
+func TestRun_PlanErrors(t *testing.T) {
+	s := bitlytest.NewServer()
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "bitly-plan")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "links.json")
+	if err := ioutil.WriteFile(path, []byte(`{"links":[{"long_url":"https://example.com"}]}`), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	code, _, stderr := runCLI(s, "plan", path)
+	if code != exitError || !strings.Contains(stderr, "links[0]: group is required") {
+		t.Fatalf("want invalid manifest got %d: %s", code, stderr)
+	}
+	code, _, stderr = runCLI(s, "plan", filepath.Join(dir, "missing.yaml"))
+	if code != exitError || !strings.Contains(stderr, "no such file") {
+		t.Fatalf("want missing manifest got %d: %s", code, stderr)
+	}
+	code, _, stderr = runCLI(s, "apply")
+	if code != exitUsage || !strings.Contains(stderr, "usage: bitly apply [flags] MANIFEST") {
+		t.Fatalf("want usage got %d: %s", code, stderr)
+	}
+}